@@ -0,0 +1,379 @@
+package gor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Query keys used by the table and pagination components.
+const (
+	SortQueryKey  = "sort"
+	OrderQueryKey = "order"
+	PageQueryKey  = "page"
+	LimitQueryKey = "limit"
+)
+
+// withQuery returns the path of req with the query values replaced by values.
+// Empty values are removed from the query.
+func withQuery(req *http.Request, values map[string]string) string {
+	q := req.URL.Query()
+	for key, value := range values {
+		if value == "" {
+			q.Del(key)
+		} else {
+			q.Set(key, value)
+		}
+	}
+
+	if len(q) == 0 {
+		return req.URL.Path
+	}
+	return req.URL.Path + "?" + q.Encode()
+}
+
+// Column is a column of a Table.
+type Column struct {
+	Key      string // Map key or struct field(name, json or form tag) of the cell values.
+	Label    string // Header text. Default is Key.
+	Sortable bool   // Whether the header links to sort the table by this column.
+
+	SortURL string // URL that sorts by this column. Set by NewTable.
+	SortDir string // "asc" or "desc" if the table is sorted by this column. Set by NewTable.
+}
+
+// Table is the data of the "table" component.
+//
+//	{{ template "table" Props "table" .Table "empty" "No users yet" }}
+type Table struct {
+	Columns []Column
+	Rows    [][]any
+	Sort    string // Key of the sorted column.
+	Order   string // "asc" or "desc".
+}
+
+// NewTable creates a table from rows, a slice of maps or structs(or pointers to structs).
+// The sort column and order are read from the "sort" and "order" query values and
+// each sortable column gets a URL toggling its order. Sorting the rows is left to
+// the caller, usually in the database query:
+//
+//	table := gor.NewTable(req, []gor.Column{
+//		{Key: "name", Label: "Name", Sortable: true},
+//		{Key: "email", Label: "Email"},
+//	}, users)
+//
+//	// ORDER BY table.Sort table.Order
+func NewTable(req *http.Request, columns []Column, rows any) Table {
+	table := Table{
+		Columns: make([]Column, len(columns)),
+		Sort:    req.URL.Query().Get(SortQueryKey),
+		Order:   strings.ToLower(req.URL.Query().Get(OrderQueryKey)),
+	}
+
+	if table.Order != "desc" {
+		table.Order = "asc"
+	}
+
+	for i, col := range columns {
+		if col.Label == "" {
+			col.Label = col.Key
+		}
+
+		if col.Sortable {
+			order := "asc"
+			if table.Sort == col.Key {
+				col.SortDir = table.Order
+				if table.Order == "asc" {
+					order = "desc"
+				}
+			}
+
+			// Sorting changes the results, so start again from the first page.
+			col.SortURL = withQuery(req, map[string]string{
+				SortQueryKey:  col.Key,
+				OrderQueryKey: order,
+				PageQueryKey:  "",
+			})
+		}
+		table.Columns[i] = col
+	}
+
+	rv := reflect.ValueOf(rows)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return table
+	}
+
+	table.Rows = make([][]any, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		row := make([]any, len(columns))
+		for j, col := range columns {
+			row[j] = cellValue(rv.Index(i), col.Key)
+		}
+		table.Rows[i] = row
+	}
+	return table
+}
+
+// cellValue returns the value of key in a map or struct row.
+func cellValue(row reflect.Value, key string) any {
+	for row.Kind() == reflect.Pointer || row.Kind() == reflect.Interface {
+		if row.IsNil() {
+			return nil
+		}
+		row = row.Elem()
+	}
+
+	switch row.Kind() {
+	case reflect.Map:
+		v := row.MapIndex(reflect.ValueOf(key))
+		if v.IsValid() {
+			return v.Interface()
+		}
+	case reflect.Struct:
+		rt := row.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			if field.Name == key || tagName(field, "json") == key || tagName(field, "form") == key {
+				return row.Field(i).Interface()
+			}
+		}
+	}
+	return nil
+}
+
+// tagName returns the name part of a struct tag.
+func tagName(field reflect.StructField, key string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+	return name
+}
+
+// MaxLimit is the maximum number of items per page that NewPagination accepts
+// from the "limit" query value. Larger values are clamped.
+var MaxLimit = 100
+
+// PageLink is a link in Pagination.Pages.
+type PageLink struct {
+	Number   int
+	URL      string
+	Active   bool // The current page.
+	Ellipsis bool // Placeholder for skipped pages.
+}
+
+// Pagination is the data of the "pagination" component.
+//
+//	{{ template "pagination" Props "pagination" .Pagination }}
+type Pagination struct {
+	Page       int // Current page, starting at 1.
+	Limit      int // Items per page.
+	Total      int // Total number of items.
+	TotalPages int
+	Offset     int // Offset of the first item of the page, for database queries.
+
+	HasPrev bool
+	HasNext bool
+	PrevURL string
+	NextURL string
+	Pages   []PageLink
+}
+
+// NewPagination reads the "page" and "limit" query values of req and builds the page links.
+// limit is the default number of items per page(default 25). The page is clamped to the
+// valid range and the limit to MaxLimit(or limit if greater).
+//
+//	p := gor.NewPagination(req, total)
+//	users := db.Users(p.Offset, p.Limit)
+func NewPagination(req *http.Request, total int, limit ...int) Pagination {
+	defaultLimit := 25
+	if len(limit) > 0 && limit[0] > 0 {
+		defaultLimit = limit[0]
+	}
+
+	p := Pagination{
+		Page:  QueryInt(req, PageQueryKey, 1),
+		Limit: QueryInt(req, LimitQueryKey, defaultLimit),
+		Total: max(total, 0),
+	}
+
+	if p.Limit <= 0 {
+		p.Limit = defaultLimit
+	}
+	p.Limit = min(p.Limit, max(MaxLimit, defaultLimit))
+
+	p.TotalPages = int(math.Ceil(float64(p.Total) / float64(p.Limit)))
+	p.Page = max(min(p.Page, p.TotalPages), 1)
+	p.Offset = (p.Page - 1) * p.Limit
+	p.HasPrev = p.Page > 1
+	p.HasNext = p.Page < p.TotalPages
+
+	pageURL := func(page int) string {
+		return withQuery(req, map[string]string{PageQueryKey: strconv.Itoa(page)})
+	}
+
+	if p.HasPrev {
+		p.PrevURL = pageURL(p.Page - 1)
+	}
+
+	if p.HasNext {
+		p.NextURL = pageURL(p.Page + 1)
+	}
+
+	if p.TotalPages == 0 {
+		return p
+	}
+
+	addPage := func(n int) {
+		p.Pages = append(p.Pages, PageLink{Number: n, URL: pageURL(n), Active: n == p.Page})
+	}
+
+	// First, last and two pages around the current page.
+	const window = 2
+	from, to := max(p.Page-window, 2), min(p.Page+window, p.TotalPages-1)
+
+	addPage(1)
+	if from > 2 {
+		p.Pages = append(p.Pages, PageLink{Ellipsis: true})
+	}
+
+	for n := from; n <= to; n++ {
+		addPage(n)
+	}
+
+	if to < p.TotalPages-1 {
+		p.Pages = append(p.Pages, PageLink{Ellipsis: true})
+	}
+
+	if p.TotalPages > 1 {
+		addPage(p.TotalPages)
+	}
+	return p
+}
+
+// Alert kinds. They are also the theme slots of the "alert" component.
+const (
+	AlertInfo    = "info"
+	AlertSuccess = "success"
+	AlertWarning = "warning"
+	AlertDanger  = "danger"
+)
+
+// Alert is a message displayed with the "alert" and "alerts" components.
+//
+//	{{ template "alerts" Props "alerts" .Alerts }}
+type Alert struct {
+	Kind        string `json:"kind"` // info, success, warning or danger.
+	Message     string `json:"message"`
+	Dismissible bool   `json:"dismissible,omitempty"`
+}
+
+// flashCookieName is the name of the cookie storing flash alerts.
+const flashCookieName = "gor_flash"
+
+// SetFlash stores alerts in a cookie to be displayed on the next request,
+// typically after a redirect.
+//
+//	gor.SetFlash(w, gor.Alert{Kind: gor.AlertSuccess, Message: "User created"})
+//	gor.Redirect(w, req, "/users")
+func SetFlash(w http.ResponseWriter, alerts ...Alert) error {
+	b, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(b),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// Flashes returns the alerts stored with SetFlash and deletes them.
+func Flashes(w http.ResponseWriter, req *http.Request) []Alert {
+	cookie, err := req.Cookie(flashCookieName)
+	if err != nil {
+		return nil
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	b, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil
+	}
+
+	var alerts []Alert
+	if err := json.Unmarshal(b, &alerts); err != nil {
+		return nil
+	}
+	return alerts
+}
+
+// Crumb is a link of the "breadcrumbs" component.
+// A crumb without URL is the current page and is not linked.
+//
+//	{{ template "breadcrumbs" Props "crumbs" .Crumbs }}
+type Crumb struct {
+	Label string
+	URL   string
+}
+
+// BreadcrumbsFromPath builds breadcrumbs from the segments of a URL path,
+// starting with a "Home" crumb. e.g "/users/42/edit" gives Home, Users, 42 and Edit.
+// The last crumb is the current page.
+func BreadcrumbsFromPath(path string) []Crumb {
+	crumbs := []Crumb{{Label: "Home", URL: "/"}}
+
+	current := ""
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		current += "/" + segment
+		label, err := url.PathUnescape(segment)
+		if err != nil {
+			label = segment
+		}
+
+		label = strings.ReplaceAll(strings.ReplaceAll(label, "-", " "), "_", " ")
+		if r, size := utf8.DecodeRuneInString(label); r != utf8.RuneError {
+			label = string(unicode.ToUpper(r)) + label[size:]
+		}
+		crumbs = append(crumbs, Crumb{Label: label, URL: current})
+	}
+
+	crumbs[len(crumbs)-1].URL = ""
+	return crumbs
+}
+
+// CSRFInput returns a hidden input carrying the CSRF token.
+// The name defaults to "csrf_token". Same as the "csrf" component.
+func CSRFInput(token string, name ...string) template.HTML {
+	field := "csrf_token"
+	if len(name) > 0 && name[0] != "" {
+		field = name[0]
+	}
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(field), template.HTMLEscapeString(token)))
+}
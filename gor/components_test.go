@@ -0,0 +1,226 @@
+package gor_test
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+type tableUser struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string
+}
+
+func TestNewTable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users?sort=name&order=asc&page=3&q=jo", nil)
+	columns := []gor.Column{
+		{Key: "id", Label: "ID"},
+		{Key: "name", Label: "Name", Sortable: true},
+		{Key: "Email", Sortable: true},
+	}
+
+	table := gor.NewTable(req, columns, []*tableUser{{ID: 1, Name: "John", Email: "john@example.com"}})
+
+	if table.Sort != "name" || table.Order != "asc" {
+		t.Errorf("expected sort by name asc, got %s %s", table.Sort, table.Order)
+	}
+
+	if url := table.Columns[1].SortURL; url != "/users?order=desc&q=jo&sort=name" {
+		t.Errorf("unexpected sort URL for the sorted column %q", url)
+	}
+
+	if url := table.Columns[2].SortURL; url != "/users?order=asc&q=jo&sort=Email" {
+		t.Errorf("unexpected sort URL %q", url)
+	}
+
+	if table.Columns[1].SortDir != "asc" || table.Columns[2].SortDir != "" {
+		t.Errorf("unexpected sort directions %q %q", table.Columns[1].SortDir, table.Columns[2].SortDir)
+	}
+
+	if table.Columns[2].Label != "Email" {
+		t.Errorf("expected label to default to the key, got %q", table.Columns[2].Label)
+	}
+
+	expected := [][]any{{1, "John", "john@example.com"}}
+	if !reflect.DeepEqual(table.Rows, expected) {
+		t.Errorf("expected rows %v, got %v", expected, table.Rows)
+	}
+
+	maps := gor.NewTable(req, columns, []gor.Map{{"id": 2, "name": "Jane"}})
+	if !reflect.DeepEqual(maps.Rows, [][]any{{2, "Jane", nil}}) {
+		t.Errorf("unexpected rows from maps %v", maps.Rows)
+	}
+}
+
+func TestNewPagination(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users?page=5&limit=10&q=jo", nil)
+	p := gor.NewPagination(req, 95)
+
+	if p.Page != 5 || p.Limit != 10 || p.TotalPages != 10 || p.Offset != 40 {
+		t.Errorf("unexpected pagination %+v", p)
+	}
+
+	if p.PrevURL != "/users?limit=10&page=4&q=jo" || p.NextURL != "/users?limit=10&page=6&q=jo" {
+		t.Errorf("unexpected prev/next URLs %q %q", p.PrevURL, p.NextURL)
+	}
+
+	if got := pageLinks(p); got != "1 ... 3 4 [5] 6 7 ... 10" {
+		t.Errorf("unexpected pages %q", got)
+	}
+
+	windows := []struct {
+		page, total int
+		want        string
+	}{
+		{1, 1, "[1]"},
+		{2, 2, "1 [2]"},
+		{1, 10, "[1] 2 3 ... 10"},
+		{4, 10, "1 2 3 [4] 5 6 ... 10"},
+		{10, 10, "1 ... 8 9 [10]"},
+		{500_000, 1_000_000, "1 ... 499998 499999 [500000] 500001 500002 ... 1000000"},
+	}
+
+	for _, tt := range windows {
+		req := httptest.NewRequest(http.MethodGet, "/users?limit=1&page="+strconv.Itoa(tt.page), nil)
+		if got := pageLinks(gor.NewPagination(req, tt.total)); got != tt.want {
+			t.Errorf("page %d of %d: expected %q, got %q", tt.page, tt.total, tt.want, got)
+		}
+	}
+
+	// The limit of the query is clamped to MaxLimit, unless the default is greater.
+	req = httptest.NewRequest(http.MethodGet, "/users?limit=1000000", nil)
+	if p := gor.NewPagination(req, 10); p.Limit != gor.MaxLimit {
+		t.Errorf("expected the limit to be clamped to %d, got %d", gor.MaxLimit, p.Limit)
+	}
+
+	if p := gor.NewPagination(req, 10, 500); p.Limit != 500 {
+		t.Errorf("expected the limit to be clamped to the default, got %d", p.Limit)
+	}
+
+	// Out of range pages are clamped.
+	req = httptest.NewRequest(http.MethodGet, "/users?page=50", nil)
+	if p := gor.NewPagination(req, 30, 20); p.Page != 2 || p.HasNext || !p.HasPrev {
+		t.Errorf("expected the last page, got %+v", p)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/users", nil)
+	if p := gor.NewPagination(req, 0); p.Page != 1 || p.TotalPages != 0 || p.Offset != 0 {
+		t.Errorf("unexpected empty pagination %+v", p)
+	}
+}
+
+// pageLinks formats the page links, e.g "1 ... 4 [5] 6 ... 10".
+func pageLinks(p gor.Pagination) string {
+	var pages []string
+	for _, link := range p.Pages {
+		switch {
+		case link.Ellipsis:
+			pages = append(pages, "...")
+		case link.Active:
+			pages = append(pages, "["+strconv.Itoa(link.Number)+"]")
+		default:
+			pages = append(pages, strconv.Itoa(link.Number))
+		}
+	}
+	return strings.Join(pages, " ")
+}
+
+func TestFlashes(t *testing.T) {
+	w := httptest.NewRecorder()
+	gor.SetFlash(w, gor.Alert{Kind: gor.AlertSuccess, Message: "Saved"})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range w.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+
+	w = httptest.NewRecorder()
+	alerts := gor.Flashes(w, req)
+	if len(alerts) != 1 || alerts[0].Message != "Saved" || alerts[0].Kind != gor.AlertSuccess {
+		t.Fatalf("unexpected flashes %+v", alerts)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("expected the flash cookie to be deleted, got %+v", cookies)
+	}
+}
+
+func TestBreadcrumbsFromPath(t *testing.T) {
+	expected := []gor.Crumb{
+		{Label: "Home", URL: "/"},
+		{Label: "Users", URL: "/users"},
+		{Label: "42", URL: "/users/42"},
+		{Label: "Edit profile"},
+	}
+
+	if got := gor.BreadcrumbsFromPath("/users/42/edit-profile/"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	// Labels starting with multi-byte runes.
+	expected = []gor.Crumb{
+		{Label: "Home", URL: "/"},
+		{Label: "Équipes", URL: "/équipes"},
+		{Label: "Ünits"},
+	}
+
+	if got := gor.BreadcrumbsFromPath("/équipes/ünits"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestComponents(t *testing.T) {
+	templ, err := gor.ParseTemplatesRecursive("testdata/components", gor.BootstrapTheme.Funcs(template.FuncMap{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users?page=2&limit=1", nil)
+
+	var buf bytes.Buffer
+	err = templ.ExecuteTemplate(&buf, "page.html", gor.Map{
+		"Crumbs":     gor.BreadcrumbsFromPath("/users"),
+		"Alerts":     []gor.Alert{{Kind: gor.AlertDanger, Message: "Failed", Dismissible: true}},
+		"Table":      gor.NewTable(req, []gor.Column{{Key: "name", Label: "Name", Sortable: true}}, []gor.Map{}),
+		"Pagination": gor.NewPagination(req, 3),
+		"csrf_token": "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`<ol class="breadcrumb">`,
+		`<li class="breadcrumb-item"><a href="/">Home</a></li>`,
+		`<li aria-current="page" class="breadcrumb-item active">Users</li>`,
+		`<div role="alert" class="alert alert-danger">`,
+		`class="btn-close"`,
+		`<table class="table table-striped">`,
+		`<a href="/users?limit=1&amp;order=asc&amp;sort=name">Name</a>`,
+		`<td colspan="1" class="text-center text-muted">No users yet</td>`,
+		`<li class="page-item active"><a href="/users?limit=1&amp;page=2" aria-current="page" class="page-link">2</a></li>`,
+		`rel="prev"`,
+		`<dialog id="confirm-delete"`,
+		`<form method="post" action="/users/1/delete" class="modal-content">`,
+		`<button type="submit" class="btn btn-primary">Delete</button>`,
+		`<input type="hidden" name="csrf_token" value="secret">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+
+	if html := gor.CSRFInput(`a"b`); html != `<input type="hidden" name="csrf_token" value="a&#34;b">` {
+		t.Errorf("unexpected CSRF input %s", html)
+	}
+}
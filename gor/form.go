@@ -531,7 +531,8 @@ func QueryParser(req *http.Request, v interface{}, tag ...string) error {
 		"2006-01-02T15:04:05",           // "2006-01-02T15:04:05"
 		time.DateTime,                   // Custom format for "YYYY-MM-DD HH:MM:SS"
 		time.DateOnly,                   // "2006-01-02" (html date format)
-		time.TimeOnly,                   // "HH:MM:SS" (html time format)
+		time.TimeOnly,                   // "HH:MM:SS" (html time format with seconds)
+		"15:04",                         // "HH:MM" (html time format)
 	}
 */
 func ParseTime(v string, timezone *time.Location) (time.Time, error) {
@@ -543,7 +544,8 @@ func ParseTime(v string, timezone *time.Location) (time.Time, error) {
 		"2006-01-02T15:04:05",           // "2006-01-02T15:04:05"
 		time.DateTime,                   // Custom format for "YYYY-MM-DD HH:MM:SS"
 		time.DateOnly,                   // "2006-01-02" (html date format)
-		time.TimeOnly,                   // "HH:MM:SS" (html time format)
+		time.TimeOnly,                   // "HH:MM:SS" (html time format with seconds)
+		"15:04",                         // "HH:MM" (html time format)
 	}
 
 	loc := DefaultTimezone
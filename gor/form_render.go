@@ -0,0 +1,585 @@
+package gor
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// FormOptions configures a form generated with RenderForm.
+type FormOptions struct {
+	Action    string // Form action. Default is "" (the current URL).
+	Method    string // Form method. Default is "post".
+	ID        string // id attribute of the form.
+	Enctype   string // Form enctype, e.g "multipart/form-data".
+	Submit    string // Text of the submit button. Default is "Submit".
+	CSRFToken string // CSRF token added as a hidden input if not empty.
+	CSRFField string // Name of the CSRF input. Default is "csrf_token".
+
+	// Request handled by the csrf middleware. Its token and field name are
+	// used when CSRFToken is empty.
+	Request *http.Request
+
+	// Errors are displayed next to their fields. A FormError, ValidationErrors
+	// or map[string]string of field name to message. Other errors are
+	// displayed above the fields.
+	Errors error
+
+	// Theme supplying the CSS classes. Default is TailwindTheme.
+	Theme *Theme
+
+	// Template parsed with ParseTemplatesRecursive(FS). The fields are rendered with
+	// its component blocks, so that overridden blocks are used, and the classes come
+	// from its theme. Default is the built-in components with Theme.
+	Template *template.Template
+
+	// Tag used for the field names. Default is "form", like BodyParser.
+	Tag string
+}
+
+// FieldErrors maps field names(struct field or form name) to error messages.
+// It can be passed as FormOptions.Errors.
+type FieldErrors map[string]string
+
+// Error implements the error interface.
+func (e FieldErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	msgs := make([]string, len(keys))
+	for i, k := range keys {
+		msgs[i] = k + ": " + e[k]
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// formField is a single generated form control.
+type formField struct {
+	Field       string // Struct field name
+	Component   string // input, textarea, select or checkbox
+	Type        string
+	Name        string
+	ID          string
+	Label       string
+	Value       string
+	Checked     bool
+	Required    bool
+	Placeholder string
+	Min         string
+	Max         string
+	Step        string
+	Pattern     string
+	Options     []string
+	Error       string
+}
+
+// props returns the props of the component block rendering the field.
+func (f *formField) props() map[string]any {
+	props := map[string]any{
+		"component":   f.Component,
+		"type":        f.Type,
+		"id":          f.ID,
+		"name":        f.Name,
+		"label":       f.Label,
+		"value":       f.Value,
+		"checked":     f.Checked,
+		"required":    f.Required,
+		"placeholder": f.Placeholder,
+		"min":         f.Min,
+		"max":         f.Max,
+		"step":        f.Step,
+		"pattern":     f.Pattern,
+		"error":       f.Error,
+	}
+
+	if f.Component == "select" {
+		options := make([]Option, 0, len(f.Options)+1)
+		if !f.Required {
+			options = append(options, Option{})
+		}
+		for _, option := range f.Options {
+			options = append(options, Option{Value: option, Label: option})
+		}
+		props["options"] = options
+	}
+	return props
+}
+
+type formData struct {
+	Options FormOptions
+	Fields  []map[string]any // Props of the fields
+	Error   string           // Error not tied to a field.
+}
+
+// RenderForm generates an HTML form for the struct v(or pointer to struct),
+// prefilled with its current values. The fields are rendered with the input,
+// textarea, select and checkbox components.
+//
+// Fields are named like BodyParser expects them: the "form" tag, then the "json" tag
+// and finally the snake case of the field name. Fields tagged "-" are skipped.
+// The input type is inferred from the field type:
+//
+//	string           text(or select if the "options" tag is set)
+//	int, uint        number
+//	float            number with step "any"
+//	bool             checkbox
+//	time.Time        datetime-local
+//
+// Other tags customize the generated controls:
+//
+//	label:       label text. Default is the field name split into words.
+//	input:       input type, e.g "email", "password", "hidden", "date", "time" or "textarea".
+//	options:     comma separated options of a select.
+//	placeholder: placeholder text.
+//	pattern:     regular expression the value must match.
+//	min, max:    bounds of numbers and dates. Also read from validate:"min=1,max=10".
+//	required:    "true" makes the field required. Same as form:"name,required".
+//
+// Example:
+//
+//	type User struct {
+//		Name  string    `form:"name,required"`
+//		Email string    `form:"email" input:"email"`
+//		Age   int       `form:"age" validate:"min=18,max=130"`
+//		Born  time.Time `form:"born" input:"date"`
+//	}
+//
+//	html, err := gor.RenderForm(user, gor.FormOptions{Action: "/users", Request: req})
+func RenderForm(v any, opts FormOptions) (template.HTML, error) {
+	fields, err := formFields(v, opts.Tag)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.CSRFToken == "" && opts.Request != nil {
+		token, field := csrfFromRequest(opts.Request)
+		opts.CSRFToken = token
+		if opts.CSRFField == "" {
+			opts.CSRFField = field
+		}
+	}
+
+	if opts.Method == "" {
+		opts.Method = "post"
+	}
+
+	if opts.Submit == "" {
+		opts.Submit = "Submit"
+	}
+
+	if opts.CSRFField == "" {
+		opts.CSRFField = "csrf_token"
+	}
+
+	data := formData{Options: opts}
+	if opts.Errors != nil {
+		data.Error = applyFieldErrors(fields, opts.Errors)
+	}
+
+	data.Fields = make([]map[string]any, len(fields))
+	for i := range fields {
+		data.Fields[i] = fields[i].props()
+	}
+
+	t := opts.Template
+	if t == nil {
+		theme := opts.Theme
+		if theme == nil {
+			theme = TailwindTheme
+		}
+		t = themeComponents(theme)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := t.ExecuteTemplate(buf, "gor_form", data); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+// themeTemplates caches the built-in components parsed with the functions of each theme.
+var themeTemplates sync.Map // map[*Theme]*template.Template
+
+// themeComponents returns the built-in components using the classes of theme.
+func themeComponents(theme *Theme) *template.Template {
+	if t, ok := themeTemplates.Load(theme); ok {
+		return t.(*template.Template)
+	}
+
+	t := parseComponents(theme.Funcs(template.FuncMap{
+		"Props":         Props,
+		"IsTrue":        isTrue,
+		"SelectOptions": SelectOptions,
+	}))
+	actual, _ := themeTemplates.LoadOrStore(theme, t)
+	return actual.(*template.Template)
+}
+
+// csrfFromRequest returns the CSRF token and field name set in the locals of req
+// by the csrf middleware. They are stored under keys printed as "csrf_token"
+// and "csrf_field_name", like in templates.
+func csrfFromRequest(req *http.Request) (token, field string) {
+	ctx, ok := req.Context().Value(contextKey).(*CTX)
+	if !ok {
+		return "", ""
+	}
+
+	ctx.localsMu.RLock()
+	defer ctx.localsMu.RUnlock()
+
+	for k, v := range ctx.locals {
+		switch fmt.Sprintf("%v", k) {
+		case "csrf_token":
+			token, _ = v.(string)
+		case "csrf_field_name":
+			field, _ = v.(string)
+		}
+	}
+	return token, field
+}
+
+// bindFormTemplate makes the "form" function of funcMap render the fields with
+// the component blocks of t, which may override the built-in ones.
+func bindFormTemplate(t *template.Template, funcMap template.FuncMap) {
+	form, ok := funcMap["form"].(func(v any, props ...any) (template.HTML, error))
+	if !ok {
+		return
+	}
+
+	t.Funcs(template.FuncMap{
+		"form": func(v any, props ...any) (template.HTML, error) {
+			return form(v, append(props, "template", t)...)
+		},
+	})
+}
+
+// formFunc is the "form" template function. Options are given as key-value pairs:
+//
+//	{{ form .User "action" "/users" "data" . "errors" .errors "submit" "Save" }}
+//
+// Keys are action, method, id, enctype, submit, csrf, csrf_field, errors, template and data.
+// data is the template data: the CSRF token and field name are read from its
+// csrf_token and csrf_field_name, set by the csrf middleware with PassContextToViews.
+func (t *Theme) formFunc(v any, props ...any) (template.HTML, error) {
+	p, err := Props(props...)
+	if err != nil {
+		return "", err
+	}
+
+	opts := FormOptions{Theme: t}
+	for key, value := range p {
+		switch key {
+		case "action":
+			opts.Action = fmt.Sprint(value)
+		case "method":
+			opts.Method = fmt.Sprint(value)
+		case "id":
+			opts.ID = fmt.Sprint(value)
+		case "enctype":
+			opts.Enctype = fmt.Sprint(value)
+		case "submit":
+			opts.Submit = fmt.Sprint(value)
+		case "csrf":
+			if value != nil {
+				opts.CSRFToken = fmt.Sprint(value)
+			}
+		case "csrf_field":
+			opts.CSRFField = fmt.Sprint(value)
+		case "template":
+			t, ok := value.(*template.Template)
+			if !ok {
+				return "", fmt.Errorf("form: template must be a *template.Template, got %T", value)
+			}
+			opts.Template = t
+		case "data":
+			data, ok := value.(Map)
+			if !ok {
+				m, isMap := value.(map[string]any)
+				if !isMap {
+					return "", fmt.Errorf("form: data must be the template data, got %T", value)
+				}
+				data = m
+			}
+
+			if token, _ := data["csrf_token"].(string); token != "" && opts.CSRFToken == "" {
+				opts.CSRFToken = token
+			}
+			if field, _ := data["csrf_field_name"].(string); field != "" && opts.CSRFField == "" {
+				opts.CSRFField = field
+			}
+		case "errors":
+			switch e := value.(type) {
+			case nil:
+			case error:
+				opts.Errors = e
+			case map[string]string:
+				opts.Errors = FieldErrors(e)
+			default:
+				return "", fmt.Errorf("form: errors must be an error, got %T", value)
+			}
+		default:
+			return "", fmt.Errorf("form: unknown option %q", key)
+		}
+	}
+	return RenderForm(v, opts)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// formFields builds the form controls for the fields of the struct v.
+func formFields(v any, tagName string) ([]formField, error) {
+	if tagName == "" {
+		tagName = "form"
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv = reflect.New(rv.Type().Elem())
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("gor: RenderForm expects a struct or pointer to struct, got %T", v)
+	}
+
+	rt := rv.Type()
+	fields := make([]formField, 0, rt.NumField())
+
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get(tagName)
+		if tag == "" {
+			tag = sf.Tag.Get("json")
+		}
+
+		tagList := strings.Split(tag, ",")
+		for i := range tagList {
+			tagList[i] = strings.TrimSpace(tagList[i])
+		}
+
+		name := tagList[0]
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = SnakeCase(sf.Name)
+		}
+
+		field := formField{
+			Field:       sf.Name,
+			Component:   "input",
+			Name:        name,
+			ID:          name,
+			Label:       sf.Tag.Get("label"),
+			Required:    slices.Contains(tagList, "required") || sf.Tag.Get("required") == "true",
+			Placeholder: sf.Tag.Get("placeholder"),
+			Pattern:     sf.Tag.Get("pattern"),
+		}
+
+		if field.Label == "" {
+			field.Label = humanize(sf.Name)
+		}
+
+		field.Min, field.Max = fieldBounds(sf.Tag)
+
+		fv := rv.Field(i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+			if fv.IsNil() {
+				fv = reflect.Zero(ft)
+			} else {
+				fv = fv.Elem()
+			}
+		}
+
+		if !setFieldControl(&field, ft, fv, sf.Tag.Get("input")) {
+			continue
+		}
+
+		if options := sf.Tag.Get("options"); options != "" {
+			field.Component = "select"
+			for _, option := range strings.Split(options, ",") {
+				field.Options = append(field.Options, strings.TrimSpace(option))
+			}
+		}
+
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// setFieldControl sets the control type and value of field from the field type.
+// It returns false for unsupported types.
+func setFieldControl(field *formField, ft reflect.Type, fv reflect.Value, input string) bool {
+	switch {
+	case ft == timeType:
+		field.Type = "datetime-local"
+		if input != "" {
+			field.Type = input
+		}
+
+		t := fv.Interface().(time.Time)
+		if !t.IsZero() {
+			switch field.Type {
+			case "date":
+				field.Value = t.Format(time.DateOnly)
+			case "time":
+				field.Value = t.Format("15:04")
+			default:
+				field.Value = t.Format("2006-01-02T15:04")
+			}
+		}
+		return true
+	case ft.Kind() == reflect.Bool:
+		field.Component = "checkbox"
+		field.Type = "checkbox"
+		field.Value = "true"
+		field.Checked = fv.Bool()
+		return true
+	case ft.Kind() >= reflect.Int && ft.Kind() <= reflect.Int64:
+		field.Type = "number"
+		field.Step = "1"
+		field.Value = strconv.FormatInt(fv.Int(), 10)
+	case ft.Kind() >= reflect.Uint && ft.Kind() <= reflect.Uint64:
+		field.Type = "number"
+		field.Step = "1"
+		field.Value = strconv.FormatUint(fv.Uint(), 10)
+		if field.Min == "" {
+			field.Min = "0"
+		}
+	case ft.Kind() == reflect.Float32 || ft.Kind() == reflect.Float64:
+		field.Type = "number"
+		field.Step = "any"
+		field.Value = strconv.FormatFloat(fv.Float(), 'f', -1, ft.Bits())
+	case ft.Kind() == reflect.String:
+		field.Type = "text"
+		field.Value = fv.String()
+	default:
+		return false
+	}
+
+	if input == "textarea" {
+		field.Component = "textarea"
+	} else if input != "" {
+		field.Type = input
+	}
+	return true
+}
+
+// fieldBounds returns the min and max of a field from the "min" and "max" tags
+// or the min, max, gte and lte rules of the "validate" tag.
+func fieldBounds(tag reflect.StructTag) (min, max string) {
+	min, max = tag.Get("min"), tag.Get("max")
+
+	for _, rule := range strings.Split(tag.Get("validate"), ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(rule), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "min", "gte":
+			if min == "" {
+				min = value
+			}
+		case "max", "lte":
+			if max == "" {
+				max = value
+			}
+		}
+	}
+	return min, max
+}
+
+// applyFieldErrors sets the error of each field from err and returns
+// the message of errors that do not belong to a field.
+func applyFieldErrors(fields []formField, err error) string {
+	messages := make(FieldErrors)
+
+	var fieldErrs FieldErrors
+	var verrs ValidationErrors
+	var ferr FormError
+
+	switch {
+	case errors.As(err, &fieldErrs):
+		messages = fieldErrs
+	case errors.As(err, &verrs):
+		for _, e := range verrs {
+			messages[e.Field] = formErrorMessage(e)
+		}
+	case errors.As(err, &ferr):
+		messages[ferr.Field] = formErrorMessage(ferr)
+	default:
+		return err.Error()
+	}
+
+	found := 0
+	for i := range fields {
+		msg, ok := messages[fields[i].Name]
+		if !ok {
+			msg, ok = messages[fields[i].Field]
+		}
+
+		if ok {
+			fields[i].Error = msg
+			found++
+		}
+	}
+
+	if found < len(messages) {
+		var unmatched []string
+		for name, msg := range messages {
+			if !slices.ContainsFunc(fields, func(f formField) bool {
+				return f.Name == name || f.Field == name
+			}) {
+				unmatched = append(unmatched, msg)
+			}
+		}
+		slices.Sort(unmatched)
+		return strings.Join(unmatched, "; ")
+	}
+	return ""
+}
+
+// formErrorMessage returns the message of a FormError without the BodyParser prefix.
+func formErrorMessage(e FormError) string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return string(e.Kind)
+}
+
+// humanize splits a Go identifier into words, e.g "FirstName" becomes "First Name".
+func humanize(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
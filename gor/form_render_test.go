@@ -0,0 +1,222 @@
+package gor_test
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/csrf"
+)
+
+type signupForm struct {
+	Name     string    `form:"name,required" placeholder:"Your name"`
+	Email    string    `form:"email" input:"email"`
+	Age      int       `form:"age" validate:"min=18,max=130"`
+	Height   float64   `form:"height"`
+	Born     time.Time `form:"born" input:"date"`
+	Meeting  time.Time `form:"meeting"`
+	Role     string    `form:"role" options:"admin, user"`
+	Bio      string    `form:"bio" input:"textarea"`
+	Agree    bool      `form:"agree" label:"I agree"`
+	Secret   string    `form:"-"`
+	Nickname *string   `json:"nick"`
+}
+
+func TestRenderForm(t *testing.T) {
+	user := signupForm{
+		Name:    "John <Doe>",
+		Age:     30,
+		Height:  1.75,
+		Born:    time.Date(1994, 5, 10, 0, 0, 0, 0, time.UTC),
+		Meeting: time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC),
+		Role:    "user",
+		Bio:     "Hello",
+		Agree:   true,
+	}
+
+	html, err := gor.RenderForm(&user, gor.FormOptions{
+		Action:    "/signup",
+		CSRFToken: "token123",
+		Theme:     gor.BootstrapTheme,
+		Errors: gor.ValidationErrors{
+			{Field: "Age", Kind: gor.ParseError, Err: errors.New("age must be a number")},
+			{Field: "email", Kind: gor.RequiredFieldMissing, Err: errors.New("email is required")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := string(html)
+	for _, want := range []string{
+		`<form method="post" action="/signup">`,
+		`<input type="hidden" name="csrf_token" value="token123">`,
+		`<input type="text" id="name" name="name" value="John &lt;Doe&gt;" class="form-control" placeholder="Your name" required>`,
+		`<input type="email" id="email" name="email" class="form-control is-invalid" aria-invalid="true">`,
+		`<p class="invalid-feedback d-block">email is required</p>`,
+		`<input type="number" id="age" name="age" value="30" min="18" max="130" step="1" class="form-control is-invalid" aria-invalid="true">`,
+		`<p class="invalid-feedback d-block">age must be a number</p>`,
+		`<input type="number" id="height" name="height" value="1.75" step="any" class="form-control">`,
+		`<input type="date" id="born" name="born" value="1994-05-10" class="form-control">`,
+		`<input type="datetime-local" id="meeting" name="meeting" value="2024-01-02T15:04" class="form-control">`,
+		`<option value="user" selected>user</option>`,
+		`<textarea id="bio" name="bio" class="form-control">Hello</textarea>`,
+		`<input type="checkbox" id="agree" name="agree" value="true" class="form-check-input" checked>`,
+		`<label for="agree" class="form-check-label">I agree</label>`,
+		`<label for="nick" class="form-label">Nickname</label>`,
+		`<button type="submit" class="btn btn-primary">Submit</button>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "secret") {
+		t.Errorf("field tagged \"-\" should be skipped:\n%s", out)
+	}
+
+	if _, err := gor.RenderForm("not a struct", gor.FormOptions{}); err == nil {
+		t.Error("expected an error for a non-struct value")
+	}
+}
+
+func TestRenderFormErrors(t *testing.T) {
+	// Errors that do not match a field are shown above the form.
+	html, err := gor.RenderForm(signupForm{}, gor.FormOptions{
+		Theme:  gor.ClasslessTheme,
+		Errors: errors.New("something went wrong"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `<div role="alert">something went wrong</div>`; !strings.Contains(string(html), want) {
+		t.Errorf("expected %s in:\n%s", want, html)
+	}
+}
+
+func TestFormTemplateFunc(t *testing.T) {
+	templ, err := gor.ParseTemplatesRecursive("testdata/themes", template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templ, err = templ.New("signup").Parse(`{{ form .User "action" "/signup" "csrf" .csrf_token "errors" .errors "submit" "Sign up" }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = templ.ExecuteTemplate(&buf, "signup", gor.Map{
+		"User":       signupForm{Name: "Jane"},
+		"csrf_token": "abc",
+		"errors":     map[string]string{"name": "name is taken"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`action="/signup"`,
+		`name="csrf_token" value="abc"`,
+		`value="Jane"`,
+		`name is taken`,
+		`>Sign up</button>`,
+		gor.TailwindTheme.Class("input", "control", "invalid"),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderFormTimeRoundTrip(t *testing.T) {
+	type meeting struct {
+		Start time.Time `form:"start" input:"time"`
+	}
+
+	html, err := gor.RenderForm(meeting{Start: time.Date(0, 1, 1, 9, 30, 0, 0, time.UTC)}, gor.FormOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match := regexp.MustCompile(`name="start" value="([^"]+)"`).FindStringSubmatch(string(html))
+	if match == nil {
+		t.Fatalf("expected a value for start in:\n%s", html)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"start": {match[1]}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var got meeting
+	if err := gor.BodyParser(req, &got, time.UTC); err != nil {
+		t.Fatalf("BodyParser(%q): %v", match[1], err)
+	}
+
+	if got.Start.Hour() != 9 || got.Start.Minute() != 30 {
+		t.Errorf("expected 09:30, got %s", got.Start.Format(time.TimeOnly))
+	}
+}
+
+func TestFormOverriddenComponents(t *testing.T) {
+	templ, err := gor.ParseTemplatesRecursive("testdata/forms", template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gor.NewRouter(gor.WithTemplates(templ), gor.PassContextToViews(true))
+	r.Use(csrf.NewDoubleSubmit([]byte("secret")))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "page.html", gor.Map{
+			"User":   signupForm{Name: "Jane"},
+			"errors": map[string]string{"name": "name is taken"},
+		})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// The token is base64, "+" is escaped in attributes.
+	out := html.UnescapeString(w.Body.String())
+	for _, want := range []string{
+		`<custom-input name="name" data-error="name is taken">`,
+		`<custom-input name="email">`,
+		`name="csrf_token" value="` + w.Header().Get("X-CSRF-Token") + `"`,
+		`<textarea id="bio" name="bio"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderFormCSRFFromRequest(t *testing.T) {
+	r := gor.NewRouter()
+	r.Use(csrf.NewDoubleSubmit([]byte("secret"), csrf.WithFormKeyName("_token")))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		form, err := gor.RenderForm(signupForm{}, gor.FormOptions{Request: req})
+		if err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(form))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	token := w.Header().Get("X-CSRF-Token")
+	out := html.UnescapeString(w.Body.String())
+	if want := `<input type="hidden" name="_token" value="` + token + `">`; token == "" || !strings.Contains(out, want) {
+		t.Errorf("expected %s in:\n%s", want, out)
+	}
+}
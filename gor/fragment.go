@@ -0,0 +1,154 @@
+package gor
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"text/template/parse"
+)
+
+// fragmentSeparator separates the page name and block name of a fragment template.
+// e.g "users/list.html#row".
+const fragmentSeparator = "#"
+
+// FragmentName returns the name under which a block defined in page is registered.
+func FragmentName(page, block string) string {
+	return page + fragmentSeparator + block
+}
+
+// addFragments registers every {{ block }} and {{ define }} in the page source
+// under the page-qualified name FragmentName(page, block).
+//
+// html/template keeps a single namespace, so two pages defining a "row" block
+// would otherwise overwrite each other.
+func addFragments(root *template.Template, page, text string) error {
+	trees := make(map[string]*parse.Tree)
+
+	tree := parse.New(page)
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(text, "", "", trees); err != nil {
+		return err
+	}
+
+	for name, t := range trees {
+		if name == page {
+			continue
+		}
+
+		if _, err := root.AddParseTree(FragmentName(page, name), t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupFragment returns the block template defined in page.
+// If the page exists but was not parsed with ParseTemplatesRecursive(FS), so none of
+// its blocks are registered under FragmentName, the global template named block is used.
+func (r *Router) lookupFragment(page, block string) (*template.Template, error) {
+	if filepath.Ext(page) == "" {
+		page = page + ".html"
+	}
+
+	templates := r.templates()
+	if t := templates.Lookup(FragmentName(page, block)); t != nil {
+		return t, nil
+	}
+
+	if templates.Lookup(page) != nil && !hasFragments(templates, page) {
+		if t := templates.Lookup(block); t != nil {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("no block %q in template %q", block, page)
+}
+
+// hasFragments reports whether blocks of page are registered under FragmentName.
+func hasFragments(templates *template.Template, page string) bool {
+	prefix := page + fragmentSeparator
+	for _, t := range templates.Templates() {
+		if strings.HasPrefix(t.Name(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderFragment executes only the named block defined in the page template,
+// without the base layout. It lets a page and its partials live in one file.
+//
+//	<!-- users/list.html -->
+//	<table>
+//	  {{ range .Users }}
+//	    {{ block "row" . }}<tr><td>{{ .Name }}</td></tr>{{ end }}
+//	  {{ end }}
+//	</table>
+//
+//	r.RenderFragment(w, req, "users/list.html", "row", gor.Map{"Name": "John"})
+//
+// If a file extension is missing in page, it will be appended as ".html".
+func (r *Router) RenderFragment(w io.Writer, req *http.Request, page, block string, data Map) {
+	r.RenderFragments(w, req, page, data, block)
+}
+
+// RenderFragments executes several blocks of the page template with the same data
+// and writes them in order. Nothing is written if any block fails to render.
+func (r *Router) RenderFragments(w io.Writer, req *http.Request, page string, data Map, blocks ...string) {
+	if err := r.reloadTemplates(); err != nil {
+		r.writeRenderError(w, err)
+		return
+	}
+
+	if r.templates() == nil {
+		panic("No template is configured")
+	}
+
+	if data == nil {
+		data = Map{}
+	}
+	r.passLocals(req, data)
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	for _, block := range blocks {
+		t, err := r.lookupFragment(page, block)
+		if err == nil {
+			err = t.Execute(buf, data)
+		}
+
+		if err != nil {
+			r.writeRenderError(w, err)
+			return
+		}
+	}
+
+	if writer, ok := w.(http.ResponseWriter); ok {
+		writer.Header().Set("Content-Type", ContentTypeHTML)
+	}
+	w.Write(buf.Bytes())
+}
+
+// RenderFragment executes a named block of the page template.
+// It is an alias for gor.Router.RenderFragment.
+// Make sure you are using gor.Router. Otherwise this function will panic.
+func RenderFragment(w io.Writer, req *http.Request, page, block string, data Map) {
+	ctx, ok := req.Context().Value(contextKey).(*CTX)
+	if !ok {
+		panic("You are not using gor.Router. You cannot use this function")
+	}
+	ctx.Router.RenderFragment(w, req, page, block, data)
+}
+
+// RenderFragments executes several named blocks of the page template.
+// It is an alias for gor.Router.RenderFragments.
+func RenderFragments(w io.Writer, req *http.Request, page string, data Map, blocks ...string) {
+	ctx, ok := req.Context().Value(contextKey).(*CTX)
+	if !ok {
+		panic("You are not using gor.Router. You cannot use this function")
+	}
+	ctx.Router.RenderFragments(w, req, page, data, blocks...)
+}
@@ -0,0 +1,125 @@
+package gor_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+type fragmentUser struct {
+	Name string
+}
+
+func newFragmentRouter(t *testing.T) *gor.Router {
+	templ, err := gor.ParseTemplatesRecursive("testdata/fragments", template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gor.NewRouter(
+		gor.WithTemplates(templ),
+		gor.BaseLayout("base.html"),
+	)
+}
+
+func TestRenderFragment(t *testing.T) {
+	r := newFragmentRouter(t)
+
+	r.Get("/users/row", func(w http.ResponseWriter, req *http.Request) {
+		gor.RenderFragment(w, req, "users/list", "row", gor.Map{"Name": "John"})
+	})
+
+	r.Get("/cards/row", func(w http.ResponseWriter, req *http.Request) {
+		gor.RenderFragment(w, req, "users/cards.html", "row", gor.Map{"Name": "Jane"})
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		// Both pages define a "row" block, each page renders its own.
+		{"/users/row", "<tr><td>John</td></tr>"},
+		{"/cards/row", `<div class="card">Jane</div>`},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if w.Body.String() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.expected, w.Body.String())
+		}
+	}
+}
+
+func TestRenderFragments(t *testing.T) {
+	r := newFragmentRouter(t)
+
+	r.Get("/users", func(w http.ResponseWriter, req *http.Request) {
+		data := gor.Map{"Name": "John", "Users": []fragmentUser{{"John"}, {"Jane"}}}
+		gor.RenderFragments(w, req, "users/list.html", data, "row", "count")
+	})
+
+	r.Get("/missing", func(w http.ResponseWriter, req *http.Request) {
+		gor.RenderFragments(w, req, "users/list.html", gor.Map{"Name": "John"}, "row", "nope")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	expected := `<tr><td>John</td></tr><span id="count">2</span>`
+	if w.Body.String() != expected {
+		t.Errorf("expected %q, got %q", expected, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+
+	if strings.Contains(w.Body.String(), "John") {
+		t.Errorf("expected no partial output, got %q", w.Body.String())
+	}
+}
+
+func TestRenderFragmentNoFallback(t *testing.T) {
+	r := newFragmentRouter(t)
+
+	r.Get("/{page...}", func(w http.ResponseWriter, req *http.Request) {
+		gor.RenderFragment(w, req, req.PathValue("page"), req.URL.Query().Get("block"), gor.Map{"Name": "John"})
+	})
+
+	// Both users/list.html and users/cards.html define "row": a misspelled page
+	// or a page without the block must not render the row of the other page.
+	for _, path := range []string{"/users/lst?block=row", "/users/cards?block=count"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "no block") {
+			t.Errorf("%s: expected a missing block error, got %d %q", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRenderFragmentWithoutFragments(t *testing.T) {
+	// Templates not parsed with ParseTemplatesRecursive have no page-qualified blocks.
+	templ := template.Must(template.New("list.html").Parse(`{{ block "row" . }}<tr>{{ .Name }}</tr>{{ end }}`))
+
+	r := gor.NewRouter(gor.WithTemplates(templ))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.RenderFragment(w, req, "list", "row", gor.Map{"Name": "John"})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Body.String() != "<tr>John</tr>" {
+		t.Errorf("expected the global block, got %q", w.Body.String())
+	}
+}
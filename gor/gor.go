@@ -14,7 +14,6 @@ package gor
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -22,9 +21,11 @@ import (
 	"io"
 	"io/fs"
 	"log"
+	"mime"
 	"net"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"runtime"
@@ -82,6 +83,14 @@ type Router struct {
 	contentBlock       string             // Content block for the templates(default is "Content")
 	errorTemplate      string             // Error template. Passed "error", "status", "status_text" in its context.
 	passContextToViews bool               // Pass the request context to the views
+	htmxSkipLayout     bool               // Render htmx requests(not boosted) without the base layout
+	layoutParents      map[string]string  // Layouts mapped to their parent layout
+	layoutBlocks       []string           // Extra blocks that pages can fill for their layouts
+	blockSizes         sync.Map           // Last size of each rendered layout block, to size the next
+	streamLayout       bool               // Flush the layout head before rendering the page
+	devMode            bool               // Reload templates and show template errors in the browser
+	reloader           *templateReloader  // Template directory set with TemplateDir
+	templateMu         sync.RWMutex       // Guards template when reloading
 
 	// groups
 	groups map[string]*Group // Groups mapped to their prefix
@@ -104,6 +113,7 @@ type CTX struct {
 	localsMu *sync.RWMutex   // Mutex to syncronize access to the locals map
 	locals   map[any]any     // Locals for the templates
 	Router   *Router         // The router
+	pattern  string          // Pattern of the matched route
 }
 
 type ResponseWriter struct {
@@ -142,6 +152,11 @@ func (w *ResponseWriter) Status() int {
 	return w.status
 }
 
+// Size returns the number of bytes written to the response body.
+func (w *ResponseWriter) Size() int {
+	return w.size
+}
+
 // Flush sends any buffered data to the client.
 func (w *ResponseWriter) Flush() {
 	if f, ok := w.ResponseWriter.(http.Flusher); ok {
@@ -203,11 +218,14 @@ func NewRouter(options ...RouterOption) *Router {
 		groups:             make(map[string]*Group),
 		globalMiddlewares:  []Middleware{},
 		template:           nil,
+		htmxSkipLayout:     true,
 	}
 
 	for _, option := range options {
 		option(r)
 	}
+
+	r.initTemplates()
 	return r
 }
 
@@ -246,6 +264,7 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		// Reset the context
 		ctx.context = nil
 		ctx.Router = nil
+		ctx.pattern = ""
 
 		for k := range ctx.locals {
 			delete(ctx.locals, k)
@@ -268,9 +287,51 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	ctx.pattern = pattern
 	r.mux.ServeHTTP(writer, req)
 }
 
+// RoutePattern returns the pattern of the route matching the request, as registered
+// with the router(e.g "GET /users/{id}"), or "" if not using gor.Router.
+// Use it instead of the URL path to group requests by route, e.g in metrics.
+func RoutePattern(req *http.Request) string {
+	ctx, ok := req.Context().Value(contextKey).(*CTX)
+	if !ok {
+		return ""
+	}
+	return ctx.pattern
+}
+
+// DetachContext returns a shallow copy of req with a private copy of the router
+// context(CTX) and its locals.
+//
+// The CTX of a request is pooled and reset when Router.ServeHTTP returns. Handlers
+// running in goroutines that may outlive the request(e.g abandoned at a timeout)
+// must use a detached request, or their locals leak into later requests.
+// Locals set on the copy are not visible through req.
+func DetachContext(req *http.Request) *http.Request {
+	ctx, ok := req.Context().Value(contextKey).(*CTX)
+	if !ok {
+		return req.WithContext(req.Context())
+	}
+
+	ctx.localsMu.RLock()
+	locals := make(map[any]any, len(ctx.locals))
+	for k, v := range ctx.locals {
+		locals[k] = v
+	}
+	ctx.localsMu.RUnlock()
+
+	detached := &CTX{
+		context:  ctx.context,
+		localsMu: &sync.RWMutex{},
+		locals:   locals,
+		Router:   ctx.Router,
+		pattern:  ctx.pattern,
+	}
+	return req.WithContext(context.WithValue(req.Context(), contextKey, detached))
+}
+
 // chain of middlewares
 func (r *Router) chain(middlewares []Middleware, handler http.Handler) http.Handler {
 	if len(middlewares) == 0 {
@@ -378,6 +439,7 @@ func (r *Router) Connect(path string, handler http.HandlerFunc, middlewares ...M
 // e.g r.Static("/static", "static").
 // This method will strip the prefix from the URL path.
 // To serve minified assets(JS and CSS) if present, call gor.ServeMinifiedAssetsIfPresent=true.
+// To serve precompressed .br and .gz files if present, call gor.ServeCompressedAssetsIfPresent=true.
 // To enable caching, provide maxAge seconds for cache duration.
 func (r *Router) Static(prefix, dir string, maxAge ...int) {
 	if !strings.HasSuffix(prefix, "/") {
@@ -410,8 +472,10 @@ func (r *Router) Static(prefix, dir string, maxAge ...int) {
 				// Check for the minified version of the file
 				minifiedPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".min" + filepath.Ext(path)
 				if filePathExists(minifiedPath) {
-					http.ServeFile(w, req, minifiedPath)
 					setCacheHeaders()
+					if !serveCompressedSibling(w, req, minifiedPath, openFile) {
+						http.ServeFile(w, req, minifiedPath)
+					}
 					return
 				}
 			}
@@ -419,6 +483,10 @@ func (r *Router) Static(prefix, dir string, maxAge ...int) {
 
 		setCacheHeaders()
 
+		if serveCompressedSibling(w, req, path, openFile) {
+			return
+		}
+
 		http.ServeFile(w, req, path)
 
 	})
@@ -515,6 +583,101 @@ func (mfs *minifiedFS) Open(name string) (http.File, error) {
 	return mfs.FileSystem.Open(name)
 }
 
+// minifiedName returns the name of the minified version of name in fs
+// if ServeMinifiedAssetsIfPresent is enabled and the file exists.
+func minifiedName(fs http.FileSystem, name string) string {
+	if !ServeMinifiedAssetsIfPresent || !(strings.HasSuffix(name, ".js") || strings.HasSuffix(name, ".css")) {
+		return name
+	}
+
+	minified := strings.TrimSuffix(name, filepath.Ext(name)) + ".min" + filepath.Ext(name)
+	if f, err := fs.Open(minified); err == nil {
+		f.Close()
+		return minified
+	}
+	return name
+}
+
+// compressedSiblings are the extensions of precompressed files and their encodings,
+// in order of preference.
+var compressedSiblings = [...]struct{ ext, encoding string }{
+	{".br", "br"},
+	{".gz", "gzip"},
+}
+
+// serveCompressedSibling serves name.br or name.gz if ServeCompressedAssetsIfPresent is
+// enabled, the file exists and the client accepts its encoding.
+// It reports whether the response was written.
+func serveCompressedSibling(w http.ResponseWriter, req *http.Request, name string, open func(string) (http.File, error)) bool {
+	if !ServeCompressedAssetsIfPresent || req.Header.Get("Range") != "" {
+		return false
+	}
+
+	for _, sibling := range compressedSiblings {
+		if !acceptsEncoding(req.Header.Get("Accept-Encoding"), sibling.encoding) {
+			continue
+		}
+
+		f, err := open(name + sibling.ext)
+		if err != nil {
+			continue
+		}
+
+		stat, err := f.Stat()
+		if err != nil || stat.IsDir() {
+			f.Close()
+			continue
+		}
+
+		// The content type is that of the original file, not of the archive.
+		contentType := mime.TypeByExtension(filepath.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Encoding", sibling.encoding)
+		w.Header().Add("Vary", "Accept-Encoding")
+		http.ServeContent(w, req, name, stat.ModTime(), f)
+		f.Close()
+		return true
+	}
+	return false
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header accepts encoding
+// with a quality above zero.
+func acceptsEncoding(acceptEncoding, encoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, encoding) && name != "*" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimSpace(params), "=")
+		if !ok || strings.TrimSpace(key) != "q" {
+			return true
+		}
+
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+func openFile(name string) (http.File, error) {
+	return os.Open(name)
+}
+
+// Serve precompressed assets if present and accepted by the client.
+// This applies to StaticFS, Static functions.
+// e.g /static/js/main.js will serve /static/js/main.js.br or /static/js/main.js.gz
+// with the matching Content-Encoding header. Brotli is preferred over gzip.
+// Combined with ServeMinifiedAssetsIfPresent, the siblings of the minified file are used.
+// Default is false.
+var ServeCompressedAssetsIfPresent = false
+
 // Serve minified Javascript and CSS if present instead of original file.
 // This applies to StaticFS, Static functions.
 // e.g /static/js/main.js will serve /static/js/main.min.js if present.
@@ -527,6 +690,7 @@ var ServeMinifiedAssetsIfPresent = false
 //
 //	mux.StaticFS("/static", http.FS(staticFs))
 //
+// Minified and precompressed assets are served as in Static.
 // To enable caching, provide maxAge seconds for cache duration.
 func (r *Router) StaticFS(prefix string, fs http.FileSystem, maxAge ...int) {
 	if !strings.HasSuffix(prefix, "/") {
@@ -548,6 +712,10 @@ func (r *Router) StaticFS(prefix string, fs http.FileSystem, maxAge ...int) {
 			// Set cache control headers with the specified maxAge
 			w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(cacheDuration))
 		}
+
+		if serveCompressedSibling(w, r, minifiedName(fs, path.Clean(r.URL.Path)), fs.Open) {
+			return
+		}
 		http.FileServer(fs).ServeHTTP(w, r)
 	})
 
@@ -712,80 +880,126 @@ func (r *Router) RenderError(w http.ResponseWriter, err error, status ...int) {
 
 // =========== TEMPLATE FUNCTIONS ===========
 func (r *Router) renderTemplate(w io.Writer, name string, data Map) error {
-	// if name is missing the extension, add it(assume it's an html file)
-	if filepath.Ext(name) == "" {
-		name = name + ".html"
-	}
-
-	buf := new(bytes.Buffer)
-	err := r.template.ExecuteTemplate(buf, name, data)
-	if err != nil {
-		log.Printf("Error rendering template: %s\n", err)
-		return err
-	}
-
-	content := buf.String()
-
-	finalBuf := new(bytes.Buffer)
-	data[r.contentBlock] = template.HTML(content)
-	err = r.template.ExecuteTemplate(finalBuf, r.baseLayout, data)
-
-	if err != nil {
-		log.Printf("Error rendering template: %s\n", err)
-		return err
-	}
-
-	if writer, ok := w.(http.ResponseWriter); ok {
-		writer.Header().Set("Content-Type", ContentTypeHTML)
-		writer.WriteHeader(http.StatusOK)
-	}
-
-	_, err = w.Write(finalBuf.Bytes())
-	return err
+	return r.renderWithLayouts(w, name, r.layoutChain(r.baseLayout), data)
 }
 
 // Render the template tmpl with the data. If no template is configured, Render will panic.
 // data is a map such that it can be extended with
 // the request context keys if passContextToViews is set to true.
 // If a file extension is missing, it will be appended as ".html".
+//
+// The layout is the one set with RenderLayout, SetLayout or the UseLayout middleware,
+// falling back to the base layout. See LayoutParent for nested layouts.
+//
+// htmx requests that are not boosted nor history restorations are rendered
+// without the base layout. See SkipLayoutForHTMX.
 func (r *Router) Render(w io.Writer, req *http.Request, name string, data Map) {
-	if r.template == nil {
+	if err := r.reloadTemplates(); err != nil {
+		r.writeRenderError(w, err)
+		return
+	}
+
+	if r.templates() == nil {
 		panic("No template is configured")
 	}
 
 	writeError := func(err error) {
-		if err != nil {
-			log.Println(err)
-			if writer, ok := w.(http.ResponseWriter); ok {
-				writer.Header().Set("Content-Type", ContentTypeHTML)
-				writer.WriteHeader(http.StatusInternalServerError)
-				writer.Write([]byte(err.Error()))
-			}
-		}
+		r.writeRenderError(w, err)
 	}
 
-	// pass the request context to the views
-	if r.passContextToViews {
-		ctx, ok := req.Context().Value(contextKey).(*CTX)
-		if ok {
-			for k, v := range ctx.locals {
-				data[fmt.Sprintf("%v", k)] = v
-			}
+	r.passLocals(req, data)
+
+	// htmx swaps the response into an existing page, so the layout is not needed.
+	// Boosted requests replace the whole body and still get the layout.
+	// Both responses vary on the htmx headers, so that caches do not mix them up.
+	writer, isResponseWriter := w.(http.ResponseWriter)
+	if r.htmxSkipLayout && req != nil && isResponseWriter {
+		writer.Header().Add("Vary", htmxVary)
+	}
+
+	if r.htmxSkipLayout && req != nil && isHtmxPartial(req) {
+		if isResponseWriter {
+			writer.Header().Set("Content-Type", ContentTypeHTML)
+		}
+
+		if filepath.Ext(name) == "" {
+			name = name + ".html"
 		}
+
+		err := r.ExecuteTemplate(w, name, data)
+		writeError(err)
+		return
 	}
 
-	// if baseLayout and contentBlock are set, render the template with the base layout
-	if r.baseLayout != "" && r.contentBlock != "" {
-		err := r.renderTemplate(w, name, data)
+	// if a layout and contentBlock are set, render the template inside the layout
+	layout := r.resolveLayout(req)
+	if layout != "" && layout != NoLayout && r.contentBlock != "" {
+		err := r.renderWithLayouts(w, name, r.layoutChain(layout), data)
 		writeError(err)
 		return
 	}
 
-	err := r.template.ExecuteTemplate(w, name, data)
+	if r.templates().Lookup(name) == nil {
+		writeError(templateNotFound("template", name))
+		return
+	}
+
+	err := r.templates().ExecuteTemplate(w, name, data)
 	writeError(err)
 
 }
 
+// writeRenderError logs err and sends it with a 500 status if w is an http.ResponseWriter.
+// In development mode, the error is rendered as a page showing its location in the template.
+func (r *Router) writeRenderError(w io.Writer, err error) {
+	if err != nil {
+		log.Println(err)
+
+		// The response was already committed.
+		if isStreamedError(err) {
+			return
+		}
+
+		if r.devMode {
+			r.writeDevError(w, err)
+			return
+		}
+
+		if writer, ok := w.(http.ResponseWriter); ok {
+			writer.Header().Set("Content-Type", ContentTypeHTML)
+			writer.WriteHeader(http.StatusInternalServerError)
+			writer.Write([]byte(err.Error()))
+		}
+	}
+}
+
+// passLocals copies the request context locals into data if passContextToViews is set.
+func (r *Router) passLocals(req *http.Request, data Map) {
+	if !r.passContextToViews || req == nil {
+		return
+	}
+
+	ctx, ok := req.Context().Value(contextKey).(*CTX)
+	if ok {
+		ctx.localsMu.RLock()
+		defer ctx.localsMu.RUnlock()
+
+		for k, v := range ctx.locals {
+			data[fmt.Sprintf("%v", k)] = v
+		}
+	}
+}
+
+// htmxVary lists the request headers that select the page or the partial.
+const htmxVary = "HX-Request, HX-Boosted, HX-History-Restore-Request"
+
+// isHtmxPartial reports whether req is an htmx request that swaps part of the page.
+// History restoration requests after a cache miss expect the full page.
+func isHtmxPartial(req *http.Request) bool {
+	return req.Header.Get("HX-Request") == "true" && req.Header.Get("HX-Boosted") != "true" &&
+		req.Header.Get("HX-History-Restore-Request") != "true"
+}
+
 // Render a template of given name and pass the data to it.
 // Make sure you are using gor.Router. Otherwise this function will panic.
 // If a file extension is missing, it will be appended as ".html".
@@ -799,15 +1013,25 @@ func Render(w io.Writer, req *http.Request, name string, data Map) {
 
 // Execute a standalone template without a layout.
 func (r *Router) ExecuteTemplate(w io.Writer, name string, data Map) error {
-	if r.template == nil {
+	if err := r.reloadTemplates(); err != nil {
+		return err
+	}
+
+	if r.templates() == nil {
 		panic("No template is configured")
 	}
 
 	// create a buffer to avoid writing directly to the response writer
 	// because if an error occurs, the response writer will have already been written to
 	// with partial data.
-	buf := new(bytes.Buffer)
-	err := r.template.ExecuteTemplate(buf, name, data)
+	if r.templates().Lookup(name) == nil {
+		return templateNotFound("template", name)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	err := r.templates().ExecuteTemplate(buf, name, data)
 	if err != nil {
 		return err
 	}
@@ -847,7 +1071,7 @@ func Execute(w io.Writer, req *http.Request, name string, data any) error {
 	if !ok {
 		panic("You are not using gor.Router. You cannot use this function")
 	}
-	return ctx.Router.template.Execute(w, data)
+	return ctx.Router.templates().Execute(w, data)
 }
 
 // Execute a standalone template without a layout.
@@ -858,11 +1082,15 @@ func LookupTemplate(req *http.Request, name string) (*template.Template, error)
 		return nil, fmt.Errorf("you are not using gor.Router. You cannot use this function")
 	}
 
-	if ctx.Router.template == nil {
+	if err := ctx.Router.reloadTemplates(); err != nil {
+		return nil, err
+	}
+
+	if ctx.Router.templates() == nil {
 		return nil, fmt.Errorf("template is nil")
 	}
 
-	t := ctx.Router.template.Lookup(name)
+	t := ctx.Router.templates().Lookup(name)
 	if t == nil {
 		return nil, fmt.Errorf("no such template '%s'", name)
 	}
@@ -877,6 +1105,10 @@ func (r *Router) SendError(w http.ResponseWriter, req *http.Request, err error,
 	SendError(w, req, err, status...)
 }
 
+func (r *Router) SendProblem(w http.ResponseWriter, p *Problem) error {
+	return SendProblem(w, p)
+}
+
 func (r *Router) SendString(w http.ResponseWriter, s string) error {
 	return SendString(w, s)
 }
@@ -544,6 +544,66 @@ func TestRouterStatic(t *testing.T) {
 
 }
 
+func TestRouterStaticCompressed(t *testing.T) {
+	dirname := t.TempDir()
+
+	files := map[string]string{
+		"app.js":           "console.log('app')",
+		"app.js.br":        "brotli",
+		"app.js.gz":        "gzip",
+		"site.css":         "body{}",
+		"assets/app.js":    "console.log('app')",
+		"assets/app.js.br": "brotli",
+	}
+
+	if err := os.Mkdir(filepath.Join(dirname, "assets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dirname, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gor.ServeCompressedAssetsIfPresent = true
+	defer func() { gor.ServeCompressedAssetsIfPresent = false }()
+
+	r := gor.NewRouter()
+	r.Static("/static", dirname)
+	r.StaticFS("/assets/", http.Dir(dirname))
+
+	tests := []struct {
+		path, accept, body, encoding string
+	}{
+		{"/static/app.js", "gzip, br", "brotli", "br"},
+		{"/static/app.js", "gzip", "gzip", "gzip"},
+		{"/static/app.js", "br;q=0, gzip", "gzip", "gzip"},
+		{"/static/app.js", "", "console.log('app')", ""},
+		{"/static/site.css", "br, gzip", "body{}", ""},
+		{"/assets/app.js", "br", "brotli", "br"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		r.ServeHTTP(w, req)
+
+		if w.Body.String() != tt.body {
+			t.Errorf("%s (%s): expected body %q, got %q", tt.path, tt.accept, tt.body, w.Body.String())
+		}
+
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s (%s): expected Content-Encoding %q, got %q", tt.path, tt.accept, tt.encoding, got)
+		}
+
+		if tt.encoding != "" && !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+			t.Errorf("expected the content type of the original file, got %q", w.Header().Get("Content-Type"))
+		}
+	}
+}
+
 func TestRouterFile(t *testing.T) {
 	// create a temporary directory for the views
 	dirname, err := os.MkdirTemp("", "static")
@@ -878,6 +938,56 @@ func BenchmarkRouterFullCycle(b *testing.B) {
 	}
 }
 
+// benchmark rendering a page inside nested layouts
+func BenchmarkRenderLayouts(b *testing.B) {
+	for _, bm := range []struct {
+		name   string
+		stream bool
+	}{
+		{"Buffered", false},
+		{"Streamed", true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			r := newStreamRouter(b, bm.stream)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				// ServeHTTP modifies the request, use a new one each time.
+				req := httptest.NewRequest("GET", "/stats", nil)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					b.Fatalf("expected status 200, got %d", w.Code)
+				}
+			}
+		})
+	}
+}
+
+// benchmark rendering a page with the base layout
+func BenchmarkRenderBaseLayout(b *testing.B) {
+	templ, err := gor.ParseTemplatesRecursive("testdata/layouts", template.FuncMap{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	r := gor.NewRouter(gor.WithTemplates(templ), gor.BaseLayout("layouts/root.html"))
+	r.Get("/users", func(w http.ResponseWriter, req *http.Request) {
+		r.Render(w, req, "pages/users.html", gor.Map{"Title": "Users"})
+	})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest("GET", "/users", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+	}
+}
+
 func TestRouterExecuteTemplate(t *testing.T) {
 	templ, err := gor.ParseTemplatesRecursive("../cmd/server/templates",
 		template.FuncMap{"upper": strings.ToUpper}, ".html")
@@ -0,0 +1,283 @@
+/*
+Package htmx provides helpers for working with htmx(https://htmx.org) requests and responses.
+
+It parses the HX-* request headers, sets the HX-* response headers and
+renders out-of-band swaps.
+
+	func UpdateTodo(w http.ResponseWriter, req *http.Request) {
+		if !htmx.IsRequest(req) {
+			gor.Redirect(w, req, "/todos")
+			return
+		}
+
+		htmx.Trigger(w, "todoUpdated", gor.Map{"id": 1})
+		htmx.Reswap(w, htmx.SwapOuterHTML)
+		gor.Render(w, req, "todos/row.html", gor.Map{})
+	}
+
+gor.Router.Render already renders non-boosted htmx requests without the base layout.
+*/
+package htmx
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// Request headers sent by htmx.
+// See https://htmx.org/reference/#request_headers
+const (
+	HeaderRequest               = "HX-Request"
+	HeaderBoosted               = "HX-Boosted"
+	HeaderCurrentURL            = "HX-Current-URL"
+	HeaderHistoryRestoreRequest = "HX-History-Restore-Request"
+	HeaderPrompt                = "HX-Prompt"
+	HeaderTarget                = "HX-Target"
+	HeaderTrigger               = "HX-Trigger"
+	HeaderTriggerName           = "HX-Trigger-Name"
+)
+
+// Response headers understood by htmx.
+// See https://htmx.org/reference/#response_headers
+const (
+	HeaderLocation           = "HX-Location"
+	HeaderPushURL            = "HX-Push-Url"
+	HeaderRedirect           = "HX-Redirect"
+	HeaderRefresh            = "HX-Refresh"
+	HeaderReplaceURL         = "HX-Replace-Url"
+	HeaderReswap             = "HX-Reswap"
+	HeaderRetarget           = "HX-Retarget"
+	HeaderReselect           = "HX-Reselect"
+	HeaderTriggerAfterSettle = "HX-Trigger-After-Settle"
+	HeaderTriggerAfterSwap   = "HX-Trigger-After-Swap"
+)
+
+// Swap strategies for HX-Reswap and hx-swap-oob.
+const (
+	SwapInnerHTML   = "innerHTML"
+	SwapOuterHTML   = "outerHTML"
+	SwapBeforeBegin = "beforebegin"
+	SwapAfterBegin  = "afterbegin"
+	SwapBeforeEnd   = "beforeend"
+	SwapAfterEnd    = "afterend"
+	SwapDelete      = "delete"
+	SwapNone        = "none"
+)
+
+// StatusStopPolling tells htmx to stop polling the endpoint.
+const StatusStopPolling = 286
+
+// Request holds the htmx request headers.
+type Request struct {
+	Enabled        bool   // HX-Request is "true".
+	Boosted        bool   // Request was made by an element using hx-boost.
+	CurrentURL     string // Current URL of the browser.
+	HistoryRestore bool   // Request is for history restoration after a cache miss.
+	Prompt         string // User response to an hx-prompt.
+	Target         string // id of the target element if it exists.
+	Trigger        string // id of the triggered element if it exists.
+	TriggerName    string // name of the triggered element if it exists.
+}
+
+// NewRequest parses the htmx headers of req.
+func NewRequest(req *http.Request) Request {
+	h := req.Header
+	return Request{
+		Enabled:        h.Get(HeaderRequest) == "true",
+		Boosted:        h.Get(HeaderBoosted) == "true",
+		CurrentURL:     h.Get(HeaderCurrentURL),
+		HistoryRestore: h.Get(HeaderHistoryRestoreRequest) == "true",
+		Prompt:         h.Get(HeaderPrompt),
+		Target:         h.Get(HeaderTarget),
+		Trigger:        h.Get(HeaderTrigger),
+		TriggerName:    h.Get(HeaderTriggerName),
+	}
+}
+
+// IsRequest reports whether the request was made by htmx.
+func IsRequest(req *http.Request) bool {
+	return req.Header.Get(HeaderRequest) == "true"
+}
+
+// IsBoosted reports whether the request was made by an element using hx-boost.
+func IsBoosted(req *http.Request) bool {
+	return req.Header.Get(HeaderBoosted) == "true"
+}
+
+// IsPartial reports whether the request is an htmx request that is not boosted,
+// i.e the response will be swapped into part of the page.
+func IsPartial(req *http.Request) bool {
+	return IsRequest(req) && !IsBoosted(req)
+}
+
+// Redirect makes htmx do a client-side redirect(full page reload) to url.
+func Redirect(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderRedirect, url)
+}
+
+// Refresh makes htmx do a full refresh of the page.
+func Refresh(w http.ResponseWriter) {
+	w.Header().Set(HeaderRefresh, "true")
+}
+
+// PushURL pushes url into the browser history. Pass "false" to prevent a history update.
+func PushURL(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderPushURL, url)
+}
+
+// ReplaceURL replaces the current URL in the location bar.
+func ReplaceURL(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderReplaceURL, url)
+}
+
+// Reswap overrides how the response will be swapped, e.g "outerHTML" or "innerHTML scroll:top".
+func Reswap(w http.ResponseWriter, swap string) {
+	w.Header().Set(HeaderReswap, swap)
+}
+
+// Retarget sets the CSS selector of the element the response is swapped into.
+func Retarget(w http.ResponseWriter, selector string) {
+	w.Header().Set(HeaderRetarget, selector)
+}
+
+// Reselect sets the CSS selector of the part of the response to swap in.
+func Reselect(w http.ResponseWriter, selector string) {
+	w.Header().Set(HeaderReselect, selector)
+}
+
+// LocationOptions are the options for HX-Location.
+// See https://htmx.org/headers/hx-location/
+type LocationOptions struct {
+	Path    string            `json:"path"`
+	Source  string            `json:"source,omitempty"`
+	Event   string            `json:"event,omitempty"`
+	Handler string            `json:"handler,omitempty"`
+	Target  string            `json:"target,omitempty"`
+	Swap    string            `json:"swap,omitempty"`
+	Values  map[string]any    `json:"values,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Select  string            `json:"select,omitempty"`
+}
+
+// Location does a client-side redirect without a full page reload.
+func Location(w http.ResponseWriter, opts LocationOptions) error {
+	simple := opts.Source == "" && opts.Event == "" && opts.Handler == "" && opts.Target == "" &&
+		opts.Swap == "" && opts.Select == "" && len(opts.Values) == 0 && len(opts.Headers) == 0
+
+	// A plain path does not need a JSON object.
+	if simple {
+		w.Header().Set(HeaderLocation, opts.Path)
+		return nil
+	}
+
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(HeaderLocation, string(b))
+	return nil
+}
+
+// Trigger triggers a client-side event as soon as the response is received.
+// detail is sent as the event detail and may be nil.
+// Calling Trigger several times triggers all the events.
+func Trigger(w http.ResponseWriter, event string, detail any) error {
+	return addTrigger(w, HeaderTrigger, event, detail)
+}
+
+// TriggerAfterSettle triggers a client-side event after the settle step.
+func TriggerAfterSettle(w http.ResponseWriter, event string, detail any) error {
+	return addTrigger(w, HeaderTriggerAfterSettle, event, detail)
+}
+
+// TriggerAfterSwap triggers a client-side event after the swap step.
+func TriggerAfterSwap(w http.ResponseWriter, event string, detail any) error {
+	return addTrigger(w, HeaderTriggerAfterSwap, event, detail)
+}
+
+// addTrigger merges event into the JSON object stored in header.
+func addTrigger(w http.ResponseWriter, header, event string, detail any) error {
+	events := make(map[string]any)
+
+	if existing := w.Header().Get(header); existing != "" {
+		if err := json.Unmarshal([]byte(existing), &events); err != nil {
+			// Plain comma separated event names.
+			for _, name := range strings.Split(existing, ",") {
+				events[strings.TrimSpace(name)] = nil
+			}
+		}
+	}
+	events[event] = detail
+
+	b, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(header, string(b))
+	return nil
+}
+
+// OOBAttr returns the hx-swap-oob attribute for an element.
+// swap is a swap strategy optionally followed by ":selector".
+//
+//	<div id="alerts" {{ oob "beforeend" }}>...</div>
+func OOBAttr(swap string) template.HTMLAttr {
+	if swap == "" {
+		swap = "true"
+	}
+	return template.HTMLAttr(`hx-swap-oob="` + template.HTMLEscapeString(swap) + `"`)
+}
+
+// WriteOOB writes content wrapped in a div swapped out of band into target
+// using the swap strategy, e.g WriteOOB(w, "innerHTML", "#cart-count", "3").
+func WriteOOB(w io.Writer, swap, target string, content template.HTML) error {
+	if swap == "" {
+		swap = SwapInnerHTML
+	}
+
+	oob := swap
+	if target != "" {
+		oob = swap + ":" + target
+	}
+
+	_, err := io.WriteString(w, "<div "+string(OOBAttr(oob))+">"+string(content)+"</div>")
+	return err
+}
+
+// RenderOOB executes the template name(without a layout) and writes it as an
+// out-of-band swap into target. Use it after the main response to update
+// other parts of the page in the same response.
+//
+//	gor.Render(w, req, "todos/row.html", data)
+//	htmx.RenderOOB(w, req, "todos/count.html", data, htmx.SwapInnerHTML, "#todo-count")
+func RenderOOB(w io.Writer, req *http.Request, name string, data gor.Map, swap, target string) error {
+	var buf bytes.Buffer
+	if err := gor.ExecuteTemplate(&buf, req, name, data); err != nil {
+		return err
+	}
+	return WriteOOB(w, swap, target, template.HTML(buf.String()))
+}
+
+// FuncMap returns template functions for htmx:
+//
+//	oob: returns the hx-swap-oob attribute. See OOBAttr.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"oob": OOBAttr,
+	}
+}
+
+// Vary is a middleware that adds "Vary: HX-Request" to all responses,
+// so that caches keep full page and partial responses apart.
+func Vary(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", HeaderRequest)
+		next.ServeHTTP(w, req)
+	})
+}
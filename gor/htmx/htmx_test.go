@@ -0,0 +1,127 @@
+package htmx
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+func TestNewRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderRequest, "true")
+	req.Header.Set(HeaderTarget, "users")
+	req.Header.Set(HeaderCurrentURL, "http://localhost/users")
+
+	hx := NewRequest(req)
+	if !hx.Enabled || hx.Boosted || hx.Target != "users" || hx.CurrentURL != "http://localhost/users" {
+		t.Errorf("unexpected request %+v", hx)
+	}
+
+	if !IsPartial(req) {
+		t.Errorf("expected non-boosted request to be partial")
+	}
+
+	req.Header.Set(HeaderBoosted, "true")
+	if IsPartial(req) {
+		t.Errorf("expected boosted request not to be partial")
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(HeaderTrigger, "first, second")
+
+	Trigger(w, "saved", map[string]int{"id": 1})
+	Trigger(w, "closeModal", nil)
+
+	var events map[string]any
+	if err := json.Unmarshal([]byte(w.Header().Get(HeaderTrigger)), &events); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 4 {
+		t.Errorf("expected 4 events, got %v", events)
+	}
+
+	if detail, ok := events["saved"].(map[string]any); !ok || detail["id"] != float64(1) {
+		t.Errorf("unexpected saved detail %v", events["saved"])
+	}
+}
+
+func TestLocation(t *testing.T) {
+	w := httptest.NewRecorder()
+	Location(w, LocationOptions{Path: "/users"})
+	if got := w.Header().Get(HeaderLocation); got != "/users" {
+		t.Errorf("expected plain path, got %q", got)
+	}
+
+	Location(w, LocationOptions{Path: "/users", Target: "#main"})
+	if got := w.Header().Get(HeaderLocation); got != `{"path":"/users","target":"#main"}` {
+		t.Errorf("unexpected location %q", got)
+	}
+}
+
+func TestWriteOOB(t *testing.T) {
+	var sb strings.Builder
+	WriteOOB(&sb, SwapInnerHTML, "#count", template.HTML("<b>3</b>"))
+
+	expected := `<div hx-swap-oob="innerHTML:#count"><b>3</b></div>`
+	if sb.String() != expected {
+		t.Errorf("expected %q, got %q", expected, sb.String())
+	}
+}
+
+func TestRenderSkipsLayout(t *testing.T) {
+	templ, err := gor.ParseTemplatesRecursive("../../cmd/server/templates", template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gor.NewRouter(gor.WithTemplates(templ), gor.BaseLayout("base.html"))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "home", gor.Map{"Title": "Home", "Body": "Body"})
+		RenderOOB(w, req, "about.html", gor.Map{"Title": "About"}, SwapOuterHTML, "#about")
+	})
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		hasLayout bool
+	}{
+		{"plain", nil, true},
+		{"htmx", map[string]string{HeaderRequest: "true"}, false},
+		{"boosted", map[string]string{HeaderRequest: "true", HeaderBoosted: "true"}, true},
+		{"history restore", map[string]string{HeaderRequest: "true", HeaderHistoryRestoreRequest: "true"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			body := w.Body.String()
+			if strings.Contains(body, "<!DOCTYPE html>") != tt.hasLayout {
+				t.Errorf("expected layout=%v, got %q", tt.hasLayout, body)
+			}
+
+			if !strings.Contains(body, `<div hx-swap-oob="outerHTML:#about"><h1>About</h1>`) {
+				t.Errorf("expected out of band swap, got %q", body)
+			}
+
+			// The page and the partial share the URL, caches must tell them apart.
+			if vary := w.Header().Get("Vary"); !strings.Contains(vary, HeaderRequest) || !strings.Contains(vary, HeaderHistoryRestoreRequest) {
+				t.Errorf("expected Vary on the htmx headers, got %q", vary)
+			}
+		})
+	}
+}
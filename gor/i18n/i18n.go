@@ -0,0 +1,511 @@
+/*
+Package i18n provides message catalogs, plural rules and per request locale negotiation.
+
+Catalogs are JSON files named after their locale(e.g "en.json", "lg.json").
+Nested objects are flattened into dotted keys and objects with plural
+categories("zero", "one", "few", "many", "other") are plural messages:
+
+	{
+		"greeting": "Hello {name}",
+		"inbox": {
+			"title": "Inbox",
+			"messages": {"zero": "No messages", "one": "{count} message", "other": "{count} messages"}
+		}
+	}
+
+Usage:
+
+	//go:embed locales
+	var locales embed.FS
+
+	bundle := i18n.NewBundle("en")
+	if err := bundle.LoadFS(locales, "locales"); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gor.NewRouter(gor.PassContextToViews(true), gor.WithTemplates(t))
+	r.Use(bundle.Middleware(i18n.WithCookie("lang")))
+
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.SendString(w, i18n.T(req, "inbox.messages", "count", 3))
+	})
+
+In templates(parsed with bundle.FuncMap()):
+
+	{{ T .locale "greeting" "name" .User.Name }}
+*/
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// Plural categories as defined by CLDR.
+const (
+	Zero  = "zero"
+	One   = "one"
+	Two   = "two"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+var pluralCategories = []string{Zero, One, Two, Few, Many, Other}
+
+// PluralRule returns the plural category of n.
+type PluralRule func(n float64) string
+
+var (
+	pluralMu    sync.RWMutex
+	pluralRules = map[string]PluralRule{
+		"en": oneOther,
+		"lg": oneOther,
+		"sw": oneOther,
+		"de": oneOther,
+		"es": oneOther,
+		"fr": func(n float64) string {
+			if n >= 0 && n < 2 {
+				return One
+			}
+			return Other
+		},
+	}
+)
+
+func oneOther(n float64) string {
+	if n == 1 {
+		return One
+	}
+	return Other
+}
+
+// RegisterPluralRule sets the plural rule of a language, e.g "ru".
+// Languages without a rule use the English rule(one for 1, other otherwise).
+func RegisterPluralRule(lang string, rule PluralRule) {
+	pluralMu.Lock()
+	defer pluralMu.Unlock()
+	pluralRules[strings.ToLower(lang)] = rule
+}
+
+func pluralRule(locale string) PluralRule {
+	pluralMu.RLock()
+	defer pluralMu.RUnlock()
+
+	locale = strings.ToLower(locale)
+	if rule, ok := pluralRules[locale]; ok {
+		return rule
+	}
+
+	if rule, ok := pluralRules[baseLanguage(locale)]; ok {
+		return rule
+	}
+	return oneOther
+}
+
+// message is a translated message, either plain text or plural forms.
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+// Bundle holds the message catalogs of all locales.
+// It is safe for concurrent use.
+type Bundle struct {
+	defaultLocale string
+
+	mu       sync.RWMutex
+	catalogs map[string]map[string]message // locale => key => message
+}
+
+// NewBundle creates an empty bundle. Messages missing in a locale fall back
+// to defaultLocale, and finally to the key itself.
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{
+		defaultLocale: normalize(defaultLocale),
+		catalogs:      make(map[string]map[string]message),
+	}
+}
+
+// DefaultLocale returns the default locale of the bundle.
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// LoadFS loads all "<locale>.json" files in dir of fsys.
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		locale := strings.TrimSuffix(entry.Name(), ".json")
+		if err := b.LoadJSON(locale, data); err != nil {
+			return fmt.Errorf("i18n: %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// LoadJSON adds the messages of a JSON catalog to locale.
+// Existing messages with the same keys are replaced.
+func (b *Bundle) LoadJSON(locale string, data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	messages := make(map[string]message)
+	if err := flatten("", raw, messages); err != nil {
+		return err
+	}
+
+	locale = normalize(locale)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	catalog, ok := b.catalogs[locale]
+	if !ok {
+		catalog = make(map[string]message, len(messages))
+		b.catalogs[locale] = catalog
+	}
+
+	for key, msg := range messages {
+		catalog[key] = msg
+	}
+	return nil
+}
+
+// flatten converts nested objects to dotted keys.
+func flatten(prefix string, raw map[string]any, messages map[string]message) error {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case string:
+			messages[key] = message{text: v}
+		case map[string]any:
+			if plural, ok := pluralForms(v); ok {
+				messages[key] = message{plural: plural, text: plural[Other]}
+				continue
+			}
+
+			if err := flatten(key, v, messages); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid message %q: expected a string or object, got %T", key, value)
+		}
+	}
+	return nil
+}
+
+// pluralForms returns the forms if all keys of v are plural categories with string values.
+func pluralForms(v map[string]any) (map[string]string, bool) {
+	if len(v) == 0 {
+		return nil, false
+	}
+
+	forms := make(map[string]string, len(v))
+	for key, value := range v {
+		s, ok := value.(string)
+		if !ok || !isPluralCategory(key) {
+			return nil, false
+		}
+		forms[key] = s
+	}
+	return forms, true
+}
+
+func isPluralCategory(key string) bool {
+	for _, category := range pluralCategories {
+		if key == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Locales returns the sorted locales that have a catalog.
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	locales := make([]string, 0, len(b.catalogs))
+	for locale := range b.catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// HasLocale reports whether the bundle has a catalog for locale.
+func (b *Bundle) HasLocale(locale string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.catalogs[normalize(locale)]
+	return ok
+}
+
+// lookup finds key in locale, its base language and the default locale.
+func (b *Bundle) lookup(locale, key string) (message, string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	locale = normalize(locale)
+	for _, candidate := range []string{locale, baseLanguage(locale), b.defaultLocale} {
+		if msg, ok := b.catalogs[candidate][key]; ok {
+			return msg, candidate, true
+		}
+	}
+	return message{}, locale, false
+}
+
+// Has reports whether key is translated in locale(or its fallbacks).
+func (b *Bundle) Has(locale, key string) bool {
+	_, _, ok := b.lookup(locale, key)
+	return ok
+}
+
+// Translate returns the message key in locale.
+//
+// args are the values interpolated into "{name}" placeholders, given as key-value
+// pairs or a single map[string]any(or gor.Map). The "count" argument selects the
+// plural form of plural messages:
+//
+//	b.Translate("en", "inbox.messages", "count", 2) // "2 messages"
+//
+// If the message does not exist, the key is returned.
+func (b *Bundle) Translate(locale, key string, args ...any) string {
+	params := toParams(args)
+
+	msg, found, ok := b.lookup(locale, key)
+	if !ok {
+		return interpolate(key, params)
+	}
+
+	text := msg.text
+	if msg.plural != nil {
+		if count, ok := params["count"]; ok {
+			text = pluralForm(msg.plural, found, toFloat(count))
+		}
+	}
+	return interpolate(text, params)
+}
+
+// pluralForm picks the form for n. An explicit "zero" form is used for 0 in every language.
+func pluralForm(forms map[string]string, locale string, n float64) string {
+	if n == 0 {
+		if form, ok := forms[Zero]; ok {
+			return form
+		}
+	}
+
+	if form, ok := forms[pluralRule(locale)(n)]; ok {
+		return form
+	}
+	return forms[Other]
+}
+
+// toParams converts key-value pairs or a map to interpolation parameters.
+func toParams(args []any) map[string]any {
+	if len(args) == 1 {
+		switch m := args[0].(type) {
+		case map[string]any:
+			return m
+		case gor.Map:
+			return m
+		case map[string]string:
+			params := make(map[string]any, len(m))
+			for k, v := range m {
+				params[k] = v
+			}
+			return params
+		}
+	}
+
+	params := make(map[string]any, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		params[fmt.Sprint(args[i])] = args[i+1]
+	}
+	return params
+}
+
+// interpolate replaces "{name}" placeholders with params. Unknown placeholders are kept.
+func interpolate(text string, params map[string]any) string {
+	if len(params) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		name := text[start+1 : end]
+		value, ok := params[name]
+
+		sb.WriteString(text[:start])
+		if ok {
+			sb.WriteString(fmt.Sprint(value))
+		} else {
+			sb.WriteString(text[start : end+1])
+		}
+		text = text[end+1:]
+	}
+	sb.WriteString(text)
+	return sb.String()
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int8:
+		return float64(n)
+	case int16:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint:
+		return float64(n)
+	case uint8:
+		return float64(n)
+	case uint16:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		f, _ := strconv.ParseFloat(fmt.Sprint(v), 64)
+		return f
+	}
+}
+
+// FuncMap returns the template functions of the bundle:
+//
+//	T: translates a message. {{ T .locale "inbox.messages" "count" .Count }}
+//
+// The locale is set in the "locale" template variable by Middleware when
+// gor.PassContextToViews is enabled.
+func (b *Bundle) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"T": func(locale any, key string, args ...any) string {
+			l, _ := locale.(string)
+			return b.Translate(l, key, args...)
+		},
+	}
+}
+
+// Keys of the translated BodyParser errors. The "field" argument is the name of the
+// struct field, translated with the key "fields.<Field>" if that message exists.
+const (
+	ErrorKeyPrefix = "errors."
+	FieldKeyPrefix = "fields."
+)
+
+// TranslateError translates a gor.FormError or gor.ValidationErrors using the messages
+// "errors.<kind>", e.g "errors.required_field_missing": "{field} is required".
+// Other errors, and errors without a message, return err.Error().
+func (b *Bundle) TranslateError(locale string, err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var verrs gor.ValidationErrors
+	if errors.As(err, &verrs) {
+		msgs := make([]string, len(verrs))
+		for i, e := range verrs {
+			msgs[i] = b.translateFormError(locale, e)
+		}
+		return strings.Join(msgs, "; ")
+	}
+
+	var ferr gor.FormError
+	if errors.As(err, &ferr) {
+		return b.translateFormError(locale, ferr)
+	}
+	return err.Error()
+}
+
+// FieldErrors translates the errors of a failed parse into messages per field,
+// ready to be displayed with gor.RenderForm:
+//
+//	gor.RenderForm(user, gor.FormOptions{Errors: bundle.FieldErrors(i18n.Locale(req), err)})
+//
+// Errors that are not tied to a field are returned unchanged.
+func (b *Bundle) FieldErrors(locale string, err error) error {
+	var verrs gor.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make(gor.FieldErrors, len(verrs))
+		for _, e := range verrs {
+			fields[e.Field] = b.translateFormError(locale, e)
+		}
+		return fields
+	}
+
+	var ferr gor.FormError
+	if errors.As(err, &ferr) && ferr.Field != "" {
+		return gor.FieldErrors{ferr.Field: b.translateFormError(locale, ferr)}
+	}
+	return err
+}
+
+func (b *Bundle) translateFormError(locale string, e gor.FormError) string {
+	key := ErrorKeyPrefix + string(e.Kind)
+	if !b.Has(locale, key) {
+		if e.Err != nil {
+			return e.Err.Error()
+		}
+		return e.Error()
+	}
+
+	field := e.Field
+	if b.Has(locale, FieldKeyPrefix+field) {
+		field = b.Translate(locale, FieldKeyPrefix+field)
+	}
+
+	detail := ""
+	if e.Err != nil {
+		detail = e.Err.Error()
+	}
+	return b.Translate(locale, key, "field", field, "error", detail)
+}
@@ -0,0 +1,269 @@
+package i18n_test
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/i18n"
+)
+
+func newBundle(t *testing.T) *i18n.Bundle {
+	t.Helper()
+
+	bundle := i18n.NewBundle("en")
+	if err := bundle.LoadFS(os.DirFS("testdata"), "locales"); err != nil {
+		t.Fatal(err)
+	}
+	return bundle
+}
+
+func TestTranslate(t *testing.T) {
+	bundle := newBundle(t)
+
+	tests := []struct {
+		locale string
+		key    string
+		args   []any
+		want   string
+	}{
+		{"en", "greeting", []any{"name", "Jane"}, "Hello Jane"},
+		{"lg", "greeting", []any{"name", "Jane"}, "Gyebale Jane"},
+		{"lg", "greeting", []any{gor.Map{"name": "Jane"}}, "Gyebale Jane"},
+		{"en-US", "greeting", []any{"name", "Jane"}, "Hello Jane"},
+		{"en", "greeting", nil, "Hello {name}"},
+		{"en", "inbox.messages", []any{"count", 0}, "No messages"},
+		{"en", "inbox.messages", []any{"count", 1}, "1 message"},
+		{"en", "inbox.messages", []any{"count", 5}, "5 messages"},
+		{"lg", "inbox.messages", []any{"count", 0}, "Obubaka 0"},
+		{"lg", "inbox.title", nil, "Inbox"}, // Falls back to the default locale.
+		{"fr", "inbox.title", nil, "Inbox"},
+		{"en", "missing.key", nil, "missing.key"},
+	}
+
+	for _, tt := range tests {
+		if got := bundle.Translate(tt.locale, tt.key, tt.args...); got != tt.want {
+			t.Errorf("Translate(%q, %q, %v) = %q, want %q", tt.locale, tt.key, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestPluralRule(t *testing.T) {
+	bundle := i18n.NewBundle("fr")
+	err := bundle.LoadJSON("fr", []byte(`{"items": {"one": "{count} article", "other": "{count} articles"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := bundle.Translate("fr", "items", "count", 0); got != "0 article" {
+		t.Errorf("expected the French rule to use one for 0, got %q", got)
+	}
+
+	i18n.RegisterPluralRule("fr", func(n float64) string { return i18n.Other })
+	defer i18n.RegisterPluralRule("fr", func(n float64) string {
+		if n >= 0 && n < 2 {
+			return i18n.One
+		}
+		return i18n.Other
+	})
+
+	if got := bundle.Translate("fr", "items", "count", 1); got != "1 articles" {
+		t.Errorf("expected the registered rule to be used, got %q", got)
+	}
+}
+
+func TestLoadJSONInvalid(t *testing.T) {
+	bundle := i18n.NewBundle("en")
+	if err := bundle.LoadJSON("en", []byte(`{"count": 1}`)); err == nil {
+		t.Error("expected an error for a non string message")
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := i18n.ParseAcceptLanguage("en;q=0.8, lg, fr;q=0, *;q=0.1, sw;q=0.9")
+	want := []string{"lg", "sw", "en"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	bundle := newBundle(t)
+
+	tests := []struct {
+		tags []string
+		want string
+		ok   bool
+	}{
+		{[]string{"lg"}, "lg", true},
+		{[]string{"en-GB"}, "en", true},
+		{[]string{"fr", "LG-ug"}, "lg", true},
+		{[]string{"fr"}, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := bundle.Match(tt.tags...)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Match(%v) = %q, %v, want %q, %v", tt.tags, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	bundle := newBundle(t)
+
+	r := gor.NewRouter()
+	r.Use(bundle.Middleware(i18n.WithCookie(), i18n.WithQuery("lang")))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.SendString(w, i18n.Locale(req)+":"+i18n.T(req, "greeting", "name", "Jane"))
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		cookie string
+		want   string
+	}{
+		{"default", "/", "", "", "en:Hello Jane"},
+		{"header", "/", "lg, en;q=0.5", "", "lg:Gyebale Jane"},
+		{"unsupported header", "/", "fr", "", "en:Hello Jane"},
+		{"cookie", "/", "en", "lg", "lg:Gyebale Jane"},
+		{"query", "/?lang=en", "", "lg", "en:Hello Jane"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Accept-Language", tt.header)
+			}
+
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: i18n.DefaultCookieName, Value: tt.cookie})
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, w.Body.String())
+			}
+
+			locale, _, _ := strings.Cut(tt.want, ":")
+			if got := w.Header().Get("Content-Language"); got != locale {
+				t.Errorf("expected Content-Language %q, got %q", locale, got)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePathPrefix(t *testing.T) {
+	bundle := newBundle(t)
+
+	r := gor.NewRouter()
+	r.Get("/users", func(w http.ResponseWriter, req *http.Request) {
+		gor.SendString(w, i18n.Locale(req)+" "+req.URL.Path)
+	})
+
+	handler := bundle.Middleware(i18n.WithPathPrefix())(r)
+
+	for path, want := range map[string]string{
+		"/lg/users": "lg /users",
+		"/en/users": "en /users",
+		"/users":    "en /users",
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Body.String() != want {
+			t.Errorf("%s: expected %q, got %q", path, want, w.Body.String())
+		}
+	}
+}
+
+func TestMiddlewarePathPrefixTemplate(t *testing.T) {
+	bundle := newBundle(t)
+
+	tmpl := template.Must(template.New("page.html").Funcs(bundle.FuncMap()).Parse(
+		`{{ .locale }}: {{ T .locale "greeting" "name" "Jane" }}`))
+
+	localize := bundle.Middleware(i18n.WithPathPrefix())
+	r := gor.NewRouter(gor.WithTemplates(tmpl), gor.PassContextToViews(true))
+	r.Use(localize)
+	r.Get("/users", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "page.html", gor.Map{})
+	})
+
+	handler := localize(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/lg/users", nil)
+	req.Header.Set("Accept-Language", "en")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Body.String() != "lg: Gyebale Jane" {
+		t.Errorf("expected the path locale in the template, got %q", w.Body.String())
+	}
+
+	// The middleware runs twice, the locale is negotiated once.
+	if vary := w.Header().Values("Vary"); !slices.Contains(vary, "Accept-Language") ||
+		strings.Count(strings.Join(vary, ","), "Accept-Language") != 1 {
+		t.Errorf("expected a single Vary: Accept-Language, got %v", vary)
+	}
+}
+
+func TestTemplateFunc(t *testing.T) {
+	bundle := newBundle(t)
+
+	tmpl := template.Must(template.New("page.html").Funcs(bundle.FuncMap()).Parse(
+		`<h1>{{ T .locale "inbox.title" }}</h1><p>{{ T .locale "inbox.messages" "count" .count }}</p>`))
+
+	r := gor.NewRouter(gor.WithTemplates(tmpl), gor.PassContextToViews(true))
+	r.Use(bundle.Middleware())
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "page.html", gor.Map{"count": 2})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "lg")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	want := "<h1>Inbox</h1><p>Obubaka 2</p>"
+	if w.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, w.Body.String())
+	}
+}
+
+func TestTranslateError(t *testing.T) {
+	bundle := newBundle(t)
+
+	err := gor.ValidationErrors{
+		{Field: "Email", Kind: gor.RequiredFieldMissing, Err: errors.New("Email is required")},
+		{Field: "Age", Kind: gor.ParseError, Err: errors.New("invalid age")},
+	}
+
+	want := "Endagiriro ya email yeetaagibwa; invalid age"
+	if got := bundle.TranslateError("lg", err); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	fields := bundle.FieldErrors("en", err)
+	wantFields := gor.FieldErrors{"Email": "Email address is required", "Age": "invalid age"}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("expected %v, got %v", wantFields, fields)
+	}
+
+	other := errors.New("boom")
+	if got := bundle.FieldErrors("en", other); got != other {
+		t.Errorf("expected the error to be returned unchanged, got %v", got)
+	}
+}
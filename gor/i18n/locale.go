@@ -0,0 +1,262 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+type contextType string
+
+// localeContextKey is the key of the locale in the request context and CTX locals.
+// Its name is the template variable LocaleKey.
+const (
+	localeContextKey = contextType(LocaleKey)
+	bundleContextKey = contextType("bundle")
+)
+
+// LocaleKey is the name of the template variable holding the request locale
+// when gor.PassContextToViews is enabled.
+const LocaleKey = "locale"
+
+// DefaultCookieName is the cookie read by WithCookie when no name is given.
+const DefaultCookieName = "lang"
+
+type negotiator struct {
+	pathPrefix bool
+	cookie     string
+	query      string
+}
+
+// Option configures the locale negotiation of Bundle.Middleware.
+type Option func(*negotiator)
+
+// WithPathPrefix picks the locale from the first path segment(e.g "/lg/users")
+// and removes it from the request path.
+//
+// The router matches routes before running middleware, so wrap the router to
+// serve "/lg/users" with the "/users" route. The router context, and the template
+// locals with it, only exist inside the router: also register the middleware with
+// Use so that templates get {{ .locale }}. The locale is only negotiated once.
+//
+//	localize := bundle.Middleware(i18n.WithPathPrefix())
+//	r.Use(localize)
+//	http.ListenAndServe(":8080", localize(r))
+func WithPathPrefix() Option {
+	return func(n *negotiator) {
+		n.pathPrefix = true
+	}
+}
+
+// WithCookie picks the locale from a cookie. The default name is "lang".
+// See SetCookie.
+func WithCookie(name ...string) Option {
+	return func(n *negotiator) {
+		n.cookie = DefaultCookieName
+		if len(name) > 0 && name[0] != "" {
+			n.cookie = name[0]
+		}
+	}
+}
+
+// WithQuery picks the locale from a query parameter, e.g "?lang=lg".
+func WithQuery(name string) Option {
+	return func(n *negotiator) {
+		n.query = name
+	}
+}
+
+// Middleware selects the locale of each request among the locales of the bundle.
+// The sources are tried in order: path prefix, query, cookie, Accept-Language header
+// and finally the default locale. Only enabled sources are used, except for the
+// Accept-Language header which is always used.
+//
+// The locale is available with Locale(req) and, if gor.PassContextToViews is enabled,
+// in templates as {{ .locale }}. The Content-Language response header is set.
+func (b *Bundle) Middleware(options ...Option) gor.Middleware {
+	n := &negotiator{}
+	for _, option := range options {
+		option(n)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// Already negotiated by the same middleware wrapping the router.
+			locale, negotiated := req.Context().Value(localeContextKey).(string)
+			if !negotiated {
+				locale = b.negotiate(n, req)
+
+				ctx := context.WithValue(req.Context(), bundleContextKey, b)
+				*req = *req.WithContext(ctx)
+
+				w.Header().Set("Content-Language", locale)
+				w.Header().Add("Vary", "Accept-Language")
+			}
+
+			// Stores the locale in the locals when running inside the router.
+			gor.SetContextValue(req, localeContextKey, locale)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func (b *Bundle) negotiate(n *negotiator, req *http.Request) string {
+	if n.pathPrefix {
+		segment, rest, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+		if locale, ok := b.match(segment); ok && segment != "" {
+			req.URL.Path = "/" + rest
+			req.URL.RawPath = ""
+			return locale
+		}
+	}
+
+	if n.query != "" {
+		if locale, ok := b.match(req.URL.Query().Get(n.query)); ok {
+			return locale
+		}
+	}
+
+	if n.cookie != "" {
+		if cookie, err := req.Cookie(n.cookie); err == nil {
+			if locale, ok := b.match(cookie.Value); ok {
+				return locale
+			}
+		}
+	}
+
+	if locale, ok := b.Match(ParseAcceptLanguage(req.Header.Get("Accept-Language"))...); ok {
+		return locale
+	}
+	return b.defaultLocale
+}
+
+// Match returns the first supported locale for the given language tags, in order
+// of preference. A tag matches a locale exactly, by its base language("en-US"
+// matches "en") or a locale with the same base language("en" matches "en-GB").
+func (b *Bundle) Match(tags ...string) (string, bool) {
+	for _, tag := range tags {
+		if locale, ok := b.match(tag); ok {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+func (b *Bundle) match(tag string) (string, bool) {
+	tag = normalize(tag)
+	if tag == "" {
+		return "", false
+	}
+
+	locales := b.Locales()
+	for _, locale := range locales {
+		if locale == tag {
+			return locale, true
+		}
+	}
+
+	base := baseLanguage(tag)
+	for _, locale := range locales {
+		if locale == base {
+			return locale, true
+		}
+	}
+
+	for _, locale := range locales {
+		if baseLanguage(locale) == base {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language header
+// sorted by quality, e.g "lg, en;q=0.8" gives ["lg", "en"].
+// Tags with q=0 and the wildcard "*" are skipped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = f
+				}
+			}
+		}
+
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
+
+// Locale returns the locale selected by Middleware for the request, or "" if none.
+func Locale(req *http.Request) string {
+	locale, _ := req.Context().Value(localeContextKey).(string)
+	return locale
+}
+
+// T translates key in the request locale with the bundle of Middleware.
+// It returns the key if the middleware is not used. See Bundle.Translate.
+func T(req *http.Request, key string, args ...any) string {
+	b, ok := req.Context().Value(bundleContextKey).(*Bundle)
+	if !ok {
+		return interpolate(key, toParams(args))
+	}
+	return b.Translate(Locale(req), key, args...)
+}
+
+// SetCookie stores the locale chosen by the user in a cookie read by WithCookie.
+func SetCookie(w http.ResponseWriter, locale string, name ...string) {
+	cookieName := DefaultCookieName
+	if len(name) > 0 && name[0] != "" {
+		cookieName = name[0]
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    locale,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// normalize lower-cases a language tag and uses "-" as separator, e.g "en_US" gives "en-us".
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// baseLanguage returns the language subtag, e.g "en" for "en-us".
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return base
+}
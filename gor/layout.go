@@ -0,0 +1,323 @@
+package gor
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// NoLayout renders a page without any layout when used as a layout name.
+//
+//	gor.RenderLayout(w, req, "reports/invoice.html", gor.NoLayout, data)
+const NoLayout = "-"
+
+type layoutContextType string
+
+const layoutContextKey = layoutContextType("layout")
+
+// LayoutParent declares parent as the layout that wraps layout.
+// Layouts can be nested any number of levels, e.g root → dashboard → page.
+// The rendered child layout is passed to the parent in the content block.
+//
+// Example:
+//
+//	r := gor.NewRouter(
+//		gor.BaseLayout("layouts/root.html"),
+//		gor.LayoutParent("layouts/dashboard.html", "layouts/root.html"),
+//	)
+func LayoutParent(layout, parent string) RouterOption {
+	return func(r *Router) {
+		if r.layoutParents == nil {
+			r.layoutParents = make(map[string]string)
+		}
+		r.layoutParents[layout] = parent
+	}
+}
+
+// LayoutBlocks sets the names of extra content blocks that pages(and child layouts)
+// can fill for their layouts, in addition to the content block.
+//
+// A page defines a block with {{ define "Scripts" }}...{{ end }} and the layout
+// outputs it with {{ .Scripts }}. If both a page and a child layout define a block,
+// the page wins.
+//
+// Example:
+//
+//	r := gor.NewRouter(gor.LayoutBlocks("Title", "Scripts"))
+func LayoutBlocks(blocks ...string) RouterOption {
+	return func(r *Router) {
+		r.layoutBlocks = append(r.layoutBlocks, blocks...)
+	}
+}
+
+// UseLayout is a middleware that selects the layout for the routes it is applied to.
+// Use NoLayout to render the routes without a layout.
+//
+//	r.Get("/invoice/{id}", invoiceHandler, gor.UseLayout("layouts/print.html"))
+func UseLayout(layout string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			SetLayout(req, layout)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// SetLayout selects the layout used by subsequent Render calls for this request.
+func SetLayout(req *http.Request, layout string) {
+	ctx := context.WithValue(req.Context(), layoutContextKey, layout)
+	*req = *req.WithContext(ctx)
+}
+
+// Layout sets the layout for routes registered on the group after this call.
+func (g *Group) Layout(layout string) {
+	g.Use(UseLayout(layout))
+}
+
+// resolveLayout returns the layout selected for the request or the base layout.
+func (r *Router) resolveLayout(req *http.Request) string {
+	if req != nil {
+		if layout, ok := req.Context().Value(layoutContextKey).(string); ok && layout != "" {
+			return layout
+		}
+	}
+	return r.baseLayout
+}
+
+// layoutChain returns layout followed by its ancestors, innermost first.
+func (r *Router) layoutChain(layout string) []string {
+	if layout == "" || layout == NoLayout {
+		return nil
+	}
+
+	chain := []string{layout}
+	seen := map[string]bool{layout: true}
+	for {
+		parent, ok := r.layoutParents[layout]
+		if !ok || parent == "" || parent == NoLayout {
+			return chain
+		}
+
+		if seen[parent] {
+			log.Printf("layout cycle detected at %q\n", parent)
+			return chain
+		}
+		seen[parent] = true
+		chain = append(chain, parent)
+		layout = parent
+	}
+}
+
+// fillLayoutBlocks renders the layout blocks defined in tmpl into data,
+// unless they were already filled by an inner template.
+func (r *Router) fillLayoutBlocks(root *template.Template, tmpl string, data Map, filled map[string]bool) error {
+	for _, block := range r.layoutBlocks {
+		if filled[block] {
+			continue
+		}
+
+		t := root.Lookup(FragmentName(tmpl, block))
+		if t == nil {
+			continue
+		}
+
+		content, err := r.executeToString(t, data)
+		if err != nil {
+			return err
+		}
+
+		data[block] = template.HTML(content)
+		filled[block] = true
+	}
+	return nil
+}
+
+// executeToString executes t into a string. The strings.Builder hands its memory over
+// to the string, so the output is not copied. The builder is sized from the previous
+// execution of t to avoid growing it.
+func (r *Router) executeToString(t *template.Template, data Map) (string, error) {
+	var sb strings.Builder
+
+	size := 0
+	if v, ok := r.blockSizes.Load(t.Name()); ok {
+		size = v.(int)
+		sb.Grow(size)
+	}
+
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+
+	if sb.Len() != size {
+		r.blockSizes.Store(t.Name(), sb.Len())
+	}
+	return sb.String(), nil
+}
+
+// renderWithLayouts renders the page and wraps it in each layout of chain in turn.
+// Nothing is written to w if rendering fails, unless the layout is streamed(see StreamLayout)
+// and the page fails after the head of the layout was flushed.
+func (r *Router) renderWithLayouts(w io.Writer, name string, chain []string, data Map) error {
+	err := r.executeWithLayouts(w, name, chain, data)
+	if err != nil {
+		log.Printf("Error rendering template: %s\n", err)
+	}
+	return err
+}
+
+func (r *Router) executeWithLayouts(w io.Writer, name string, chain []string, data Map) error {
+	// if name is missing the extension, add it(assume it's an html file)
+	if filepath.Ext(name) == "" {
+		name = name + ".html"
+	}
+
+	// Use the same template set throughout, even if templates are reloaded meanwhile.
+	root := r.templates()
+
+	page := root.Lookup(name)
+	if page == nil {
+		return templateNotFound("template", name)
+	}
+
+	layouts := make([]*template.Template, len(chain))
+	for i, layout := range chain {
+		if layouts[i] = root.Lookup(layout); layouts[i] == nil {
+			return templateNotFound("layout", layout)
+		}
+	}
+
+	filled := make(map[string]bool, len(r.layoutBlocks))
+	if err := r.fillLayoutBlocks(root, name, data, filled); err != nil {
+		return err
+	}
+
+	for _, layout := range chain {
+		if err := r.fillLayoutBlocks(root, layout, data, filled); err != nil {
+			return err
+		}
+	}
+
+	if len(layouts) > 0 {
+		shell, head, tail, split, err := r.renderShell(layouts, data)
+		defer putBuffer(shell)
+
+		if err != nil {
+			return err
+		}
+
+		if split {
+			return r.writeInShell(w, page, head, tail, data)
+		}
+	}
+
+	// The layouts could not be split: render the page and the layouts in turn.
+	buf := getBuffer()
+	defer func() { putBuffer(buf) }()
+
+	if err := page.Execute(buf, data); err != nil {
+		return err
+	}
+
+	for _, layout := range layouts {
+		data[r.contentBlock] = template.HTML(buf.String())
+
+		next := getBuffer()
+		if err := layout.Execute(next, data); err != nil {
+			putBuffer(next)
+			return err
+		}
+		putBuffer(buf)
+		buf = next
+	}
+
+	if writer, ok := w.(http.ResponseWriter); ok {
+		writer.Header().Set("Content-Type", ContentTypeHTML)
+		writer.WriteHeader(http.StatusOK)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeInShell writes the page between the head and tail of the layouts, streaming
+// the head first if enabled. The page is rendered once, directly into the output.
+func (r *Router) writeInShell(w io.Writer, page *template.Template, head, tail []byte, data Map) error {
+	if r.streamLayout {
+		if writer, ok := w.(http.ResponseWriter); ok {
+			if flusher, ok := w.(http.Flusher); ok {
+				return r.streamPage(writer, flusher, page, head, tail, data)
+			}
+		}
+	}
+
+	// Assembled in a pooled buffer to write the response at once.
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.Write(head)
+	if err := page.Execute(buf, data); err != nil {
+		return err
+	}
+	buf.Write(tail)
+
+	if writer, ok := w.(http.ResponseWriter); ok {
+		writer.Header().Set("Content-Type", ContentTypeHTML)
+		writer.WriteHeader(http.StatusOK)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// RenderLayout renders the page inside layout, overriding the layout selected
+// for the request. An empty layout selects the layout of the request, like Render.
+// Use NoLayout to render the page on its own.
+func (r *Router) RenderLayout(w io.Writer, req *http.Request, name, layout string, data Map) {
+	if err := r.reloadTemplates(); err != nil {
+		r.writeRenderError(w, err)
+		return
+	}
+
+	if r.templates() == nil {
+		panic("No template is configured")
+	}
+
+	if data == nil {
+		data = Map{}
+	}
+	r.passLocals(req, data)
+
+	if layout == "" {
+		layout = r.resolveLayout(req)
+	}
+
+	var err error
+	if layout == "" || layout == NoLayout {
+		if filepath.Ext(name) == "" {
+			name = name + ".html"
+		}
+
+		if writer, ok := w.(http.ResponseWriter); ok {
+			writer.Header().Set("Content-Type", ContentTypeHTML)
+		}
+		err = r.ExecuteTemplate(w, name, data)
+	} else {
+		err = r.renderWithLayouts(w, name, r.layoutChain(layout), data)
+	}
+	r.writeRenderError(w, err)
+}
+
+// RenderLayout renders the page inside layout.
+// It is an alias for gor.Router.RenderLayout.
+// Make sure you are using gor.Router. Otherwise this function will panic.
+func RenderLayout(w io.Writer, req *http.Request, name, layout string, data Map) {
+	ctx, ok := req.Context().Value(contextKey).(*CTX)
+	if !ok {
+		panic("You are not using gor.Router. You cannot use this function")
+	}
+	ctx.Router.RenderLayout(w, req, name, layout, data)
+}
@@ -0,0 +1,80 @@
+package gor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+func TestNestedLayouts(t *testing.T) {
+	templ, err := gor.ParseTemplatesRecursive("testdata/layouts", template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gor.NewRouter(
+		gor.WithTemplates(templ),
+		gor.BaseLayout("layouts/root.html"),
+		gor.LayoutParent("layouts/dashboard.html", "layouts/root.html"),
+		gor.LayoutBlocks("Title", "Scripts"),
+	)
+
+	r.Get("/users", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "pages/users", gor.Map{})
+	})
+
+	r.Get("/print", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "pages/users", gor.Map{})
+	}, gor.UseLayout("layouts/print.html"))
+
+	r.Get("/raw", func(w http.ResponseWriter, req *http.Request) {
+		gor.RenderLayout(w, req, "pages/users", gor.NoLayout, gor.Map{})
+	})
+
+	// An empty layout is the layout of the request.
+	r.Get("/default", func(w http.ResponseWriter, req *http.Request) {
+		gor.RenderLayout(w, req, "pages/users", "", gor.Map{})
+	}, gor.UseLayout("layouts/print.html"))
+
+	admin := r.Group("/admin")
+	admin.Layout("layouts/dashboard.html")
+	admin.Get("/stats", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "pages/stats.html", gor.Map{})
+	})
+	admin.Get("/users", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "pages/users.html", gor.Map{})
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/users", "<html><head><title></title></head><body><h1>Users</h1>\n</body></html>\n"},
+		{"/print", "<div class=\"print\"><h1>Users</h1>\n</div>\n"},
+		{"/raw", "<h1>Users</h1>\n"},
+		{"/default", "<div class=\"print\"><h1>Users</h1>\n</div>\n"},
+		// Page blocks win over the blocks of the dashboard layout.
+		{"/admin/stats", "<html><head><title>Stats</title><script src=\"/chart.js\"></script></head>" +
+			"<body><nav>menu</nav><main><h1>Stats</h1>\n</main>\n</body></html>\n"},
+		{"/admin/users", "<html><head><title>Dashboard</title><script src=\"/dashboard.js\"></script></head>" +
+			"<body><nav>menu</nav><main><h1>Users</h1>\n</main>\n</body></html>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			if w.Body.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, w.Body.String())
+			}
+		})
+	}
+}
@@ -80,10 +80,14 @@ func VerifyJWToken(secret, tokenString string) (jwt.MapClaims, error) {
 }
 
 // GetClaims returns the claims from the request context or nil if not found.
+// Claims of a Verifier are only returned if their type is jwt.MapClaims,
+// use ClaimsOf or TokenClaims for other types.
 func GetClaims(req *http.Request) jwt.MapClaims {
-	claims, ok := req.Context().Value(jwtClaimsKey).(jwt.MapClaims)
-	if !ok {
-		return nil
+	switch claims := req.Context().Value(jwtClaimsKey).(type) {
+	case jwt.MapClaims:
+		return claims
+	case *jwt.MapClaims:
+		return *claims
 	}
-	return claims
+	return nil
 }
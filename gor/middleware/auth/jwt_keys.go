@@ -0,0 +1,487 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultReloadInterval is the default minimum interval between the checks of the
+// key files triggered by unknown key IDs.
+const DefaultReloadInterval = 10 * time.Second
+
+// ErrUnknownKey is returned when no key of the key set matches the "kid" header of a token.
+var ErrUnknownKey = errors.New("auth: unknown signing key")
+
+// verificationKey is a key of a KeySet.
+type verificationKey struct {
+	key any    // *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey or []byte.
+	alg string // Algorithm restriction from the JWK "alg" member, if any.
+}
+
+// keySource is a file the keys of a KeySet are loaded from.
+type keySource struct {
+	path    string
+	kid     string // For PEM files.
+	jwks    bool
+	modTime time.Time
+}
+
+// KeySet holds the keys used to verify tokens, identified by their key ID("kid").
+// It is safe for concurrent use.
+//
+// Keys are loaded from PEM files and JWKS documents on disk. When a token is signed
+// with an unknown key ID, the files that changed since they were loaded are read
+// again, so signing keys can be rotated by updating the files without a restart.
+// The files are checked at most once per ReloadInterval, so tokens with made up
+// key IDs cannot make every request stat the files and wait for a reload.
+type KeySet struct {
+	// ReloadInterval is the minimum interval between the checks of the files
+	// triggered by unknown key IDs. Default is DefaultReloadInterval.
+	// Set it before the key set is used.
+	ReloadInterval time.Duration
+
+	mu      sync.RWMutex
+	keys    map[string]verificationKey
+	static  map[string]verificationKey
+	sources []*keySource
+
+	reloadMu  sync.Mutex // Serializes the checks of the files.
+	lastCheck time.Time
+}
+
+// NewKeySet creates an empty key set.
+func NewKeySet() *KeySet {
+	return &KeySet{
+		ReloadInterval: DefaultReloadInterval,
+		keys:           make(map[string]verificationKey),
+		static:         make(map[string]verificationKey),
+	}
+}
+
+// LoadJWKS creates a key set from a JWKS document(RFC 7517) on disk.
+func LoadJWKS(path string) (*KeySet, error) {
+	ks := NewKeySet()
+	if err := ks.AddJWKSFile(path); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// LoadPEM creates a key set from a PEM encoded public key, certificate or private key,
+// identified by kid. The key is also used for tokens without a "kid" header.
+func LoadPEM(kid, path string) (*KeySet, error) {
+	ks := NewKeySet()
+	if err := ks.AddPEMFile(kid, path); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// AddKey adds a key to the set. key is a *rsa.PublicKey, *ecdsa.PublicKey,
+// ed25519.PublicKey, or a []byte secret for HMAC algorithms.
+// Private keys are accepted and their public key is used.
+func (ks *KeySet) AddKey(kid string, key any) error {
+	key, err := verificationKeyOf(key)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.static[kid] = verificationKey{key: key}
+	ks.keys[kid] = verificationKey{key: key}
+	return nil
+}
+
+// AddPEMFile adds the key of a PEM file to the set.
+func (ks *KeySet) AddPEMFile(kid, path string) error {
+	return ks.addSource(&keySource{path: path, kid: kid})
+}
+
+// AddJWKSFile adds the keys of a JWKS document to the set.
+func (ks *KeySet) AddJWKSFile(path string) error {
+	return ks.addSource(&keySource{path: path, jwks: true})
+}
+
+func (ks *KeySet) addSource(src *keySource) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	keys, err := src.load()
+	if err != nil {
+		return err
+	}
+
+	ks.sources = append(ks.sources, src)
+	for kid, key := range keys {
+		ks.keys[kid] = key
+	}
+	return nil
+}
+
+// Reload reads all the files of the key set again. Keys removed from the files
+// are removed from the set. On error, the keys are left unchanged.
+func (ks *KeySet) Reload() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.reload()
+}
+
+// reloadIfChanged reads the sources again if one of them changed, unless they
+// were checked less than ReloadInterval ago. The files are stat'ed without
+// holding ks.mu, so lookups are only blocked while the keys are replaced.
+func (ks *KeySet) reloadIfChanged() error {
+	ks.reloadMu.Lock()
+	defer ks.reloadMu.Unlock()
+
+	if time.Since(ks.lastCheck) < ks.ReloadInterval {
+		return nil
+	}
+	ks.lastCheck = time.Now()
+
+	type snapshot struct {
+		path    string
+		modTime time.Time
+	}
+
+	ks.mu.RLock()
+	sources := make([]snapshot, len(ks.sources))
+	for i, src := range ks.sources {
+		sources[i] = snapshot{path: src.path, modTime: src.modTime}
+	}
+	ks.mu.RUnlock()
+
+	changed := false
+	for _, src := range sources {
+		info, err := os.Stat(src.path)
+		if err != nil || !info.ModTime().Equal(src.modTime) {
+			changed = true
+			break
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.reload()
+}
+
+// reload reads the sources again. ks.mu must be held.
+func (ks *KeySet) reload() error {
+	keys := make(map[string]verificationKey, len(ks.keys))
+	for kid, key := range ks.static {
+		keys[kid] = key
+	}
+
+	for _, src := range ks.sources {
+		loaded, err := src.load()
+		if err != nil {
+			return err
+		}
+
+		for kid, key := range loaded {
+			keys[kid] = key
+		}
+	}
+
+	ks.keys = keys
+	return nil
+}
+
+// Len returns the number of keys in the set.
+func (ks *KeySet) Len() int {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return len(ks.keys)
+}
+
+// Keyfunc returns the key to verify token with, selected by its "kid" header.
+// Tokens without a "kid" are verified with the only key of the set, if there is one.
+// The algorithm of the token must match the type of the key.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := ks.lookup(kid)
+	if !ok {
+		// The key may have been rotated.
+		if err := ks.reloadIfChanged(); err != nil {
+			return nil, err
+		}
+
+		if key, ok = ks.lookup(kid); !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+		}
+	}
+
+	if key.alg != "" && key.alg != token.Method.Alg() {
+		return nil, fmt.Errorf("auth: key %q does not allow the %s algorithm", kid, token.Method.Alg())
+	}
+
+	if !methodMatchesKey(token.Method, key.key) {
+		return nil, fmt.Errorf("auth: algorithm %s does not match the type of key %q", token.Method.Alg(), kid)
+	}
+	return key.key, nil
+}
+
+func (ks *KeySet) lookup(kid string) (verificationKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if key, ok := ks.keys[kid]; ok {
+		return key, true
+	}
+
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	return verificationKey{}, false
+}
+
+// methodMatchesKey prevents algorithm confusion, e.g an HS256 token signed
+// with a public RSA key as the secret.
+func methodMatchesKey(method jwt.SigningMethod, key any) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+	case *ecdsa.PublicKey:
+		_, ok := method.(*jwt.SigningMethodECDSA)
+		return ok
+	case ed25519.PublicKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	case []byte:
+		_, ok := method.(*jwt.SigningMethodHMAC)
+		return ok
+	}
+	return false
+}
+
+func (src *keySource) load() (map[string]verificationKey, error) {
+	info, err := os.Stat(src.path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(src.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys map[string]verificationKey
+	if src.jwks {
+		keys, err = parseJWKS(data)
+	} else {
+		var key any
+		key, err = ParsePEMKey(data)
+		if err == nil {
+			key, err = verificationKeyOf(key)
+		}
+		keys = map[string]verificationKey{src.kid: {key: key}}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("auth: %s: %w", src.path, err)
+	}
+
+	src.modTime = info.ModTime()
+	return keys, nil
+}
+
+// ParsePEMKey parses the first key of PEM encoded data: a public key(PKIX or PKCS #1),
+// a certificate or a private key(PKCS #8, PKCS #1 or SEC 1).
+func ParsePEMKey(data []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// verificationKeyOf returns the public key of private keys.
+func verificationKeyOf(key any) (any, error) {
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+
+	switch key := key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	case []byte:
+		if len(key) == 0 {
+			return nil, errors.New("auth: empty HMAC secret")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("auth: unsupported key type %T", key)
+}
+
+// jsonWebKey is a JWK(RFC 7517). Only the members used for verification are decoded.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+func parseJWKS(data []byte) (map[string]verificationKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue // Encryption keys.
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %d(%q): %w", i, jwk.Kid, err)
+		}
+		keys[jwk.Kid] = verificationKey{key: key, alg: jwk.Alg}
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+
+		switch jwk.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC coordinates")
+		}
+
+		// Reject points that are not on the curve.
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		k, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return nil, err
+		}
+		return verificationKeyOf(k)
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
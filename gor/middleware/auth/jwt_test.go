@@ -0,0 +1,408 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type userClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+}
+
+func newClaims(sub string) userClaims {
+	return userClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sub,
+			Issuer:    "https://auth.example.com",
+			Audience:  jwt.ClaimStrings{"api"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		Role: "admin",
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key crypto.PrivateKey, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "alg": "RS256",
+		"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func serve(handler http.Handler, token string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestVerifierJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path,
+		rsaJWK("rsa-1", rsaKey),
+		map[string]string{
+			"kty": "EC", "kid": "ec-1", "crv": "P-256",
+			"x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32))),
+		},
+		map[string]string{"kty": "OKP", "kid": "ed-1", "crv": "Ed25519", "x": b64(edPublic)},
+	)
+
+	keys, err := auth.LoadJWKS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gor.NewRouter()
+	r.Use(auth.NewJWT[userClaims](auth.JWTConfig{
+		Keys:     keys,
+		Issuer:   "https://auth.example.com",
+		Audience: "api",
+	}))
+
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		claims, ok := auth.ClaimsOf[userClaims](req)
+		if !ok {
+			t.Fatal("expected typed claims")
+		}
+
+		if auth.TokenClaims(req) == nil {
+			t.Error("expected TokenClaims to return the claims")
+		}
+		w.Write([]byte(claims.Subject + ":" + claims.Role))
+	})
+
+	claims := newClaims("alice")
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"RS256", sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims), http.StatusOK},
+		{"ES256", sign(t, jwt.SigningMethodES256, "ec-1", ecKey, claims), http.StatusOK},
+		{"EdDSA", sign(t, jwt.SigningMethodEdDSA, "ed-1", edKey, claims), http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, "rsa-2", rsaKey, claims), http.StatusUnauthorized},
+		{"wrong key type", sign(t, jwt.SigningMethodES256, "rsa-1", ecKey, claims), http.StatusUnauthorized},
+		{"forbidden alg", sign(t, jwt.SigningMethodRS512, "rsa-1", rsaKey, claims), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.token)
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+
+			if tt.status == http.StatusOK && w.Body.String() != "alice:admin" {
+				t.Errorf("unexpected body %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestVerifierValidation(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := auth.NewKeySet()
+	if err := keys.AddKey("", key); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := auth.NewJWT[userClaims](auth.JWTConfig{
+		Keys:     keys,
+		Issuer:   "https://auth.example.com",
+		Audience: "api",
+		Leeway:   30 * time.Second,
+	})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+
+	expiredInLeeway := newClaims("alice")
+	expiredInLeeway.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+
+	expired := newClaims("alice")
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+	wrongIssuer := newClaims("alice")
+	wrongIssuer.Issuer = "https://evil.example.com"
+
+	wrongAudience := newClaims("alice")
+	wrongAudience.Audience = jwt.ClaimStrings{"other"}
+
+	tests := []struct {
+		name   string
+		claims userClaims
+		status int
+		detail string
+	}{
+		{"within leeway", expiredInLeeway, http.StatusOK, ""},
+		{"expired", expired, http.StatusUnauthorized, "The token has expired"},
+		{"issuer", wrongIssuer, http.StatusUnauthorized, "Invalid issuer"},
+		{"audience", wrongAudience, http.StatusUnauthorized, "Invalid audience"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(handler, sign(t, jwt.SigningMethodES256, "", key, tt.claims))
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+
+			if tt.detail != "" && !strings.Contains(w.Header().Get("WWW-Authenticate"), tt.detail) {
+				t.Errorf("expected %q in WWW-Authenticate, got %q", tt.detail, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	w := serve(handler, "", "Accept", gor.ContentTypeProblemJSON)
+	if w.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("expected a bare Bearer challenge, got %q", w.Header().Get("WWW-Authenticate"))
+	}
+
+	if w.Header().Get("Content-Type") != gor.ContentTypeProblemJSON {
+		t.Errorf("expected a problem response, got %q", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestVerifierPEMAndRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pemPath := filepath.Join(dir, "public.pem")
+	err = os.WriteFile(pemPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.LoadPEM("2024", pemPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwksPath := filepath.Join(dir, "jwks.json")
+	writeJWKS(t, jwksPath)
+	if err := keys.AddJWKSFile(jwksPath); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := auth.NewVerifier[jwt.MapClaims](auth.JWTConfig{Keys: keys, Methods: []string{"RS256"}})
+	claims := jwt.MapClaims{"sub": "bob", "exp": time.Now().Add(time.Minute).Unix()}
+
+	got, err := verifier.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "2024", key, claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if (*got)["sub"] != "bob" {
+		t.Errorf("unexpected claims %v", *got)
+	}
+
+	// Rotate: publish a new key in the JWKS file.
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := sign(t, jwt.SigningMethodRS256, "2025", newKey, claims)
+	if _, err := verifier.Verify(context.Background(), token); err == nil {
+		t.Fatal("expected an error for an unpublished key")
+	}
+
+	writeJWKS(t, jwksPath, rsaJWK("2025", newKey))
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(jwksPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	// The files were checked less than ReloadInterval ago.
+	if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, auth.ErrUnknownKey) {
+		t.Fatalf("expected the reload to be rate limited, got %v", err)
+	}
+
+	keys.ReloadInterval = 0
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Fatalf("expected the rotated key to be loaded: %v", err)
+	}
+
+	if keys.Len() != 2 {
+		t.Errorf("expected 2 keys, got %d", keys.Len())
+	}
+}
+
+func TestVerifierExtractorsAndRevocation(t *testing.T) {
+	keys := auth.NewKeySet()
+	if err := keys.AddKey("", []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	revoked := map[string]bool{"revoked-id": true}
+	checker := auth.RevocationFunc(func(ctx context.Context, id string, claims jwt.Claims) (bool, error) {
+		return revoked[id], nil
+	})
+
+	r := gor.NewRouter()
+	r.Use(auth.NewJWT[userClaims](auth.JWTConfig{
+		Keys:       keys,
+		Extractors: []auth.TokenExtractor{auth.FromAuthHeader, auth.FromCookie("access_token"), auth.FromQuery("token")},
+		Revocation: checker,
+	}))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		claims, _ := auth.ClaimsOf[userClaims](req)
+		w.Write([]byte(claims.Subject))
+	})
+
+	claims := newClaims("carol")
+	claims.ID = "valid-id"
+	token := sign(t, jwt.SigningMethodHS256, "", []byte("secret"), claims)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "carol" {
+		t.Fatalf("cookie: expected carol, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?token="+token, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("query: expected 200, got %d", w.Code)
+	}
+
+	claims.ID = "revoked-id"
+	w = serve(r, sign(t, jwt.SigningMethodHS256, "", []byte("secret"), claims))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked token to be rejected, got %d", w.Code)
+	}
+}
+
+func TestVerifierOptional(t *testing.T) {
+	keys := auth.NewKeySet()
+	if err := keys.AddKey("", []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := auth.NewJWT[userClaims](auth.JWTConfig{Keys: keys, Optional: true})(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if _, ok := auth.ClaimsOf[userClaims](req); ok {
+				t.Error("expected no claims")
+			}
+		}))
+
+	if w := serve(handler, ""); w.Code != http.StatusOK {
+		t.Errorf("expected anonymous requests through, got %d", w.Code)
+	}
+
+	if w := serve(handler, "not-a-token"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected invalid tokens to be rejected, got %d", w.Code)
+	}
+}
+
+func TestGetClaimsCompat(t *testing.T) {
+	token, err := auth.CreateJWTToken("secret", "payload", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := auth.JWT("secret")(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if auth.GetClaims(req)["payload"] != "payload" {
+			t.Error("expected the payload claim")
+		}
+
+		if auth.TokenClaims(req) == nil {
+			t.Error("expected TokenClaims to support auth.JWT")
+		}
+	}))
+
+	if w := serve(handler, token); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
@@ -0,0 +1,304 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrTokenMissing is returned when the request has no token.
+	ErrTokenMissing = errors.New("auth: missing token")
+
+	// ErrTokenRevoked is returned when the RevocationChecker reports the token as revoked.
+	ErrTokenRevoked = errors.New("auth: token has been revoked")
+)
+
+// TokenExtractor returns the token of a request, or "" if there is none.
+type TokenExtractor func(req *http.Request) string
+
+// FromAuthHeader reads the token from the "Authorization: Bearer <token>" header.
+func FromAuthHeader(req *http.Request) string {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// FromCookie reads the token from the named cookie.
+func FromCookie(name string) TokenExtractor {
+	return func(req *http.Request) string {
+		cookie, err := req.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	}
+}
+
+// FromQuery reads the token from the named query parameter, e.g for websocket
+// and EventSource connections that cannot set headers.
+// Query strings end up in logs, so keep those tokens short-lived.
+func FromQuery(name string) TokenExtractor {
+	return func(req *http.Request) string {
+		return req.URL.Query().Get(name)
+	}
+}
+
+// RevocationChecker reports whether a verified token has been revoked,
+// e.g after a logout.
+type RevocationChecker interface {
+	// IsRevoked is called with the token ID("jti" claim, "" if absent) and the claims
+	// of every valid token.
+	IsRevoked(ctx context.Context, tokenID string, claims jwt.Claims) (bool, error)
+}
+
+// RevocationFunc adapts a function to the RevocationChecker interface.
+type RevocationFunc func(ctx context.Context, tokenID string, claims jwt.Claims) (bool, error)
+
+// IsRevoked calls f(ctx, tokenID, claims).
+func (f RevocationFunc) IsRevoked(ctx context.Context, tokenID string, claims jwt.Claims) (bool, error) {
+	return f(ctx, tokenID, claims)
+}
+
+// JWTConfig is the configuration of a token Verifier.
+type JWTConfig struct {
+	// Keys used to verify the signatures. Required.
+	Keys *KeySet
+
+	// Methods restricts the accepted algorithms, e.g []string{"RS256"}.
+	// The algorithm of a token must always match the type of its key.
+	Methods []string
+
+	// Issuer, if not empty, must match the "iss" claim.
+	Issuer string
+
+	// Audience, if not empty, must be one of the "aud" claim values.
+	Audience string
+
+	// Leeway is the clock skew allowed when validating "exp", "nbf" and "iat".
+	Leeway time.Duration
+
+	// Extractors are tried in order to find the token of a request.
+	// Default is FromAuthHeader.
+	Extractors []TokenExtractor
+
+	// Revocation, if not nil, is consulted for every valid token.
+	Revocation RevocationChecker
+
+	// Optional lets requests without a token through, without claims.
+	// Requests with an invalid token are still rejected.
+	Optional bool
+
+	// ErrorHandler handles rejected requests. The default responds with
+	// 401 Unauthorized and a WWW-Authenticate header, as problem+json
+	// if the client accepts it. Failures of the revocation checker are 500 errors.
+	ErrorHandler func(w http.ResponseWriter, req *http.Request, err error)
+}
+
+// revocationError is a failure of the RevocationChecker, as opposed to an invalid token.
+type revocationError struct{ err error }
+
+func (e revocationError) Error() string { return "auth: revocation check failed: " + e.err.Error() }
+func (e revocationError) Unwrap() error { return e.err }
+
+// Verifier verifies tokens into claims of type T, a struct embedding
+// jwt.RegisteredClaims or jwt.MapClaims:
+//
+//	type UserClaims struct {
+//		jwt.RegisteredClaims
+//		Role string `json:"role"`
+//	}
+//
+//	keys, err := auth.LoadJWKS("keys/jwks.json")
+//	v := auth.NewVerifier[UserClaims](auth.JWTConfig{Keys: keys, Issuer: "https://auth.example.com"})
+//	api := r.Group("/api", v.Middleware)
+//
+// Handlers read the claims with auth.ClaimsOf[UserClaims](req).
+type Verifier[T any, PT interface {
+	*T
+	jwt.Claims
+}] struct {
+	config JWTConfig
+	parser *jwt.Parser
+}
+
+// NewVerifier creates a verifier. It panics if config.Keys is nil.
+func NewVerifier[T any, PT interface {
+	*T
+	jwt.Claims
+}](config JWTConfig) *Verifier[T, PT] {
+	if config.Keys == nil {
+		panic("auth: JWTConfig.Keys is required")
+	}
+
+	if len(config.Extractors) == 0 {
+		config.Extractors = []TokenExtractor{FromAuthHeader}
+	}
+
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = defaultJWTErrorHandler
+	}
+
+	opts := []jwt.ParserOption{jwt.WithLeeway(config.Leeway)}
+	if len(config.Methods) > 0 {
+		opts = append(opts, jwt.WithValidMethods(config.Methods))
+	}
+
+	if config.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.Issuer))
+	}
+
+	if config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(config.Audience))
+	}
+
+	return &Verifier[T, PT]{config: config, parser: jwt.NewParser(opts...)}
+}
+
+// NewJWT creates a middleware verifying tokens into claims of type T.
+// It is a shortcut for NewVerifier[T](config).Middleware.
+func NewJWT[T any, PT interface {
+	*T
+	jwt.Claims
+}](config JWTConfig) gor.Middleware {
+	return NewVerifier[T, PT](config).Middleware
+}
+
+// Verify parses and validates a token, and checks that it has not been revoked.
+func (v *Verifier[T, PT]) Verify(ctx context.Context, tokenString string) (*T, error) {
+	claims := PT(new(T))
+
+	token, err := v.parser.ParseWithClaims(tokenString, claims, v.config.Keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.config.Revocation != nil {
+		revoked, err := v.config.Revocation.IsRevoked(ctx, tokenID(token), claims)
+		if err != nil {
+			return nil, revocationError{err}
+		}
+
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+	return claims, nil
+}
+
+// Middleware verifies the token of the request and stores its claims in the context.
+func (v *Verifier[T, PT]) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var tokenString string
+		for _, extract := range v.config.Extractors {
+			if tokenString = extract(req); tokenString != "" {
+				break
+			}
+		}
+
+		if tokenString == "" {
+			if v.config.Optional {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			v.config.ErrorHandler(w, req, ErrTokenMissing)
+			return
+		}
+
+		claims, err := v.Verify(req.Context(), tokenString)
+		if err != nil {
+			v.config.ErrorHandler(w, req, err)
+			return
+		}
+
+		gor.SetContextValue(req, jwtClaimsKey, claims)
+		next.ServeHTTP(w, req)
+	})
+}
+
+// ClaimsOf returns the claims stored by a Verifier of type T.
+func ClaimsOf[T any](req *http.Request) (*T, bool) {
+	claims, ok := req.Context().Value(jwtClaimsKey).(*T)
+	return claims, ok
+}
+
+// TokenClaims returns the claims stored by JWT or a Verifier, or nil if the
+// request has not been authenticated.
+func TokenClaims(req *http.Request) jwt.Claims {
+	claims, _ := req.Context().Value(jwtClaimsKey).(jwt.Claims)
+	return claims
+}
+
+// tokenID returns the "jti" claim of a verified token.
+func tokenID(token *jwt.Token) string {
+	if claims, ok := token.Claims.(*jwt.MapClaims); ok {
+		id, _ := (*claims)["jti"].(string)
+		return id
+	}
+
+	parts := strings.Split(token.Raw, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := jwt.NewParser().DecodeSegment(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var registered struct {
+		ID string `json:"jti"`
+	}
+	_ = json.Unmarshal(payload, &registered)
+	return registered.ID
+}
+
+func defaultJWTErrorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	var revocationErr revocationError
+	if errors.As(err, &revocationErr) {
+		gor.SendError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+
+	// RFC 6750: requests without credentials get no error code.
+	challenge := "Bearer"
+	detail := "Missing token"
+	if !errors.Is(err, ErrTokenMissing) {
+		detail = tokenErrorDetail(err)
+		challenge = fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, detail)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+
+	if gor.AcceptsProblemJSON(req) {
+		gor.SendProblem(w, gor.NewProblem(http.StatusUnauthorized, detail))
+		return
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// tokenErrorDetail describes why a token was rejected, without echoing the token.
+func tokenErrorDetail(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "The token has expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "The token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "Invalid issuer"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "Invalid audience"
+	case errors.Is(err, ErrTokenRevoked):
+		return "The token has been revoked"
+	}
+	return "Invalid token"
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRefreshTokenNotFound is returned by a RefreshStore for unknown or expired tokens.
+var ErrRefreshTokenNotFound = errors.New("auth: refresh token not found")
+
+// RefreshToken is the server side state of a refresh token.
+type RefreshToken struct {
+	// ID identifies the token. It is a hash of the token, the token itself is never stored.
+	ID string
+
+	// FamilyID is shared by all the tokens obtained by rotating the same login.
+	FamilyID string
+
+	// Subject is the "sub" claim of the access tokens.
+	Subject string
+
+	// ExpiresAt is the time after which the token cannot be used.
+	ExpiresAt time.Time
+
+	// UsedAt is the time the token was exchanged, zero if unused.
+	UsedAt time.Time
+}
+
+// RefreshStore stores refresh tokens. Implementations must be safe for concurrent use.
+type RefreshStore interface {
+	// Create stores a new token.
+	Create(ctx context.Context, token RefreshToken) error
+
+	// Use marks the token as used and returns its state before the call, so that
+	// only one of concurrent calls sees an unused token.
+	// It returns ErrRefreshTokenNotFound if the token does not exist.
+	Use(ctx context.Context, id string) (RefreshToken, error)
+
+	// Release marks a token marked as used by Use as unused again. It is called when
+	// the new token pair could not be issued, so that the client can retry.
+	Release(ctx context.Context, id string) error
+
+	// RevokeFamily deletes all the tokens of a family and remembers the family as revoked
+	// until its tokens would have expired.
+	RevokeFamily(ctx context.Context, familyID string) error
+
+	// IsFamilyRevoked reports whether RevokeFamily was called for the family.
+	IsFamilyRevoked(ctx context.Context, familyID string) (bool, error)
+}
+
+// MemoryRefreshStore is a RefreshStore in memory, for single instance deployments and tests.
+type MemoryRefreshStore struct {
+	mu       sync.Mutex
+	tokens   map[string]RefreshToken
+	families map[string]map[string]struct{} // Family ID to token IDs.
+	revoked  map[string]time.Time           // Family ID to expiry.
+	creates  int
+	now      func() time.Time
+}
+
+// NewMemoryRefreshStore creates an empty in-memory store.
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{
+		tokens:   make(map[string]RefreshToken),
+		families: make(map[string]map[string]struct{}),
+		revoked:  make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// Create stores a new token.
+func (s *MemoryRefreshStore) Create(ctx context.Context, token RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Remove expired tokens from time to time.
+	s.creates++
+	if s.creates%1024 == 0 {
+		s.sweep()
+	}
+
+	s.tokens[token.ID] = token
+	if s.families[token.FamilyID] == nil {
+		s.families[token.FamilyID] = make(map[string]struct{})
+	}
+	s.families[token.FamilyID][token.ID] = struct{}{}
+	return nil
+}
+
+// Use marks the token as used and returns its previous state.
+func (s *MemoryRefreshStore) Use(ctx context.Context, id string) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok || s.now().After(token.ExpiresAt) {
+		return RefreshToken{}, ErrRefreshTokenNotFound
+	}
+
+	used := token
+	if used.UsedAt.IsZero() {
+		used.UsedAt = s.now()
+	}
+	s.tokens[id] = used
+	return token, nil
+}
+
+// Release marks the token as unused.
+func (s *MemoryRefreshStore) Release(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token, ok := s.tokens[id]; ok {
+		token.UsedAt = time.Time{}
+		s.tokens[id] = token
+	}
+	return nil
+}
+
+// RevokeFamily deletes the tokens of the family.
+func (s *MemoryRefreshStore) RevokeFamily(ctx context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiry := s.revoked[familyID]
+	for id := range s.families[familyID] {
+		if token := s.tokens[id]; token.ExpiresAt.After(expiry) {
+			expiry = token.ExpiresAt
+		}
+		delete(s.tokens, id)
+	}
+
+	delete(s.families, familyID)
+	s.revoked[familyID] = expiry
+	return nil
+}
+
+// IsFamilyRevoked reports whether the family has been revoked.
+func (s *MemoryRefreshStore) IsFamilyRevoked(ctx context.Context, familyID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, revoked := s.revoked[familyID]
+	return revoked, nil
+}
+
+// sweep deletes expired tokens and revocations. s.mu must be held.
+func (s *MemoryRefreshStore) sweep() {
+	now := s.now()
+
+	for id, token := range s.tokens {
+		if now.After(token.ExpiresAt) {
+			delete(s.tokens, id)
+
+			if family := s.families[token.FamilyID]; family != nil {
+				delete(family, id)
+				if len(family) == 0 {
+					delete(s.families, token.FamilyID)
+				}
+			}
+		}
+	}
+
+	for family, expiry := range s.revoked {
+		if now.After(expiry) {
+			delete(s.revoked, family)
+		}
+	}
+}
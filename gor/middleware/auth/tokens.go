@@ -0,0 +1,454 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrRefreshTokenInvalid is returned for unknown, expired or revoked refresh tokens.
+	ErrRefreshTokenInvalid = errors.New("auth: invalid refresh token")
+
+	// ErrRefreshTokenReused is returned when a refresh token is used twice. The whole
+	// family of tokens is revoked, as the token has probably been stolen.
+	ErrRefreshTokenReused = errors.New("auth: refresh token reused")
+)
+
+// TokenPair is the response of a successful login or refresh, with the
+// field names of OAuth 2.0 token responses(RFC 6749).
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // Lifetime of the access token in seconds.
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// TokenConfig is the configuration of a TokenService.
+type TokenConfig struct {
+	// SigningKey signs the access tokens: a []byte secret, *rsa.PrivateKey,
+	// *ecdsa.PrivateKey or ed25519.PrivateKey. Required.
+	SigningKey any
+
+	// Method is the signing algorithm. Default is HS256, RS256, ES256(ES384, ES512
+	// for larger curves) or EdDSA depending on the type of SigningKey.
+	Method jwt.SigningMethod
+
+	// KeyID is the "kid" header of the access tokens.
+	KeyID string
+
+	// Issuer and Audience are the "iss" and "aud" claims of the access tokens.
+	Issuer   string
+	Audience string
+
+	// AccessTTL is the lifetime of access tokens. Default is 15 minutes.
+	AccessTTL time.Duration
+
+	// RefreshTTL is the lifetime of refresh tokens. Each refresh issues a new
+	// refresh token with a full lifetime. Default is 30 days.
+	RefreshTTL time.Duration
+
+	// Store stores the refresh tokens. Default is a MemoryRefreshStore.
+	Store RefreshStore
+
+	// Claims returns extra claims of the access tokens of subject, e.g its roles.
+	// It is called on login and on every refresh, so changes are picked up.
+	Claims func(ctx context.Context, subject string) (map[string]any, error)
+
+	// RefreshCookie, if not empty, is the name of an HttpOnly cookie the refresh token
+	// is sent in and read from, instead of the JSON response.
+	RefreshCookie string
+
+	// CookiePath is the path of the refresh cookie. Set it to the path of the
+	// refresh and logout handlers so that the cookie is not sent with every request.
+	// Default is "/".
+	CookiePath string
+
+	// SecureCookie is the Secure attribute of the refresh cookie.
+	// If nil, it is set for TLS requests.
+	SecureCookie *bool
+}
+
+// TokenService issues short-lived access tokens and rotating refresh tokens.
+//
+// Refresh tokens are opaque random strings, only their hash is stored. Every refresh
+// returns a new refresh token and invalidates the previous one. If an invalidated
+// refresh token is used again, all the tokens obtained from the same login are revoked.
+//
+//	tokens, err := auth.NewTokenService(auth.TokenConfig{SigningKey: key, Issuer: "myapp"})
+//
+//	r.Post("/login", func(w http.ResponseWriter, req *http.Request) {
+//		// Check the credentials...
+//		tokens.Login(w, req, user.ID)
+//	})
+//	tokens.Mount(r.Group("/auth")) // POST /auth/token/refresh and POST /auth/logout.
+//
+//	api := r.Group("/api", auth.NewJWT[jwt.MapClaims](auth.JWTConfig{
+//		Keys:       tokens.Keys(),
+//		Issuer:     "myapp",
+//		Revocation: tokens, // Reject access tokens after logout.
+//	}))
+type TokenService struct {
+	config TokenConfig
+	keys   *KeySet
+}
+
+// NewTokenService creates a token service.
+func NewTokenService(config TokenConfig) (*TokenService, error) {
+	if config.SigningKey == nil {
+		return nil, errors.New("auth: TokenConfig.SigningKey is required")
+	}
+
+	if config.Method == nil {
+		method, err := signingMethodFor(config.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Method = method
+	}
+
+	if config.AccessTTL <= 0 {
+		config.AccessTTL = 15 * time.Minute
+	}
+
+	if config.RefreshTTL <= 0 {
+		config.RefreshTTL = 30 * 24 * time.Hour
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryRefreshStore()
+	}
+
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+
+	keys := NewKeySet()
+	if err := keys.AddKey(config.KeyID, config.SigningKey); err != nil {
+		return nil, err
+	}
+	return &TokenService{config: config, keys: keys}, nil
+}
+
+// signingMethodFor returns the default algorithm of a signing key.
+func signingMethodFor(key any) (jwt.SigningMethod, error) {
+	switch key := key.(type) {
+	case []byte:
+		return jwt.SigningMethodHS256, nil
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			return jwt.SigningMethodES256, nil
+		case 384:
+			return jwt.SigningMethodES384, nil
+		case 521:
+			return jwt.SigningMethodES512, nil
+		}
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("auth: unsupported signing key type %T", key)
+}
+
+// Keys returns the key set verifying the access tokens of the service.
+func (s *TokenService) Keys() *KeySet {
+	return s.keys
+}
+
+// Issue starts a new session for subject and returns its first token pair.
+func (s *TokenService) Issue(ctx context.Context, subject string) (*TokenPair, error) {
+	return s.issue(ctx, subject, randomToken(16))
+}
+
+func (s *TokenService) issue(ctx context.Context, subject, familyID string) (*TokenPair, error) {
+	access, err := s.accessToken(ctx, subject, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := randomToken(32)
+	err = s.config.Store.Create(ctx, RefreshToken{
+		ID:        hashToken(refresh),
+		FamilyID:  familyID,
+		Subject:   subject,
+		ExpiresAt: time.Now().Add(s.config.RefreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.config.AccessTTL / time.Second),
+		RefreshToken: refresh,
+	}, nil
+}
+
+// accessToken signs an access token. The "sid" claim is the refresh token family,
+// used to reject the access tokens of revoked sessions.
+func (s *TokenService) accessToken(ctx context.Context, subject, familyID string) (string, error) {
+	claims := jwt.MapClaims{}
+	if s.config.Claims != nil {
+		extra, err := s.config.Claims(ctx, subject)
+		if err != nil {
+			return "", err
+		}
+
+		for name, value := range extra {
+			claims[name] = value
+		}
+	}
+
+	now := time.Now()
+	claims["sub"] = subject
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(s.config.AccessTTL).Unix()
+	claims["jti"] = randomToken(16)
+	claims["sid"] = familyID
+
+	if s.config.Issuer != "" {
+		claims["iss"] = s.config.Issuer
+	}
+
+	if s.config.Audience != "" {
+		claims["aud"] = s.config.Audience
+	}
+
+	token := jwt.NewWithClaims(s.config.Method, claims)
+	if s.config.KeyID != "" {
+		token.Header["kid"] = s.config.KeyID
+	}
+	return token.SignedString(s.config.SigningKey)
+}
+
+// Refresh exchanges a refresh token for a new token pair.
+// It returns ErrRefreshTokenInvalid or ErrRefreshTokenReused for rejected tokens.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	token, err := s.config.Store.Use(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenNotFound) {
+		return nil, ErrRefreshTokenInvalid
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !token.UsedAt.IsZero() {
+		if err := s.config.Store.RevokeFamily(ctx, token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	pair, err := s.issue(ctx, token.Subject, token.FamilyID)
+	if err != nil {
+		// Let the client retry instead of treating the retry as a reuse.
+		if releaseErr := s.config.Store.Release(ctx, token.ID); releaseErr != nil {
+			return nil, errors.Join(err, releaseErr)
+		}
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Revoke ends the session of a refresh token: the tokens of its family can no longer be
+// refreshed, and their access tokens are rejected by verifiers using the service as
+// RevocationChecker. Unknown tokens are ignored.
+func (s *TokenService) Revoke(ctx context.Context, refreshToken string) error {
+	token, err := s.config.Store.Use(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return s.config.Store.RevokeFamily(ctx, token.FamilyID)
+}
+
+// IsRevoked implements RevocationChecker: access tokens of revoked sessions are revoked.
+// Typed claims must have a field for the "sid" claim:
+//
+//	SessionID string `json:"sid"`
+func (s *TokenService) IsRevoked(ctx context.Context, tokenID string, claims jwt.Claims) (bool, error) {
+	var familyID string
+	switch claims := claims.(type) {
+	case *jwt.MapClaims:
+		familyID, _ = (*claims)["sid"].(string)
+	case jwt.MapClaims:
+		familyID, _ = claims["sid"].(string)
+	default:
+		// Typed claims: decode the "sid" claim.
+		data, err := json.Marshal(claims)
+		if err != nil {
+			return false, err
+		}
+
+		var session struct {
+			ID string `json:"sid"`
+		}
+		if err := json.Unmarshal(data, &session); err != nil {
+			return false, err
+		}
+		familyID = session.ID
+	}
+
+	if familyID == "" {
+		return false, nil
+	}
+	return s.config.Store.IsFamilyRevoked(ctx, familyID)
+}
+
+// Login issues a token pair for subject and writes it as JSON, or sets the refresh
+// cookie if configured. Call it from the login handler once the credentials are checked.
+func (s *TokenService) Login(w http.ResponseWriter, req *http.Request, subject string) {
+	pair, err := s.Issue(req.Context(), subject)
+	if err != nil {
+		gor.SendError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	s.sendTokenPair(w, req, pair)
+}
+
+// RefreshHandler exchanges the refresh token of the request for a new token pair.
+// The refresh token is read from the refresh cookie, or the "refresh_token" field
+// of a JSON or form body.
+func (s *TokenService) RefreshHandler(w http.ResponseWriter, req *http.Request) {
+	refreshToken := s.refreshToken(req)
+	if refreshToken == "" {
+		s.sendTokenError(w, "invalid_request", "Missing refresh token", http.StatusBadRequest)
+		return
+	}
+
+	pair, err := s.Refresh(req.Context(), refreshToken)
+	if errors.Is(err, ErrRefreshTokenInvalid) || errors.Is(err, ErrRefreshTokenReused) {
+		s.clearCookie(w, req)
+		s.sendTokenError(w, "invalid_grant", "Invalid refresh token", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		gor.SendError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	s.sendTokenPair(w, req, pair)
+}
+
+// LogoutHandler revokes the session of the refresh token of the request and
+// responds with 204 No Content.
+func (s *TokenService) LogoutHandler(w http.ResponseWriter, req *http.Request) {
+	if refreshToken := s.refreshToken(req); refreshToken != "" {
+		if err := s.Revoke(req.Context(), refreshToken); err != nil {
+			gor.SendError(w, req, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	s.clearCookie(w, req)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Mount registers RefreshHandler on POST /token/refresh and LogoutHandler on
+// POST /logout of a Router or Group.
+func (s *TokenService) Mount(r interface {
+	Post(path string, handler http.HandlerFunc, middlewares ...gor.Middleware)
+}, middlewares ...gor.Middleware) {
+	r.Post("/token/refresh", s.RefreshHandler, middlewares...)
+	r.Post("/logout", s.LogoutHandler, middlewares...)
+}
+
+func (s *TokenService) refreshToken(req *http.Request) string {
+	if s.config.RefreshCookie != "" {
+		if cookie, err := req.Cookie(s.config.RefreshCookie); err == nil && cookie.Value != "" {
+			return cookie.Value
+		}
+	}
+
+	if gor.ContentType(req) == gor.ContentTypeJSON {
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+
+		if err := json.NewDecoder(http.MaxBytesReader(nil, req.Body, 1<<16)).Decode(&body); err != nil {
+			return ""
+		}
+		return body.RefreshToken
+	}
+	return req.PostFormValue("refresh_token")
+}
+
+func (s *TokenService) sendTokenPair(w http.ResponseWriter, req *http.Request, pair *TokenPair) {
+	if s.config.RefreshCookie != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     s.config.RefreshCookie,
+			Value:    pair.RefreshToken,
+			Path:     s.config.CookiePath,
+			MaxAge:   int(s.config.RefreshTTL / time.Second),
+			HttpOnly: true,
+			Secure:   s.secureCookie(req),
+			SameSite: http.SameSiteStrictMode,
+		})
+
+		pair.RefreshToken = ""
+	}
+
+	// Token responses must not be cached(RFC 6749 section 5.1).
+	w.Header().Set("Cache-Control", "no-store")
+	gor.SendJSON(w, pair)
+}
+
+func (s *TokenService) sendTokenError(w http.ResponseWriter, code, description string, status int) {
+	w.Header().Set("Cache-Control", "no-store")
+	gor.SendJSONError(w, map[string]any{"error": code, "error_description": description}, status)
+}
+
+func (s *TokenService) clearCookie(w http.ResponseWriter, req *http.Request) {
+	if s.config.RefreshCookie == "" {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.config.RefreshCookie,
+		Path:     s.config.CookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.secureCookie(req),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// secureCookie returns the Secure attribute of the refresh cookie.
+func (s *TokenService) secureCookie(req *http.Request) bool {
+	if s.config.SecureCookie != nil {
+		return *s.config.SecureCookie
+	}
+	return req.TLS != nil
+}
+
+// randomToken returns n random bytes, base64url encoded.
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,256 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type sessionClaims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid"`
+	Role      string `json:"role"`
+}
+
+func newTokenService(t *testing.T, config auth.TokenConfig) *auth.TokenService {
+	t.Helper()
+
+	if config.SigningKey == nil {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.SigningKey = key
+	}
+
+	tokens, err := auth.NewTokenService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func TestTokenServiceRotation(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokenService(t, auth.TokenConfig{
+		Issuer: "myapp",
+		Claims: func(ctx context.Context, subject string) (map[string]any, error) {
+			return map[string]any{"role": "admin"}, nil
+		},
+	})
+
+	verifier := auth.NewVerifier[sessionClaims](auth.JWTConfig{
+		Keys:       tokens.Keys(),
+		Issuer:     "myapp",
+		Methods:    []string{"EdDSA"},
+		Revocation: tokens,
+	})
+
+	first, err := tokens.Issue(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := verifier.Verify(ctx, first.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "alice" || claims.Role != "admin" || claims.SessionID == "" || claims.ID == "" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	second, err := tokens.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("expected a new refresh token")
+	}
+
+	// Reusing the first refresh token revokes the whole session.
+	if _, err := tokens.Refresh(ctx, first.RefreshToken); !errors.Is(err, auth.ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+
+	if _, err := tokens.Refresh(ctx, second.RefreshToken); !errors.Is(err, auth.ErrRefreshTokenInvalid) {
+		t.Fatalf("expected the rotated token to be revoked, got %v", err)
+	}
+
+	if _, err := verifier.Verify(ctx, second.AccessToken); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Fatalf("expected the access token to be revoked, got %v", err)
+	}
+
+	if _, err := tokens.Refresh(ctx, "unknown"); !errors.Is(err, auth.ErrRefreshTokenInvalid) {
+		t.Fatalf("expected ErrRefreshTokenInvalid, got %v", err)
+	}
+}
+
+func TestTokenServiceRefreshRetry(t *testing.T) {
+	ctx := context.Background()
+	fail := false
+	tokens := newTokenService(t, auth.TokenConfig{
+		Claims: func(ctx context.Context, subject string) (map[string]any, error) {
+			if fail {
+				fail = false
+				return nil, errors.New("database is down")
+			}
+			return nil, nil
+		},
+	})
+
+	first, err := tokens.Issue(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail = true
+	if _, err := tokens.Refresh(ctx, first.RefreshToken); err == nil || errors.Is(err, auth.ErrRefreshTokenReused) {
+		t.Fatalf("expected the Claims error, got %v", err)
+	}
+
+	// The failed refresh did not use the token, the retry is not a reuse.
+	second, err := tokens.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+
+	if _, err := tokens.Refresh(ctx, second.RefreshToken); err != nil {
+		t.Fatalf("expected the session to be alive, got %v", err)
+	}
+}
+
+func TestTokenServiceHandlers(t *testing.T) {
+	tokens := newTokenService(t, auth.TokenConfig{SigningKey: []byte("secret")})
+
+	r := gor.NewRouter()
+	r.Post("/login", func(w http.ResponseWriter, req *http.Request) {
+		tokens.Login(w, req, "bob")
+	})
+	tokens.Mount(r.Group("/auth"))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/login", "")
+	var pair auth.TokenPair
+	if err := json.NewDecoder(w.Body).Decode(&pair); err != nil {
+		t.Fatal(err)
+	}
+
+	if pair.TokenType != "Bearer" || pair.ExpiresIn != 900 || pair.RefreshToken == "" {
+		t.Fatalf("unexpected token pair %+v", pair)
+	}
+
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Error("expected token responses not to be cached")
+	}
+
+	w = post("/auth/token/refresh", `{"refresh_token":"`+pair.RefreshToken+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	var refreshed auth.TokenPair
+	if err := json.NewDecoder(w.Body).Decode(&refreshed); err != nil {
+		t.Fatal(err)
+	}
+
+	w = post("/auth/logout", `{"refresh_token":"`+refreshed.RefreshToken+`"}`)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+
+	w = post("/auth/token/refresh", `{"refresh_token":"`+refreshed.RefreshToken+`"}`)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "invalid_grant") {
+		t.Fatalf("expected invalid_grant after logout, got %d: %s", w.Code, w.Body)
+	}
+
+	w = post("/auth/token/refresh", `{}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a token, got %d", w.Code)
+	}
+}
+
+func TestTokenServiceCookie(t *testing.T) {
+	tokens := newTokenService(t, auth.TokenConfig{RefreshCookie: "refresh", CookiePath: "/auth"})
+
+	r := gor.NewRouter()
+	r.Post("/login", func(w http.ResponseWriter, req *http.Request) {
+		tokens.Login(w, req, "carol")
+	})
+	tokens.Mount(r.Group("/auth"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+
+	var pair auth.TokenPair
+	if err := json.NewDecoder(w.Body).Decode(&pair); err != nil {
+		t.Fatal(err)
+	}
+
+	if pair.RefreshToken != "" {
+		t.Error("expected the refresh token in the cookie only")
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].Path != "/auth" {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+
+	// The cookie is only Secure over TLS by default.
+	if cookies[0].Secure {
+		t.Error("expected a cookie without the Secure attribute over plain HTTP")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/token/refresh", nil)
+	req.AddCookie(cookies[0])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].Value == req.Cookies()[0].Value {
+		t.Errorf("expected a rotated cookie, got %v", cookies)
+	}
+}
+
+func TestTokenServiceSecureCookie(t *testing.T) {
+	secure := true
+	tokens := newTokenService(t, auth.TokenConfig{RefreshCookie: "refresh", SecureCookie: &secure})
+
+	w := httptest.NewRecorder()
+	tokens.Login(w, httptest.NewRequest(http.MethodPost, "/login", nil), "carol")
+
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || !cookies[0].Secure {
+		t.Fatalf("expected a Secure cookie, got %v", cookies)
+	}
+
+	tls := httptest.NewRequest(http.MethodPost, "https://example.com/login", nil)
+	tokens = newTokenService(t, auth.TokenConfig{RefreshCookie: "refresh"})
+
+	w = httptest.NewRecorder()
+	tokens.Login(w, tls, "carol")
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || !cookies[0].Secure {
+		t.Fatalf("expected a Secure cookie over TLS, got %v", cookies)
+	}
+}
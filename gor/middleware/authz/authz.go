@@ -0,0 +1,278 @@
+/*
+Package authz implements role and permission based authorization.
+
+A Policy maps roles to permissions. The principal(the authenticated user) of a
+request is read by an Extractor, from the claims of a JWT, a session or basic auth.
+Authentication itself is left to the auth and session middlewares, which must run first.
+
+	policy := authz.New(authz.Config{
+		Extractor: authz.FromJWT("roles"),
+		Roles: map[string][]string{
+			"admin":  {"*"},
+			"editor": {"posts:*"},
+			"viewer": {"posts:read"},
+		},
+	})
+
+	api := r.Group("/api", verifier.Middleware, policy.Load)
+	api.Get("/posts", listPosts, policy.RequirePermission("posts:read"))
+	api.Delete("/posts/{id}", deletePost, policy.RequireOwner("id", postAuthor, "posts:delete"))
+
+	admin := r.Group("/admin", verifier.Middleware, policy.RequireRole("admin"))
+
+Permissions are strings, by convention "resource:action". A granted permission
+"posts:*" matches all the actions on posts and "*" matches everything.
+
+Templates hide UI elements with the functions of Policy.FuncMap, given the template
+data(with gor.PassContextToViews enabled) or the request:
+
+	{{ if can "posts:delete" . }}<button>Delete</button>{{ end }}
+*/
+package authz
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+type contextType string
+
+// principalKey is the key of the principal in the request context and CTX locals.
+const principalKey = contextType("principal")
+
+// Principal is the authenticated user of a request.
+type Principal struct {
+	ID          string   // User ID, compared with the owner of resources.
+	Roles       []string // Roles, granted permissions by the Policy.
+	Permissions []string // Permissions granted to the user directly.
+}
+
+// HasRole reports whether the principal has one of the roles.
+func (p *Principal) HasRole(roles ...string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, role := range roles {
+		for _, r := range p.Roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Config is the configuration of a Policy.
+type Config struct {
+	// Extractor reads the principal of requests. Required.
+	Extractor Extractor
+
+	// Roles maps roles to their permissions.
+	Roles map[string][]string
+
+	// ErrorHandler handles denied requests, with 401 Unauthorized for anonymous
+	// requests and 403 Forbidden otherwise. The default responds with problem+json
+	// if the client accepts it, plain text otherwise.
+	// Errors of the extractor and owner lookups are sent with gor.SendError.
+	ErrorHandler func(w http.ResponseWriter, req *http.Request, status int)
+}
+
+// Policy authorizes requests. It is safe for concurrent use.
+type Policy struct {
+	config Config
+}
+
+// New creates a policy. It panics if config.Extractor is nil.
+func New(config Config) *Policy {
+	if config.Extractor == nil {
+		panic("authz: Config.Extractor is required")
+	}
+
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = defaultErrorHandler
+	}
+	return &Policy{config: config}
+}
+
+// Load reads the principal of the request, if any, without enforcing anything.
+// Use it before rendering templates that call the template functions.
+func (p *Policy) Load(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, err := p.principal(req); err != nil {
+			gor.SendError(w, req, err, http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// RequireAuth rejects anonymous requests.
+func (p *Policy) RequireAuth() gor.Middleware {
+	return p.require(func(req *http.Request, principal *Principal) (bool, error) {
+		return true, nil
+	})
+}
+
+// RequireRole allows principals with at least one of the roles.
+func (p *Policy) RequireRole(roles ...string) gor.Middleware {
+	return p.require(func(req *http.Request, principal *Principal) (bool, error) {
+		return principal.HasRole(roles...), nil
+	})
+}
+
+// RequirePermission allows principals with all the permissions.
+func (p *Policy) RequirePermission(permissions ...string) gor.Middleware {
+	return p.require(func(req *http.Request, principal *Principal) (bool, error) {
+		return p.Can(principal, permissions...), nil
+	})
+}
+
+// OwnerFunc returns the ID of the owner of the resource identified by id,
+// e.g the author of a post. It returns an error wrapping ErrNotFound if there
+// is no such resource.
+type OwnerFunc func(req *http.Request, id string) (string, error)
+
+// ErrNotFound is wrapped by OwnerFunc errors for missing resources.
+var ErrNotFound = errors.New("authz: resource not found")
+
+// RequireOwner allows the owner of the resource identified by the path value param,
+// and principals with all the bypass permissions(if any), e.g moderators.
+//
+// If owner is nil, the path value is the ID of the owner itself, as in /users/{id}.
+func (p *Policy) RequireOwner(param string, owner OwnerFunc, bypass ...string) gor.Middleware {
+	return p.require(func(req *http.Request, principal *Principal) (bool, error) {
+		if len(bypass) > 0 && p.Can(principal, bypass...) {
+			return true, nil
+		}
+
+		id := req.PathValue(param)
+		if id == "" {
+			return false, nil
+		}
+
+		ownerID := id
+		if owner != nil {
+			var err error
+			if ownerID, err = owner(req, id); err != nil {
+				return false, err
+			}
+		}
+		return principal.ID != "" && ownerID == principal.ID, nil
+	})
+}
+
+// RequireFunc allows requests for which allow returns true, for checks that do not
+// fit the other middlewares.
+func (p *Policy) RequireFunc(allow func(req *http.Request, principal *Principal) (bool, error)) gor.Middleware {
+	return p.require(allow)
+}
+
+// require rejects anonymous requests and requests for which allow returns false.
+func (p *Policy) require(allow func(req *http.Request, principal *Principal) (bool, error)) gor.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			principal, err := p.principal(req)
+			if err != nil {
+				gor.SendError(w, req, err, http.StatusInternalServerError)
+				return
+			}
+
+			if principal == nil {
+				p.config.ErrorHandler(w, req, http.StatusUnauthorized)
+				return
+			}
+
+			ok, err := allow(req, principal)
+			if errors.Is(err, ErrNotFound) {
+				gor.SendError(w, req, err, http.StatusNotFound)
+				return
+			} else if err != nil {
+				gor.SendError(w, req, err, http.StatusInternalServerError)
+				return
+			}
+
+			if !ok {
+				p.config.ErrorHandler(w, req, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// principal returns the principal of the request, extracting it once per request.
+func (p *Policy) principal(req *http.Request) (*Principal, error) {
+	if principal, ok := req.Context().Value(principalKey).(*Principal); ok {
+		return principal, nil
+	}
+
+	principal, err := p.config.Extractor(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if principal != nil {
+		gor.SetContextValue(req, principalKey, principal)
+	}
+	return principal, nil
+}
+
+// Can reports whether the principal has all the permissions, through its roles
+// or directly. A nil principal has no permissions.
+func (p *Policy) Can(principal *Principal, permissions ...string) bool {
+	if principal == nil {
+		return false
+	}
+
+	for _, permission := range permissions {
+		if !p.granted(principal, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Policy) granted(principal *Principal, permission string) bool {
+	if matchAny(principal.Permissions, permission) {
+		return true
+	}
+
+	for _, role := range principal.Roles {
+		if matchAny(p.config.Roles[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny reports whether one of the granted permissions matches permission.
+func matchAny(granted []string, permission string) bool {
+	for _, g := range granted {
+		if g == permission || g == "*" {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(g, "*"); ok && strings.HasPrefix(permission, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPrincipal returns the principal loaded by a Policy middleware, or nil.
+func GetPrincipal(req *http.Request) *Principal {
+	principal, _ := req.Context().Value(principalKey).(*Principal)
+	return principal
+}
+
+func defaultErrorHandler(w http.ResponseWriter, req *http.Request, status int) {
+	if gor.AcceptsProblemJSON(req) {
+		gor.SendProblem(w, gor.NewProblem(status, ""))
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
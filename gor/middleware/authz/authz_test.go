@@ -0,0 +1,235 @@
+package authz_test
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/auth"
+	"github.com/abiiranathan/gor/gor/middleware/authz"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/sessions"
+)
+
+var roles = map[string][]string{
+	"admin":     {"*"},
+	"editor":    {"posts:*"},
+	"viewer":    {"posts:read"},
+	"moderator": {"posts:delete"},
+}
+
+func TestCan(t *testing.T) {
+	policy := authz.New(authz.Config{Extractor: authz.FromBasicAuth(nil), Roles: roles})
+
+	tests := []struct {
+		principal  *authz.Principal
+		permission []string
+		want       bool
+	}{
+		{nil, []string{"posts:read"}, false},
+		{&authz.Principal{Roles: []string{"admin"}}, []string{"users:delete"}, true},
+		{&authz.Principal{Roles: []string{"editor"}}, []string{"posts:edit", "posts:read"}, true},
+		{&authz.Principal{Roles: []string{"editor"}}, []string{"posts:edit", "users:read"}, false},
+		{&authz.Principal{Roles: []string{"viewer"}}, []string{"posts:edit"}, false},
+		{&authz.Principal{Roles: []string{"viewer"}, Permissions: []string{"posts:edit"}}, []string{"posts:edit"}, true},
+		{&authz.Principal{Roles: []string{"unknown"}}, []string{"posts:read"}, false},
+	}
+
+	for i, tt := range tests {
+		if got := policy.Can(tt.principal, tt.permission...); got != tt.want {
+			t.Errorf("%d: expected %v, got %v", i, tt.want, got)
+		}
+	}
+}
+
+func TestRequireJWT(t *testing.T) {
+	keys := auth.NewKeySet()
+	if err := keys.AddKey("", []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := authz.New(authz.Config{Extractor: authz.FromJWT("roles"), Roles: roles})
+	posts := map[string]string{"1": "alice", "2": "bob"}
+	postAuthor := func(req *http.Request, id string) (string, error) {
+		author, ok := posts[id]
+		if !ok {
+			return "", fmt.Errorf("post %s: %w", id, authz.ErrNotFound)
+		}
+		return author, nil
+	}
+
+	r := gor.NewRouter()
+	api := r.Group("/api", auth.NewJWT[jwt.MapClaims](auth.JWTConfig{Keys: keys, Optional: true}))
+	api.Get("/posts", func(w http.ResponseWriter, req *http.Request) {}, policy.RequirePermission("posts:read"))
+	api.Delete("/posts/{id}", func(w http.ResponseWriter, req *http.Request) {},
+		policy.RequireOwner("id", postAuthor, "posts:delete"))
+	api.Get("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(authz.GetPrincipal(req).ID))
+	}, policy.RequireOwner("id", nil))
+
+	admin := r.Group("/admin", auth.NewJWT[jwt.MapClaims](auth.JWTConfig{Keys: keys}), policy.RequireRole("admin"))
+	admin.Get("/", func(w http.ResponseWriter, req *http.Request) {})
+
+	token := func(sub string, roles ...string) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": sub, "roles": roles, "exp": time.Now().Add(time.Minute).Unix(),
+		}).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		method, path, token string
+		status              int
+	}{
+		{http.MethodGet, "/api/posts", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/posts", token("alice", "viewer"), http.StatusOK},
+		{http.MethodGet, "/api/posts", token("alice"), http.StatusForbidden},
+		{http.MethodDelete, "/api/posts/1", token("alice"), http.StatusOK},
+		{http.MethodDelete, "/api/posts/2", token("alice"), http.StatusForbidden},
+		{http.MethodDelete, "/api/posts/2", token("carol", "moderator"), http.StatusOK},
+		{http.MethodDelete, "/api/posts/3", token("carol"), http.StatusNotFound},
+		{http.MethodGet, "/api/users/alice", token("alice"), http.StatusOK},
+		{http.MethodGet, "/api/users/bob", token("alice"), http.StatusForbidden},
+		{http.MethodGet, "/admin/", token("alice", "editor"), http.StatusForbidden},
+		{http.MethodGet, "/admin/", token("root", "admin"), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+	}
+}
+
+func TestFromSession(t *testing.T) {
+	store := sessions.NewCookieStore([]byte("session-secret"))
+	policy := authz.New(authz.Config{Extractor: authz.FromSession(store, "session"), Roles: roles})
+
+	r := gor.NewRouter()
+	r.Post("/login", func(w http.ResponseWriter, req *http.Request) {
+		err := authz.SaveSession(w, req, store, "session", authz.Principal{ID: "dave", Roles: []string{"editor"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	r.Get("/posts/new", func(w http.ResponseWriter, req *http.Request) {}, policy.RequirePermission("posts:create"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/new", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 before login, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/new", nil)
+	for _, cookie := range w.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 after login, got %d", w.Code)
+	}
+}
+
+func TestBasicAuthAndErrors(t *testing.T) {
+	policy := authz.New(authz.Config{
+		Extractor: authz.Chain(
+			func(req *http.Request) (*authz.Principal, error) {
+				if req.Header.Get("X-Fail") != "" {
+					return nil, errors.New("extractor failed")
+				}
+				return nil, nil
+			},
+			authz.FromBasicAuth(map[string][]string{"admin": {"admin"}}),
+		),
+		Roles: roles,
+	})
+
+	handler := auth.BasicAuth("admin", "password")(
+		policy.RequireRole("admin")(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("admin", "password")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("admin", "password")
+	req.Header.Set("X-Fail", "1")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected extractor errors to be 500, got %d", w.Code)
+	}
+
+	// Problem responses for denied API requests.
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", gor.ContentTypeProblemJSON)
+	w = httptest.NewRecorder()
+	policy.RequireAuth()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})).ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Content-Type") != gor.ContentTypeProblemJSON {
+		t.Fatalf("expected a 401 problem, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	policy := authz.New(authz.Config{
+		Extractor: func(req *http.Request) (*authz.Principal, error) {
+			if user := req.URL.Query().Get("user"); user != "" {
+				return &authz.Principal{ID: user, Roles: []string{user}}, nil
+			}
+			return nil, nil
+		},
+		Roles: roles,
+	})
+
+	tmpl := template.Must(template.New("page.html").Funcs(policy.FuncMap()).Parse(
+		`{{ if can "posts:delete" . }}delete{{ end }}|{{ if has_role "admin" "editor" . }}admin{{ end }}`))
+
+	r := gor.NewRouter(gor.WithTemplates(tmpl), gor.PassContextToViews(true))
+	r.Use(policy.Load)
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "page.html", gor.Map{})
+	})
+
+	tests := map[string]string{
+		"/":                "|",
+		"/?user=viewer":    "|",
+		"/?user=moderator": "delete|",
+		"/?user=admin":     "delete|admin",
+		"/?user=editor":    "delete|admin",
+	}
+
+	for path, want := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if got := strings.TrimSpace(w.Body.String()); got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+}
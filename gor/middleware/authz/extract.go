@@ -0,0 +1,149 @@
+package authz
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/abiiranathan/gor/gor/middleware/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/sessions"
+)
+
+// Extractor returns the principal of a request, or nil for anonymous requests.
+type Extractor func(req *http.Request) (*Principal, error)
+
+// FromJWT reads the principal from the claims verified by auth.JWT or an auth.Verifier.
+// The ID is the "sub" claim and the roles are read from rolesClaim("roles" if empty),
+// a string or an array of strings. Permissions are read from the "permissions" claim.
+func FromJWT(rolesClaim string) Extractor {
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+
+	return func(req *http.Request) (*Principal, error) {
+		claims := auth.TokenClaims(req)
+		if claims == nil {
+			return nil, nil
+		}
+
+		values, err := claimValues(claims)
+		if err != nil {
+			return nil, err
+		}
+
+		principal := &Principal{
+			Roles:       stringList(values[rolesClaim]),
+			Permissions: stringList(values["permissions"]),
+		}
+		principal.ID, _ = claims.GetSubject()
+		return principal, nil
+	}
+}
+
+// claimValues returns the claims as a map, typed claims are converted through JSON.
+func claimValues(claims jwt.Claims) (map[string]any, error) {
+	switch claims := claims.(type) {
+	case jwt.MapClaims:
+		return claims, nil
+	case *jwt.MapClaims:
+		return *claims, nil
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]any
+	err = json.Unmarshal(data, &values)
+	return values, err
+}
+
+// stringList converts a claim to a list of strings. Strings are split on spaces,
+// like the OAuth "scope" claim.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// Session keys of the principal, used by FromSession and SaveSession.
+const (
+	SessionUserKey        = "user_id"
+	SessionRolesKey       = "roles"
+	SessionPermissionsKey = "permissions"
+)
+
+// FromSession reads the principal saved by SaveSession in the named session.
+// Sessions without a user ID are anonymous.
+func FromSession(store sessions.Store, name string) Extractor {
+	return func(req *http.Request) (*Principal, error) {
+		session, err := store.Get(req, name)
+		if err != nil {
+			// Invalid cookies(e.g after a key change) are anonymous sessions.
+			return nil, nil
+		}
+
+		id, _ := session.Values[SessionUserKey].(string)
+		if id == "" {
+			return nil, nil
+		}
+
+		roles, _ := session.Values[SessionRolesKey].([]string)
+		permissions, _ := session.Values[SessionPermissionsKey].([]string)
+		return &Principal{ID: id, Roles: roles, Permissions: permissions}, nil
+	}
+}
+
+// SaveSession saves the principal in the named session, e.g after a login.
+func SaveSession(w http.ResponseWriter, req *http.Request, store sessions.Store, name string, principal Principal) error {
+	session, err := store.Get(req, name)
+	if err != nil && session == nil {
+		return err
+	}
+
+	session.Values[SessionUserKey] = principal.ID
+	session.Values[SessionRolesKey] = principal.Roles
+	session.Values[SessionPermissionsKey] = principal.Permissions
+	return session.Save(req, w)
+}
+
+// FromBasicAuth reads the principal from the username of basic auth, with the roles
+// of the username in roles. Use it after the auth.BasicAuth middleware, which checks
+// the password.
+func FromBasicAuth(roles map[string][]string) Extractor {
+	return func(req *http.Request) (*Principal, error) {
+		username, _, ok := req.BasicAuth()
+		if !ok || username == "" {
+			return nil, nil
+		}
+		return &Principal{ID: username, Roles: roles[username]}, nil
+	}
+}
+
+// Chain returns the principal of the first extractor that finds one,
+// e.g to accept both tokens and sessions.
+func Chain(extractors ...Extractor) Extractor {
+	return func(req *http.Request) (*Principal, error) {
+		for _, extract := range extractors {
+			principal, err := extract(req)
+			if err != nil || principal != nil {
+				return principal, err
+			}
+		}
+		return nil, nil
+	}
+}
@@ -0,0 +1,72 @@
+package authz
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// FuncMap returns the template functions of the policy:
+//
+//	can: reports whether the principal has all the permissions.
+//	has_role: reports whether the principal has one of the roles.
+//
+// The last argument is the template data(with gor.PassContextToViews enabled)
+// or the request. Use $ inside range and with blocks:
+//
+//	{{ if can "posts:delete" . }}<button>Delete</button>{{ end }}
+//	{{ range .posts }}{{ if can "posts:edit" $ }}...{{ end }}{{ end }}
+//	{{ if has_role "admin" "owner" . }}<a href="/admin">Admin</a>{{ end }}
+//
+// Anonymous requests have no permissions nor roles. The principal must have been
+// loaded by one of the policy middlewares, e.g Load.
+func (p *Policy) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"can": func(args ...any) (bool, error) {
+			permissions, principal, err := templateArgs("can", args)
+			if err != nil {
+				return false, err
+			}
+			return p.Can(principal, permissions...), nil
+		},
+		"has_role": func(args ...any) (bool, error) {
+			roles, principal, err := templateArgs("has_role", args)
+			if err != nil {
+				return false, err
+			}
+			return principal.HasRole(roles...), nil
+		},
+	}
+}
+
+// templateArgs splits the arguments of a template function into
+// the strings and the principal of the data.
+func templateArgs(name string, args []any) ([]string, *Principal, error) {
+	if len(args) < 2 {
+		return nil, nil, fmt.Errorf("%s: expected at least one name and the template data", name)
+	}
+
+	names := make([]string, len(args)-1)
+	for i, arg := range args[:len(args)-1] {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: expected a string, got %T", name, arg)
+		}
+		names[i] = s
+	}
+
+	var principal *Principal
+	switch data := args[len(args)-1].(type) {
+	case *http.Request:
+		principal = GetPrincipal(data)
+	case gor.Map:
+		principal, _ = data[string(principalKey)].(*Principal)
+	case map[string]any:
+		principal, _ = data[string(principalKey)].(*Principal)
+	default:
+		return nil, nil, fmt.Errorf("%s: expected the template data or a request, got %T", name, data)
+	}
+	return names, principal, nil
+}
@@ -0,0 +1,382 @@
+/*
+Package compress compresses responses with gzip or deflate(zlib format), depending
+on the Accept-Encoding header of the request.
+
+	r.Use(logger.New(nil), compress.New())
+
+Small responses, responses that are already compressed(images, archives,
+fonts...) and responses with a Content-Encoding are sent as is.
+To serve precompressed ".br" and ".gz" files with Static and StaticFS,
+see gor.ServeCompressedAssetsIfPresent.
+*/
+package compress
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// Supported encodings. HTTP "deflate" is the zlib format(RFC 9110 section 8.4.1.2),
+// not raw DEFLATE.
+const (
+	Gzip    = "gzip"
+	Deflate = "deflate"
+)
+
+// DefaultMinSize is the minimum size of compressed responses in bytes.
+const DefaultMinSize = 1024
+
+// DefaultSkipContentTypes are content types that are already compressed.
+// Entries ending with "/" match all subtypes.
+var DefaultSkipContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/zstd",
+	"application/pdf",
+	"application/wasm",
+	"application/octet-stream",
+	"text/event-stream",
+}
+
+// Config is the configuration of the compression middleware.
+type Config struct {
+	// Level is the compression level, from flate.BestSpeed to flate.BestCompression.
+	// Default is flate.DefaultCompression.
+	Level int
+
+	// MinSize is the minimum size of compressed responses. Default is DefaultMinSize.
+	// Responses are buffered until MinSize bytes are written or the response is flushed.
+	MinSize int
+
+	// SkipContentTypes are not compressed. Default is DefaultSkipContentTypes.
+	// Images with a text format("image/svg+xml") are compressed anyway.
+	SkipContentTypes []string
+
+	// SkipIf is a function that can be used to skip compression based on the request.
+	SkipIf func(req *http.Request) bool
+}
+
+// New creates a compression middleware.
+func New(config ...Config) gor.Middleware {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Level == 0 {
+		cfg.Level = flate.DefaultCompression
+	}
+
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = DefaultMinSize
+	}
+
+	if cfg.SkipContentTypes == nil {
+		cfg.SkipContentTypes = DefaultSkipContentTypes
+	}
+
+	gzipPool := &sync.Pool{New: func() any {
+		gw, _ := gzip.NewWriterLevel(io.Discard, cfg.Level)
+		return gw
+	}}
+
+	zlibPool := &sync.Pool{New: func() any {
+		zw, _ := zlib.NewWriterLevel(io.Discard, cfg.Level)
+		return zw
+	}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := Negotiate(req.Header.Get("Accept-Encoding"))
+			if encoding == "" || req.Method == http.MethodHead || (cfg.SkipIf != nil && cfg.SkipIf(req)) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				config:         &cfg,
+				encoding:       encoding,
+				gzipPool:       gzipPool,
+				zlibPool:       zlibPool,
+				status:         http.StatusOK,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, req)
+		})
+	}
+}
+
+// Negotiate returns the preferred supported encoding of an Accept-Encoding header,
+// or "" if the response must not be compressed. gzip is preferred over deflate
+// when both have the same quality. The wildcard "*" only applies to the encodings
+// that are not listed, so "*, gzip;q=0" refuses gzip.
+func Negotiate(acceptEncoding string) string {
+	qualities := make(map[string]float64, 3)
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = f
+			}
+		}
+		qualities[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range []string{Gzip, Deflate} {
+		q, listed := qualities[name]
+		if !listed {
+			q, listed = qualities["*"]
+		}
+
+		if listed && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of the response to decide whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	config   *Config
+	encoding string
+	gzipPool *sync.Pool
+	zlibPool *sync.Pool
+
+	status      int
+	wroteHeader bool // WriteHeader was called by the handler.
+	decided     bool // Headers are sent and compressor is set if compressing.
+	buf         []byte
+	compressor  io.WriteCloser
+	hijacked    bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader || cw.decided {
+		return
+	}
+
+	cw.status = status
+	cw.wroteHeader = true
+
+	// Informational responses are sent immediately.
+	if status >= 100 && status < 200 {
+		cw.wroteHeader = false
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		if cw.compressor != nil {
+			return cw.compressor.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	// Buffer up to MinSize bytes, the rest is written once decided.
+	n := min(len(p), cw.config.MinSize-len(cw.buf))
+	cw.buf = append(cw.buf, p[:n]...)
+	if len(cw.buf) < cw.config.MinSize {
+		return n, nil
+	}
+
+	if err := cw.decide(true); err != nil {
+		return 0, err
+	}
+
+	if n == len(p) {
+		return n, nil
+	}
+
+	m, err := cw.Write(p[n:])
+	return n + m, err
+}
+
+// Status returns the response status, for middlewares such as the logger
+// that run inside the compression middleware.
+func (cw *compressWriter) Status() int {
+	return cw.status
+}
+
+// decide sends the headers, compressing the response if allowed and enough
+// data was written, and writes the buffered data.
+func (cw *compressWriter) decide(enough bool) error {
+	cw.decided = true
+
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if enough && cw.shouldCompress() {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+
+		// Strong validators do not match the compressed representation.
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		if cw.encoding == Gzip {
+			gw := cw.gzipPool.Get().(*gzip.Writer)
+			gw.Reset(cw.ResponseWriter)
+			cw.compressor = gw
+		} else {
+			zw := cw.zlibPool.Get().(*zlib.Writer)
+			zw.Reset(cw.ResponseWriter)
+			cw.compressor = zw
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+
+	var err error
+	if cw.compressor != nil {
+		_, err = cw.compressor.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressWriter) shouldCompress() bool {
+	switch {
+	case cw.status < 200, cw.status == http.StatusNoContent, cw.status == http.StatusNotModified,
+		cw.status == http.StatusPartialContent:
+		return false
+	}
+
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	if strings.Contains(header.Get("Cache-Control"), "no-transform") {
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	if strings.HasPrefix(contentType, "image/svg+xml") {
+		return true
+	}
+
+	for _, skip := range cw.config.SkipContentTypes {
+		if strings.HasPrefix(contentType, skip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Flush sends the buffered data, compressing it if allowed, and flushes the
+// underlying writer.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(len(cw.buf) > 0)
+	}
+
+	if fw, ok := cw.compressor.(interface{ Flush() error }); ok {
+		fw.Flush()
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection. Buffered data is discarded.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		cw.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push.
+func (cw *compressWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := cw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close writes the buffered data of small responses and finishes the compressed stream.
+func (cw *compressWriter) Close() error {
+	if cw.hijacked {
+		return nil
+	}
+
+	if !cw.decided {
+		if !cw.wroteHeader && len(cw.buf) == 0 {
+			// Nothing was written, let the server send its default response.
+			return nil
+		}
+		return cw.decide(false)
+	}
+
+	if cw.compressor == nil {
+		return nil
+	}
+
+	err := cw.compressor.Close()
+	switch c := cw.compressor.(type) {
+	case *gzip.Writer:
+		cw.gzipPool.Put(c)
+	case *zlib.Writer:
+		cw.zlibPool.Put(c)
+	}
+	cw.compressor = nil
+	return err
+}
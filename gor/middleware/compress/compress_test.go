@@ -0,0 +1,277 @@
+package compress_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/compress"
+	"github.com/abiiranathan/gor/gor/middleware/logger"
+)
+
+var largeBody = strings.Repeat("hello world ", 200)
+
+func newRouter() *gor.Router {
+	r := gor.NewRouter()
+	r.Use(compress.New())
+
+	r.Get("/large", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(largeBody))
+	})
+
+	r.Get("/small", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("small"))
+	})
+
+	r.Get("/image", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte(largeBody))
+	})
+
+	r.Get("/stream", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(" second"))
+	})
+
+	r.Get("/created", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(largeBody))
+	})
+	return r
+}
+
+func get(r http.Handler, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"gzip":                  "gzip",
+		"deflate, gzip":         "gzip",
+		"gzip;q=0.5, deflate":   "deflate",
+		"br":                    "",
+		"gzip;q=0":              "",
+		"*":                     "gzip",
+		"*, gzip;q=0":           "deflate",
+		"gzip;q=0, *":           "deflate",
+		"*;q=0":                 "",
+		"*;q=0.5, deflate":      "deflate",
+		"identity, deflate;q=1": "deflate",
+	}
+
+	for header, want := range tests {
+		if got := compress.Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompressGzip(t *testing.T) {
+	w := get(newRouter(), "/large", "gzip, deflate")
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+	}
+
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != largeBody {
+		t.Errorf("unexpected body after decompression")
+	}
+}
+
+func TestCompressDeflate(t *testing.T) {
+	w := get(newRouter(), "/created", "deflate")
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", w.Code)
+	}
+
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("expected deflate encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	zr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != largeBody {
+		t.Errorf("unexpected body after decompression")
+	}
+}
+
+func TestCompressSkipped(t *testing.T) {
+	r := newRouter()
+
+	tests := []struct {
+		name, path, accept, body string
+	}{
+		{"not accepted", "/large", "", largeBody},
+		{"small", "/small", "gzip", "small"},
+		{"compressed content type", "/image", "gzip", largeBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(r, tt.path, tt.accept)
+
+			if enc := w.Header().Get("Content-Encoding"); enc != "" {
+				t.Errorf("expected no encoding, got %q", enc)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected the body unchanged, got %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	w := get(newRouter(), "/stream", "gzip")
+
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+
+	// Flushed responses are compressed even below the minimum size.
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(gr)
+	if string(body) != "first second" {
+		t.Errorf("expected %q, got %q", "first second", body)
+	}
+}
+
+func TestCompressHijack(t *testing.T) {
+	r := gor.NewRouter()
+	r.Use(compress.New())
+
+	r.Get("/ws", func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("expected the writer to implement http.Hijacker")
+		}
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 204 No Content\r\n\r\n"))
+		conn.Close()
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("expected the hijacked response, got %d", res.StatusCode)
+	}
+}
+
+func TestCompressLoggerInside(t *testing.T) {
+	var out bytes.Buffer
+
+	r := gor.NewRouter()
+	r.Use(compress.New(), logger.New(&logger.Config{Output: &out}))
+	r.Get("/created", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(largeBody))
+	})
+
+	w := get(r, "/created", "gzip")
+	if w.Code != http.StatusCreated || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a compressed 201, got %d %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+
+	if !strings.Contains(out.String(), "status=201") {
+		t.Errorf("expected the logged status to be 201, got %q", out.String())
+	}
+}
+
+// countingWriter records the size of each write to the underlying writer.
+type countingWriter struct {
+	*httptest.ResponseRecorder
+	writes []int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.writes = append(cw.writes, len(p))
+	return cw.ResponseRecorder.Write(p)
+}
+
+func TestCompressBuffersMinSize(t *testing.T) {
+	body := strings.Repeat("a", 4*compress.DefaultMinSize)
+	handler := compress.New()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Cache-Control", "no-transform")
+		n, err := w.Write([]byte(body))
+		if n != len(body) || err != nil {
+			t.Errorf("Write() = %d, %v, want %d, nil", n, err, len(body))
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(w, req)
+
+	if w.Body.String() != body {
+		t.Fatal("unexpected body")
+	}
+
+	want := []int{compress.DefaultMinSize, len(body) - compress.DefaultMinSize}
+	if len(w.writes) != 2 || w.writes[0] != want[0] || w.writes[1] != want[1] {
+		t.Errorf("expected writes of %v, got %v", want, w.writes)
+	}
+}
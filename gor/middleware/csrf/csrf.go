@@ -2,12 +2,18 @@ package csrf
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/abiiranathan/gor/gor"
 	"github.com/gorilla/sessions"
@@ -18,21 +24,41 @@ import (
 // If the token is not present, or is invalid, it returns a 403 Forbidden.
 // The token is expected to be in the request header, with the key "X-CSRF-Token"
 // or in the request body, with the key "csrf_token".
+//
+// Three modes are available:
+//
+//	New:             the token is stored in a gorilla/sessions session.
+//	NewDoubleSubmit: the token is stored in a signed cookie(no session needed).
+//	NewOriginCheck:  no token. Cross-origin requests are rejected based on the
+//	                 Sec-Fetch-Site and Origin headers, for same-origin-only APIs.
 
 const (
 	// The default key to look for the CSRF token in the request header, query, form, or cookie.
 	headerKeyName = "X-CSRF-Token"
 	formKeyName   = "csrf_token"
 	sessionName   = "csrf_session"
+	cookieName    = "_csrf"
 )
 
+// DefaultMaxAge is the default lifetime of tokens. See WithMaxAge.
+const DefaultMaxAge = 12 * time.Hour
+
 type TokenContextType string
 
+// Context keys. The token and the name of its form field are also set in
+// the CTX locals, available in templates as {{ .csrf_token }} and {{ .csrf_field_name }}.
+const (
+	tokenContextKey     = TokenContextType(formKeyName)
+	fieldNameContextKey = TokenContextType("csrf_field_name")
+	csrfContextKey      = TokenContextType("csrf")
+)
+
 var (
 	ErrMissingHeader  = errors.New("missing CSRF token in request header")
 	ErrMissingFormKey = errors.New("missing CSRF token in request body")
 	ErrInvalidToken   = errors.New("invalid CSRF token")
 	ErrMissingQuery   = errors.New("missing CSRF token in request query")
+	ErrCrossOrigin    = errors.New("cross-origin request denied")
 )
 
 // Extract the CSRF token from the request header.
@@ -62,7 +88,17 @@ func FromQuery(req *http.Request, key string) (string, error) {
 	return token, nil
 }
 
+type mode int
+
+const (
+	sessionMode mode = iota
+	doubleSubmitMode
+	originMode
+)
+
 type csrf struct {
+	mode mode
+
 	// The key to look for the CSRF token in the request header, query, form, or cookie.
 	// Defaults to "X-CSRF-Token".
 	HeaderKeyName string
@@ -74,9 +110,12 @@ type csrf struct {
 	// Name of the cookie session. defaults to "csrf_session"
 	SessionName string
 
+	// Name of the double-submit cookie. Defaults to "_csrf".
+	CookieName string
+
 	// The function to call when the CSRF token is invalid.
 	// If not set, the middleware will return a 403 Forbidden.
-	// The function should write the response and return true if the request should continue.
+	// The function should write the response and return true if it handled the error.
 	ErrorHandler func(w http.ResponseWriter, req *http.Request) bool
 
 	// This store must implement the gorilla/sessions.Store interface.
@@ -84,12 +123,70 @@ type csrf struct {
 	// The middleware will look for the CSRF token in the session first, before looking in the request.
 	Store sessions.Store
 
+	// Key signing the double-submit cookie.
+	secret []byte
+
+	// Lifetime of tokens.
+	maxAge time.Duration
+
+	// Returns the session the double-submit tokens are bound to.
+	sessionID func(req *http.Request) string
+
+	// Secure attribute of the double-submit cookie. If nil, it is set for TLS requests.
+	secure *bool
+
+	// Origins allowed by the origin check, in addition to the request host.
+	trustedOrigins map[string]bool
+
+	// Route patterns(see gor.RoutePattern) or paths that are not checked.
+	exempt map[string]bool
+
+	// Requests for which skipIf returns true are not checked.
+	skipIf func(req *http.Request) bool
+
 	// Must satisfy the CSRFTokenGetter interface.
 	// The function to call to get the CSRF token from the request.
 	tokenGetter func(req *http.Request) (string, error)
 }
 
-// New returns a new CSRF middleware.
+func newCSRF(m mode, options []CSRFOption) *csrf {
+	c := &csrf{
+		mode:           m,
+		HeaderKeyName:  headerKeyName,
+		FormKeyName:    formKeyName,
+		SessionName:    sessionName,
+		CookieName:     cookieName,
+		maxAge:         DefaultMaxAge,
+		trustedOrigins: make(map[string]bool),
+		exempt:         make(map[string]bool),
+	}
+
+	c.tokenGetter = func(req *http.Request) (string, error) {
+		contentType := strings.Split(req.Header.Get("Content-Type"), ";")[0]
+
+		switch contentType {
+		case "application/x-www-form-urlencoded", "multipart/form-data":
+			token, err := FromForm(req, c.FormKeyName)
+			if err != nil {
+				// htmx and fetch send the token in the header with form bodies.
+				if token, herr := FromHeader(req, c.HeaderKeyName); herr == nil {
+					return token, nil
+				}
+			}
+			return token, err
+		default:
+			return FromHeader(req, c.HeaderKeyName)
+		}
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+// New returns a new CSRF middleware storing the token in a session.
+// Tokens expire after DefaultMaxAge(see WithMaxAge).
 // Usage:
 //
 //	var store = sessions.NewCookieStore([]byte("secret key"))
@@ -104,36 +201,51 @@ type csrf struct {
 //
 //	mux.Use(middleware.New(store))
 func New(store sessions.Store, options ...CSRFOption) gor.Middleware {
-	c := &csrf{
-		HeaderKeyName: headerKeyName,
-		tokenGetter: func(req *http.Request) (string, error) {
-			contentType := strings.Split(req.Header.Get("Content-Type"), ";")[0]
-
-			switch contentType {
-			case "application/x-www-form-urlencoded":
-				return FromForm(req, formKeyName)
-			case "multipart/form-data":
-				return FromForm(req, formKeyName)
-			case "application/json":
-				return FromHeader(req, headerKeyName)
-			default:
-				return FromHeader(req, headerKeyName)
-			}
-		},
-		ErrorHandler: func(w http.ResponseWriter, req *http.Request) bool {
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return false
-		},
-		Store: store,
-	}
+	c := newCSRF(sessionMode, options)
+	c.Store = store
+	return c.Middleware
+}
 
-	for _, opt := range options {
-		opt(c)
+// NewDoubleSubmit returns a CSRF middleware that stores the token in a cookie signed
+// with secret(HMAC-SHA256), so that no session store is needed. Unsafe requests must
+// send the token of the cookie in the header or form, which other sites cannot read.
+// The signature covers the token and its expiry(see WithMaxAge), so they cannot be
+// forged without the secret.
+//
+// Without WithSessionID, a valid cookie obtained by an attacker(e.g by visiting the site)
+// can be planted in the browser of a victim by a subdomain able to set cookies.
+// WithSessionID binds the token to the session of the user(the signed double-submit
+// cookie recommended by OWASP), which prevents it:
+//
+//	mux.Use(csrf.NewDoubleSubmit([]byte(os.Getenv("CSRF_SECRET")),
+//		csrf.WithSessionID(func(req *http.Request) string {
+//			cookie, err := req.Cookie("session")
+//			if err != nil {
+//				return ""
+//			}
+//			return cookie.Value
+//		})))
+func NewDoubleSubmit(secret []byte, options ...CSRFOption) gor.Middleware {
+	if len(secret) == 0 {
+		panic("secret cannot be empty")
 	}
 
+	c := newCSRF(doubleSubmitMode, options)
+	c.secret = secret
 	return c.Middleware
 }
 
+// NewOriginCheck returns a CSRF middleware that rejects unsafe cross-origin requests
+// from browsers, without tokens. Use it for APIs that are only called from the same origin.
+//
+// Requests are allowed if the Sec-Fetch-Site header is "same-origin" or "none", or,
+// for browsers without Sec-Fetch-Site, if the Origin header matches the request host or a
+// trusted origin(see WithTrustedOrigins). Requests with neither header are not from
+// browsers and are allowed.
+func NewOriginCheck(options ...CSRFOption) gor.Middleware {
+	return newCSRF(originMode, options).Middleware
+}
+
 type CSRFOption func(*csrf)
 
 func WithHeaderKeyName(name string) CSRFOption {
@@ -154,15 +266,91 @@ func WithSessionName(name string) CSRFOption {
 	}
 }
 
-// Verify the CSRF token in the request against the token in the session.
-func (c *csrf) verifyToken(req *http.Request) bool {
-	session, err := c.Store.Get(req, sessionName)
-	if err != nil {
-		return false
+// WithCookieName sets the name of the double-submit cookie.
+func WithCookieName(name string) CSRFOption {
+	return func(c *csrf) {
+		c.CookieName = name
 	}
+}
 
-	expectedToken, ok := session.Values["token"].(string)
-	if !ok {
+// WithMaxAge sets the lifetime of tokens. Expired tokens are rejected and a new
+// token is issued on the next safe request. Default is DefaultMaxAge.
+func WithMaxAge(maxAge time.Duration) CSRFOption {
+	return func(c *csrf) {
+		c.maxAge = maxAge
+	}
+}
+
+// WithSessionID binds the double-submit tokens to the session returned by sessionID,
+// e.g the session cookie or the user ID, or "" for anonymous requests. A token is only
+// valid for the session it was issued to. Rotate the token(see RotateToken) when the
+// session changes, e.g after login, once sessionID returns the new session.
+func WithSessionID(sessionID func(req *http.Request) string) CSRFOption {
+	return func(c *csrf) {
+		c.sessionID = sessionID
+	}
+}
+
+// WithSecure sets the Secure attribute of the double-submit cookie.
+// By default it is set for TLS requests only, enable it behind a TLS-terminating proxy.
+func WithSecure(secure bool) CSRFOption {
+	return func(c *csrf) {
+		c.secure = &secure
+	}
+}
+
+// WithTrustedOrigins allows unsafe requests from other origins(e.g "https://admin.example.com")
+// in the origin check mode.
+func WithTrustedOrigins(origins ...string) CSRFOption {
+	return func(c *csrf) {
+		for _, origin := range origins {
+			c.trustedOrigins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+		}
+	}
+}
+
+// WithExempt disables the check for routes, given as route patterns as registered
+// with the router(e.g "POST /webhooks/stripe", see gor.RoutePattern) or URL paths.
+func WithExempt(routes ...string) CSRFOption {
+	return func(c *csrf) {
+		for _, route := range routes {
+			c.exempt[route] = true
+		}
+	}
+}
+
+// WithSkipIf disables the check for requests for which skip returns true.
+func WithSkipIf(skip func(req *http.Request) bool) CSRFOption {
+	return func(c *csrf) {
+		c.skipIf = skip
+	}
+}
+
+// WithErrorHandler sets the function called when the check fails.
+// It should write the response and return true. If it returns false,
+// a 403 Forbidden is sent.
+func WithErrorHandler(handler func(w http.ResponseWriter, req *http.Request) bool) CSRFOption {
+	return func(c *csrf) {
+		c.ErrorHandler = handler
+	}
+}
+
+// isSafe reports whether the method does not change state(GET, HEAD, OPTIONS, TRACE).
+func isSafe(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead ||
+		method == http.MethodOptions || method == http.MethodTrace
+}
+
+func (c *csrf) isExempt(req *http.Request) bool {
+	if c.skipIf != nil && c.skipIf(req) {
+		return true
+	}
+	return c.exempt[gor.RoutePattern(req)] || c.exempt[req.URL.Path]
+}
+
+// Verify the CSRF token in the request against the expected token.
+func (c *csrf) verifyToken(req *http.Request, expectedToken string) bool {
+	if expectedToken == "" {
 		return false
 	}
 
@@ -171,7 +359,7 @@ func (c *csrf) verifyToken(req *http.Request) bool {
 		return false
 	}
 
-	return token == expectedToken
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expectedToken)) == 1
 }
 
 // createToken generates a random CSRF token.
@@ -186,70 +374,209 @@ func createToken() (string, error) {
 	return escapedToken, nil
 }
 
+// sign returns the HMAC of the token, its expiry(unix milliseconds) and the session
+// of the request, base64 encoded.
+func (c *csrf) sign(req *http.Request, token string, expires int64) string {
+	var session string
+	if c.sessionID != nil {
+		session = c.sessionID(req)
+	}
+
+	mac := hmac.New(sha256.New, c.secret)
+	// The lengths prevent moving bytes between the values.
+	fmt.Fprintf(mac, "%d!%s!%d!%s!%d", len(session), session, len(token), token, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// storedToken returns the token stored in the session or cookie,
+// or "" if none or if it expired.
+func (c *csrf) storedToken(req *http.Request) (string, error) {
+	now := time.Now().UnixMilli()
+
+	switch c.mode {
+	case sessionMode:
+		session, err := c.Store.Get(req, c.SessionName)
+		if err != nil {
+			return "", err
+		}
+
+		token, _ := session.Values["token"].(string)
+		expires, _ := session.Values["expires"].(int64)
+		if now >= expires {
+			return "", nil
+		}
+		return token, nil
+	case doubleSubmitMode:
+		cookie, err := req.Cookie(c.CookieName)
+		if err != nil {
+			return "", nil
+		}
+
+		// token.expires.signature
+		parts := strings.Split(cookie.Value, ".")
+		if len(parts) != 3 {
+			return "", nil
+		}
+
+		token := parts[0]
+		expires, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || !hmac.Equal([]byte(parts[2]), []byte(c.sign(req, token, expires))) || now >= expires {
+			// Tampered, expired or issued to another session, a new token is issued.
+			return "", nil
+		}
+		return token, nil
+	}
+	return "", nil
+}
+
+// saveToken stores a new token in the session or cookie.
+func (c *csrf) saveToken(w http.ResponseWriter, req *http.Request) (string, error) {
+	token, err := createToken()
+	if err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(c.maxAge)
+
+	switch c.mode {
+	case sessionMode:
+		session, err := c.Store.Get(req, c.SessionName)
+		if err != nil {
+			return "", err
+		}
+
+		session.Values["token"] = token
+		session.Values["expires"] = expires.UnixMilli()
+		if err := session.Save(req, w); err != nil {
+			return "", err
+		}
+	case doubleSubmitMode:
+		secure := req.TLS != nil
+		if c.secure != nil {
+			secure = *c.secure
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     c.CookieName,
+			Value:    token + "." + strconv.FormatInt(expires.UnixMilli(), 10) + "." + c.sign(req, token, expires.UnixMilli()),
+			Path:     "/",
+			Expires:  expires,
+			HttpOnly: true,
+			Secure:   secure,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	return token, nil
+}
+
+// setToken exposes the token to handlers, templates and scripts(through the response header).
+func (c *csrf) setToken(w http.ResponseWriter, req *http.Request, token string) {
+	w.Header().Set(c.HeaderKeyName, token)
+	gor.SetContextValue(req, tokenContextKey, token)
+}
+
+func (c *csrf) fail(w http.ResponseWriter, req *http.Request, message string) {
+	if c.ErrorHandler != nil && c.ErrorHandler(w, req) {
+		return
+	}
+	http.Error(w, message, http.StatusForbidden)
+}
+
 // Middleware implements the CSRF protection middleware.
 func (c *csrf) Middleware(next http.Handler) http.Handler {
-	if c.Store == nil {
+	if c.mode == sessionMode && c.Store == nil {
 		panic("Store cannot be nil")
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		// Get or create CSRF token.
-		session, err := c.Store.Get(req, sessionName)
-		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		if c.isExempt(req) {
+			next.ServeHTTP(w, req)
 			return
 		}
 
-		token, ok := session.Values["token"].(string)
-		if !ok || token == "" {
-			token, err = createToken()
-			if err != nil {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		if c.mode == originMode {
+			if !isSafe(req.Method) && !c.sameOrigin(req) {
+				c.fail(w, req, ErrCrossOrigin.Error())
 				return
 			}
+			next.ServeHTTP(w, req)
+			return
+		}
 
-			session.Values["token"] = token
-			err = session.Save(req, w)
-			if err != nil {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				return
-			}
+		ctx := context.WithValue(req.Context(), csrfContextKey, c)
+		*req = *req.WithContext(ctx)
+		gor.SetContextValue(req, fieldNameContextKey, c.FormKeyName)
+
+		// Get or create CSRF token.
+		token, err := c.storedToken(req)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
 		}
 
 		// Skip CSRF check for safe methods (GET, HEAD, OPTIONS, TRACE).
-		if req.Method == http.MethodGet || req.Method == http.MethodHead ||
-			req.Method == http.MethodOptions || req.Method == http.MethodTrace {
+		if isSafe(req.Method) {
+			if token == "" {
+				token, err = c.saveToken(w, req)
+				if err != nil {
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+			}
+
 			// We still need to set the token in the response header for GET requests.
 			// if the key is not valid, the next request will fail.
-			w.Header().Set(c.HeaderKeyName, token)
-			gor.SetContextValue(req, TokenContextType(formKeyName), token)
-
-			// fmt.Println("Token:", token)
+			c.setToken(w, req, token)
 			next.ServeHTTP(w, req)
 			return
 		}
 
 		// Verify CSRF token.
-		if !c.verifyToken(req) {
-			if c.ErrorHandler != nil && c.ErrorHandler(w, req) {
-				return
-			}
-			http.Error(w, "CSRF token validation failed", http.StatusForbidden)
+		if !c.verifyToken(req, token) {
+			c.fail(w, req, "CSRF token validation failed")
 			return
 		}
 
-		ctx := context.WithValue(req.Context(), TokenContextType(formKeyName), token)
-		*req = *req.WithContext(ctx)
+		c.setToken(w, req, token)
 
 		// Continue with the next handler if all checks pass.
 		next.ServeHTTP(w, req)
 	})
 }
 
+// RotateToken replaces the CSRF token of the client with a new one and returns it.
+// Call it when the privileges of the user change, e.g after login or logout,
+// so that a token obtained before cannot be reused.
+//
+// The request must have been handled by the session or double-submit middleware.
+func RotateToken(w http.ResponseWriter, req *http.Request) (string, error) {
+	c, ok := req.Context().Value(csrfContextKey).(*csrf)
+	if !ok {
+		return "", errors.New("csrf: the request was not handled by the CSRF middleware")
+	}
+
+	token, err := c.saveToken(w, req)
+	if err != nil {
+		return "", err
+	}
+
+	c.setToken(w, req, token)
+	return token, nil
+}
+
 func TokenFromRequest(req *http.Request) string {
-	token, ok := gor.GetContextValue(req, TokenContextType(formKeyName)).(string)
+	token, ok := gor.GetContextValue(req, tokenContextKey).(string)
 	if !ok {
 		return ""
 	}
 	return token
 }
+
+// fieldName returns the name of the form field of the token.
+func fieldName(req *http.Request) string {
+	name, ok := gor.GetContextValue(req, fieldNameContextKey).(string)
+	if !ok || name == "" {
+		return formKeyName
+	}
+	return name
+}
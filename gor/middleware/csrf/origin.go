@@ -0,0 +1,37 @@
+package csrf
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sameOrigin reports whether an unsafe request may be processed in the origin check mode.
+func (c *csrf) sameOrigin(req *http.Request) bool {
+	origin := strings.ToLower(req.Header.Get("Origin"))
+	if origin != "" && c.trustedOrigins[origin] {
+		return true
+	}
+
+	switch req.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		// "none" is a navigation initiated by the user, e.g a bookmark.
+		return true
+	case "":
+		// Older browsers: compare the Origin header with the host.
+	default:
+		// "same-site" and "cross-site".
+		return false
+	}
+
+	if origin == "" {
+		// Not a browser request, browsers send Origin with unsafe requests.
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, req.Host)
+}
@@ -0,0 +1,56 @@
+package csrf
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// FuncMap returns the template functions of the CSRF middleware:
+//
+//	csrf_field: a hidden input with the CSRF token. See Field.
+//
+// Add them to the functions used to parse the templates:
+//
+//	funcMap := csrf.FuncMap()
+//	t, err := gor.ParseTemplatesRecursive("templates", funcMap)
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"csrf_field": Field,
+	}
+}
+
+// Field returns a hidden input with the CSRF token, named after the form key name
+// of the middleware("csrf_token" by default).
+//
+// v is the template data(with gor.PassContextToViews enabled), the request or the token:
+//
+//	<form method="post">{{ csrf_field . }}...</form>
+func Field(v any) (template.HTML, error) {
+	var token, name string
+
+	switch data := v.(type) {
+	case string:
+		token = data
+	case *http.Request:
+		token, name = TokenFromRequest(data), fieldName(data)
+	case gor.Map:
+		token, name = stringValue(data[formKeyName]), stringValue(data[string(fieldNameContextKey)])
+	case map[string]any:
+		token, name = stringValue(data[formKeyName]), stringValue(data[string(fieldNameContextKey)])
+	default:
+		return "", fmt.Errorf("csrf_field: expected the template data, a request or a token, got %T", v)
+	}
+
+	if token == "" {
+		return "", fmt.Errorf("csrf_field: no CSRF token, is the CSRF middleware and gor.PassContextToViews enabled?")
+	}
+	return gor.CSRFInput(token, name), nil
+}
+
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return s
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/requestid"
 )
 
 // LogFormat is the format of the log output, compatible with the new slog package.
@@ -117,7 +118,7 @@ func (l *Config) Logger(handler http.Handler) http.Handler {
 			logger = slog.New(slog.NewTextHandler(l.Output, l.Options))
 		}
 
-		args := []any{"status", w.(*gor.ResponseWriter).Status()}
+		args := []any{"status", status(w)}
 		if l.Flags&LOG_LATENCY != 0 {
 			args = append(args, "latency", latency)
 		}
@@ -132,6 +133,15 @@ func (l *Config) Logger(handler http.Handler) http.Handler {
 			args = append(args, "user_agent", req.UserAgent())
 		}
 
+		// Set by the requestid middleware.
+		if id := requestid.Get(req); id != "" {
+			args = append(args, "request_id", id)
+		}
+
+		if tc, ok := requestid.Trace(req); ok {
+			args = append(args, "trace_id", tc.TraceID, "span_id", tc.SpanID)
+		}
+
 		if l.Callback != nil {
 			args = l.Callback(req, args...)
 
@@ -143,3 +153,18 @@ func (l *Config) Logger(handler http.Handler) http.Handler {
 		logger.Info("", args...)
 	})
 }
+
+// status returns the response status of w: the status of the first writer implementing
+// Status() int(e.g *gor.ResponseWriter) found by unwrapping the writers of middlewares.
+func status(w http.ResponseWriter) int {
+	for {
+		switch rw := w.(type) {
+		case interface{ Status() int }:
+			return rw.Status()
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return http.StatusOK
+		}
+	}
+}
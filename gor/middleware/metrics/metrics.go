@@ -0,0 +1,306 @@
+/*
+Package metrics records HTTP request metrics and exposes them in the Prometheus
+text exposition format, without depending on the Prometheus client library.
+
+	m := metrics.New()
+	r.Use(m.Middleware)
+	r.Get("/metrics", m.ServeHTTP)
+
+Requests are labeled by method, route pattern(e.g "/users/{id}", not "/users/42")
+and status code. The metrics are:
+
+	gor_http_requests_total              counter
+	gor_http_request_duration_seconds    histogram
+	gor_http_response_size_bytes         histogram
+	gor_http_requests_in_flight          gauge
+*/
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds of the request duration histogram in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the upper bounds of the response size histogram in bytes.
+var DefaultSizeBuckets = []float64{100, 1000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+// Config is the configuration of the metrics.
+type Config struct {
+	// Namespace is the prefix of the metric names. Default is "gor".
+	Namespace string
+
+	// Buckets of the request duration histogram. Default is DefaultBuckets.
+	Buckets []float64
+
+	// SizeBuckets of the response size histogram. Default is DefaultSizeBuckets.
+	SizeBuckets []float64
+
+	// SkipIf is a function that can be used to skip recording requests,
+	// e.g requests to the metrics endpoint itself.
+	SkipIf func(req *http.Request) bool
+}
+
+// Metrics records the metrics of the requests handled by its middleware.
+// It is safe for concurrent use.
+type Metrics struct {
+	config   Config
+	inFlight atomic.Int64
+
+	mu     sync.Mutex
+	series map[labels]*series
+}
+
+// labels identify a series.
+type labels struct {
+	method string
+	route  string
+	status int
+}
+
+type series struct {
+	count    uint64
+	duration histogram
+	size     histogram
+}
+
+// histogram stores non-cumulative counts per bucket. The last count is for +Inf.
+type histogram struct {
+	counts []uint64
+	sum    float64
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(bounds)+1)
+	}
+
+	i := sort.SearchFloat64s(bounds, v) // First bound >= v
+	h.counts[i]++
+	h.sum += v
+}
+
+// New creates the metrics.
+func New(config ...Config) *Metrics {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Namespace == "" {
+		cfg.Namespace = "gor"
+	}
+
+	if cfg.Buckets == nil {
+		cfg.Buckets = DefaultBuckets
+	}
+
+	if cfg.SizeBuckets == nil {
+		cfg.SizeBuckets = DefaultSizeBuckets
+	}
+
+	cfg.Buckets = sortedCopy(cfg.Buckets)
+	cfg.SizeBuckets = sortedCopy(cfg.SizeBuckets)
+
+	return &Metrics{config: cfg, series: make(map[labels]*series)}
+}
+
+func sortedCopy(values []float64) []float64 {
+	c := append([]float64(nil), values...)
+	sort.Float64s(c)
+	return c
+}
+
+// Middleware records the metrics of requests. It is a gor.Middleware.
+//
+// The status and size are read from the gor.ResponseWriter of the router,
+// also when the writer is wrapped by middlewares implementing Unwrap.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if m.config.SkipIf != nil && m.config.SkipIf(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		start := time.Now()
+		next.ServeHTTP(w, req)
+		elapsed := time.Since(start).Seconds()
+
+		status, size := http.StatusOK, 0
+		if rw := gorResponseWriter(w); rw != nil {
+			status, size = rw.Status(), rw.Size()
+		}
+
+		m.observe(labels{method: req.Method, route: route(req), status: status}, elapsed, size)
+	})
+}
+
+// gorResponseWriter finds the *gor.ResponseWriter wrapped by w.
+func gorResponseWriter(w http.ResponseWriter) *gor.ResponseWriter {
+	for {
+		switch rw := w.(type) {
+		case *gor.ResponseWriter:
+			return rw
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// route returns the path of the matched route pattern, without the method.
+func route(req *http.Request) string {
+	pattern := gor.RoutePattern(req)
+	if pattern == "" {
+		return "unmatched"
+	}
+
+	// Patterns are "[METHOD ][HOST]/PATH".
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = strings.TrimSpace(path)
+	}
+	return pattern
+}
+
+func (m *Metrics) observe(l labels, seconds float64, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.series[l]
+	if !ok {
+		s = &series{}
+		m.series[l] = s
+	}
+
+	s.count++
+	s.duration.observe(m.config.Buckets, seconds)
+	s.size.observe(m.config.SizeBuckets, float64(size))
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	keys := make([]labels, 0, len(m.series))
+	snapshot := make(map[labels]series, len(m.series))
+	for l, s := range m.series {
+		keys = append(keys, l)
+		snapshot[l] = series{
+			count:    s.count,
+			duration: histogram{counts: append([]uint64(nil), s.duration.counts...), sum: s.duration.sum},
+			size:     histogram{counts: append([]uint64(nil), s.size.counts...), sum: s.size.sum},
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	ew := &errWriter{w: w}
+	ns := m.config.Namespace
+
+	name := ns + "_http_requests_total"
+	ew.printf("# HELP %s Total number of HTTP requests.\n# TYPE %s counter\n", name, name)
+	for _, l := range keys {
+		ew.printf("%s{%s} %d\n", name, l.String(), snapshot[l].count)
+	}
+
+	name = ns + "_http_request_duration_seconds"
+	ew.printf("# HELP %s Duration of HTTP requests in seconds.\n# TYPE %s histogram\n", name, name)
+	for _, l := range keys {
+		writeHistogram(ew, name, l.String(), m.config.Buckets, snapshot[l].duration)
+	}
+
+	name = ns + "_http_response_size_bytes"
+	ew.printf("# HELP %s Size of HTTP response bodies in bytes.\n# TYPE %s histogram\n", name, name)
+	for _, l := range keys {
+		writeHistogram(ew, name, l.String(), m.config.SizeBuckets, snapshot[l].size)
+	}
+
+	name = ns + "_http_requests_in_flight"
+	ew.printf("# HELP %s Number of HTTP requests being served.\n# TYPE %s gauge\n", name, name)
+	ew.printf("%s %d\n", name, m.inFlight.Load())
+
+	return ew.n, ew.err
+}
+
+func writeHistogram(ew *errWriter, name, labels string, bounds []float64, h histogram) {
+	var cumulative, total uint64
+	for _, c := range h.counts {
+		total += c
+	}
+
+	for i, bound := range bounds {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		ew.printf("%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), cumulative)
+	}
+
+	ew.printf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, total)
+	ew.printf("%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	ew.printf("%s_count{%s} %d\n", name, labels, total)
+}
+
+// String formats the labels for the exposition format.
+func (l labels) String() string {
+	return fmt.Sprintf(`method="%s",route="%s",status="%d"`, escape(l.method), escape(l.route), l.status)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// errWriter keeps the first write error and the number of bytes written.
+type errWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(ew.w, format, args...)
+	ew.n += int64(n)
+	ew.err = err
+}
@@ -0,0 +1,69 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/compress"
+	"github.com/abiiranathan/gor/gor/middleware/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New(metrics.Config{
+		Buckets: []float64{1, 0.1},
+		SkipIf: func(req *http.Request) bool {
+			return req.URL.Path == "/metrics"
+		},
+	})
+
+	r := gor.NewRouter()
+	r.Use(m.Middleware, compress.New())
+	r.Get("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("user " + req.PathValue("id")))
+	})
+	r.Post("/users", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	r.Get("/metrics", m.ServeHTTP)
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/3"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Header().Get("Content-Type") != metrics.ContentType {
+		t.Errorf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+
+	body := w.Body.String()
+	want := []string{
+		"# TYPE gor_http_requests_total counter",
+		`gor_http_requests_total{method="GET",route="/users/{id}",status="200"} 3`,
+		`gor_http_requests_total{method="POST",route="/users",status="201"} 1`,
+		"# TYPE gor_http_request_duration_seconds histogram",
+		`gor_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="200",le="0.1"} 3`,
+		`gor_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="200",le="+Inf"} 3`,
+		`gor_http_request_duration_seconds_count{method="GET",route="/users/{id}",status="200"} 3`,
+		`gor_http_response_size_bytes_bucket{method="GET",route="/users/{id}",status="200",le="100"} 3`,
+		`gor_http_response_size_bytes_sum{method="GET",route="/users/{id}",status="200"} 18`,
+		`gor_http_response_size_bytes_sum{method="POST",route="/users",status="201"} 0`,
+		"# TYPE gor_http_requests_in_flight gauge",
+		"gor_http_requests_in_flight 0",
+	}
+
+	for _, line := range want {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, body)
+		}
+	}
+
+	if strings.Contains(body, "/users/1") || strings.Contains(body, "/metrics") {
+		t.Errorf("expected requests to be labeled by route and skipped requests to be ignored:\n%s", body)
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Package ratelimit limits the rate of requests per client.
+
+	// 100 requests per minute per IP address for the whole application.
+	r.Use(ratelimit.New(ratelimit.Config{Limit: ratelimit.PerMinute(100)}))
+
+	// 5 login attempts per minute, counted separately from the global limit.
+	r.Post("/login", login, ratelimit.New(ratelimit.Config{
+		Limit:     ratelimit.PerMinute(5),
+		Algorithm: ratelimit.SlidingWindow,
+	}))
+
+	// Per user limits for the API, keyed by the JWT subject.
+	api := r.Group("/api", auth.JWT(secret))
+	api.Use(ratelimit.New(ratelimit.Config{Limit: ratelimit.PerSecond(10), KeyFunc: ratelimit.ByJWTSubject}))
+
+Responses carry the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+RateLimit-Policy headers. Rejected requests get a 429 Too Many Requests with
+a Retry-After header.
+*/
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/auth"
+)
+
+// Algorithm is the rate limiting algorithm.
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts of up to Limit.Burst requests and refills
+	// Limit.Requests tokens per Limit.Period. This is the default.
+	TokenBucket Algorithm = iota
+
+	// SlidingWindow allows Limit.Requests requests in any window of Limit.Period.
+	// It does not allow bursts above the limit.
+	SlidingWindow
+)
+
+// Limit is the number of requests allowed per period.
+type Limit struct {
+	Requests int           // Number of requests allowed per period.
+	Period   time.Duration // Length of the period.
+	Burst    int           // Token bucket only. Maximum burst of requests. Default is Requests.
+}
+
+// PerSecond returns a limit of n requests per second.
+func PerSecond(n int) Limit {
+	return Limit{Requests: n, Period: time.Second}
+}
+
+// PerMinute returns a limit of n requests per minute.
+func PerMinute(n int) Limit {
+	return Limit{Requests: n, Period: time.Minute}
+}
+
+// PerHour returns a limit of n requests per hour.
+func PerHour(n int) Limit {
+	return Limit{Requests: n, Period: time.Hour}
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+func (l Limit) validate() error {
+	if l.Requests <= 0 || l.Period <= 0 {
+		return fmt.Errorf("ratelimit: invalid limit %d per %s", l.Requests, l.Period)
+	}
+	return nil
+}
+
+// Result is the outcome of Store.Take.
+type Result struct {
+	Allowed    bool
+	Limit      int           // Maximum number of requests.
+	Remaining  int           // Requests left.
+	Reset      time.Duration // Time until the limit is fully available again.
+	RetryAfter time.Duration // Time until the next request is allowed, if not allowed.
+}
+
+// KeyFunc returns the key identifying the client of a request.
+type KeyFunc func(req *http.Request) (string, error)
+
+// ByIP identifies clients by their IP address. See gor.ClientIPAddress.
+func ByIP(req *http.Request) (string, error) {
+	return gor.ClientIPAddress(req)
+}
+
+// ByJWTSubject identifies clients by the "sub" claim of the token verified by
+// auth.JWT or an auth.Verifier and falls back to the IP address for anonymous requests.
+func ByJWTSubject(req *http.Request) (string, error) {
+	if claims := auth.TokenClaims(req); claims != nil {
+		if sub, err := claims.GetSubject(); err == nil && sub != "" {
+			return "sub:" + sub, nil
+		}
+	}
+	return ByIP(req)
+}
+
+// Config is the configuration of the rate limiting middleware.
+type Config struct {
+	// Limit is the number of requests allowed per period. Required.
+	Limit Limit
+
+	// Algorithm is the rate limiting algorithm. Default is TokenBucket.
+	Algorithm Algorithm
+
+	// Store keeps the state of the limits. Default is a new MemoryStore.
+	// Share a store between middlewares to save memory; their keys are kept apart by Name.
+	Store Store
+
+	// KeyFunc identifies the client. Default is ByIP.
+	KeyFunc KeyFunc
+
+	// Name is prefixed to the keys in the store. Middlewares with the same name and
+	// store share their limits. Default is a name unique to each middleware.
+	Name string
+
+	// SkipIf is a function that can be used to skip rate limiting based on the request.
+	SkipIf func(req *http.Request) bool
+
+	// OnLimited writes the response of rejected requests.
+	// Default is a 429 Too Many Requests with a plain text message.
+	OnLimited http.HandlerFunc
+}
+
+var limiterCount atomic.Int64
+
+// New creates a rate limiting middleware.
+// It panics if the limit is invalid.
+//
+// Errors of the store or KeyFunc are sent with gor.SendError and a 500 status.
+func New(config Config) gor.Middleware {
+	if err := config.Limit.validate(); err != nil {
+		panic(err)
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+
+	if config.KeyFunc == nil {
+		config.KeyFunc = ByIP
+	}
+
+	if config.Name == "" {
+		config.Name = "ratelimit" + strconv.FormatInt(limiterCount.Add(1), 10)
+	}
+
+	if config.OnLimited == nil {
+		config.OnLimited = func(w http.ResponseWriter, req *http.Request) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}
+	}
+
+	policy := fmt.Sprintf("%d;w=%d", config.Limit.Requests, int(math.Ceil(config.Limit.Period.Seconds())))
+	if config.Algorithm == TokenBucket && config.Limit.Burst > 0 {
+		policy += fmt.Sprintf(";burst=%d", config.Limit.Burst)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if config.SkipIf != nil && config.SkipIf(req) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			key, err := config.KeyFunc(req)
+			if err != nil {
+				gor.SendError(w, req, fmt.Errorf("ratelimit: %w", err), http.StatusInternalServerError)
+				return
+			}
+
+			result, err := config.Store.Take(req.Context(), config.Name+":"+key, config.Limit, config.Algorithm)
+			if err != nil {
+				gor.SendError(w, req, fmt.Errorf("ratelimit: %w", err), http.StatusInternalServerError)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+			header.Set("RateLimit-Policy", policy)
+
+			if !result.Allowed {
+				header.Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+				config.OnLimited(w, req)
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
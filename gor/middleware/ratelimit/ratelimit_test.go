@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore() (*MemoryStore, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore(4)
+	store.now = c.Now
+	return store, c
+}
+
+func TestTokenBucket(t *testing.T) {
+	store, c := newTestStore()
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 3}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		res, _ := store.Take(ctx, "a", limit, TokenBucket)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("request %d: expected allowed with %d remaining, got %+v", i, 2-i, res)
+		}
+	}
+
+	res, _ := store.Take(ctx, "a", limit, TokenBucket)
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected rejection with retry after 500ms, got %+v", res)
+	}
+
+	// Other keys are independent.
+	if res, _ := store.Take(ctx, "b", limit, TokenBucket); !res.Allowed {
+		t.Fatalf("expected another key to be allowed, got %+v", res)
+	}
+
+	c.now = c.now.Add(500 * time.Millisecond)
+	if res, _ := store.Take(ctx, "a", limit, TokenBucket); !res.Allowed {
+		t.Fatalf("expected a token after refill, got %+v", res)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	store, c := newTestStore()
+	limit := PerMinute(4)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if res, _ := store.Take(ctx, "a", limit, SlidingWindow); !res.Allowed {
+			t.Fatalf("request %d: expected allowed, got %+v", i, res)
+		}
+	}
+
+	res, _ := store.Take(ctx, "a", limit, SlidingWindow)
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected rejection, got %+v", res)
+	}
+
+	// 4 requests in the previous window weigh 2 halfway through the next one.
+	c.now = c.now.Add(90 * time.Second)
+	for i := 0; i < 2; i++ {
+		if res, _ := store.Take(ctx, "a", limit, SlidingWindow); !res.Allowed {
+			t.Fatalf("request %d: expected allowed, got %+v", i, res)
+		}
+	}
+
+	res, _ = store.Take(ctx, "a", limit, SlidingWindow)
+	if res.Allowed {
+		t.Fatalf("expected rejection, got %+v", res)
+	}
+
+	// The previous window must decay to 1 request: 45s into the window.
+	if res.RetryAfter != 15*time.Second {
+		t.Errorf("expected retry after 15s, got %s", res.RetryAfter)
+	}
+
+	c.now = c.now.Add(res.RetryAfter)
+	if res, _ := store.Take(ctx, "a", limit, SlidingWindow); !res.Allowed {
+		t.Errorf("expected allowed after retry after, got %+v", res)
+	}
+}
+
+func TestInvalidLimit(t *testing.T) {
+	store := NewMemoryStore()
+	if _, err := store.Take(context.Background(), "a", Limit{}, TokenBucket); err == nil {
+		t.Error("expected an error for an invalid limit")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected New to panic for an invalid limit")
+		}
+	}()
+	New(Config{})
+}
+
+func TestMiddleware(t *testing.T) {
+	r := gor.NewRouter()
+	r.Use(New(Config{Limit: PerMinute(2)}))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	r.Get("/login", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	}, New(Config{Limit: PerMinute(1), Algorithm: SlidingWindow}))
+
+	do := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/login", "10.0.0.1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	// The route limit applies before the global limit is exhausted.
+	w = do("/login", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+
+	// The request at the start of the window still weighs fully on the next one.
+	if w.Header().Get("Retry-After") != "120" {
+		t.Errorf("expected Retry-After 120, got %q", w.Header().Get("Retry-After"))
+	}
+
+	if w.Header().Get("RateLimit-Policy") != "1;w=60" {
+		t.Errorf("expected the route policy, got %q", w.Header().Get("RateLimit-Policy"))
+	}
+
+	w = do("/", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the global limit to be exhausted, got %d", w.Code)
+	}
+
+	w = do("/", "10.0.0.2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected another client to be allowed, got %d", w.Code)
+	}
+
+	if got := w.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("expected RateLimit-Limit 2, got %q", got)
+	}
+
+	if got := w.Header().Get("RateLimit-Remaining"); got != "1" {
+		t.Errorf("expected RateLimit-Remaining 1, got %q", got)
+	}
+}
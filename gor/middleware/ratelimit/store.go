@@ -0,0 +1,194 @@
+package ratelimit
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store keeps the state of the rate limits.
+// Implementations must be safe for concurrent use.
+//
+// A shared backend(e.g Redis) implements Take atomically so that several
+// instances of the application enforce the same limits.
+type Store interface {
+	// Take consumes one request for key and reports whether it is allowed.
+	Take(ctx context.Context, key string, limit Limit, algorithm Algorithm) (Result, error)
+}
+
+// DefaultShards is the number of shards of a MemoryStore created without a shard count.
+const DefaultShards = 32
+
+// sweepEvery is the number of Take calls on a shard between removals of expired entries.
+const sweepEvery = 1024
+
+// MemoryStore is an in-memory Store. Keys are spread over shards, each with its
+// own lock, to reduce contention. Idle keys are removed periodically.
+type MemoryStore struct {
+	shards []*shard
+	now    func() time.Time
+}
+
+type shard struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	takes   int
+}
+
+// entry is the state of a key. The token bucket uses tokens and last,
+// the sliding window uses start, prev and curr.
+type entry struct {
+	tokens float64
+	last   time.Time
+
+	start time.Time
+	prev  int
+	curr  int
+
+	expires time.Time
+}
+
+// NewMemoryStore creates an in-memory store with the given number of shards(default DefaultShards).
+func NewMemoryStore(shards ...int) *MemoryStore {
+	n := DefaultShards
+	if len(shards) > 0 && shards[0] > 0 {
+		n = shards[0]
+	}
+
+	s := &MemoryStore{shards: make([]*shard, n), now: time.Now}
+	for i := range s.shards {
+		s.shards[i] = &shard{entries: make(map[string]*entry)}
+	}
+	return s
+}
+
+func (s *MemoryStore) shard(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, algorithm Algorithm) (Result, error) {
+	if err := limit.validate(); err != nil {
+		return Result{}, err
+	}
+
+	now := s.now()
+	sh := s.shard(key)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.takes++
+	if sh.takes%sweepEvery == 0 {
+		sh.sweep(now)
+	}
+
+	e, ok := sh.entries[key]
+	if !ok || now.After(e.expires) {
+		e = &entry{tokens: float64(limit.burst()), last: now, start: now}
+		sh.entries[key] = e
+	}
+
+	if algorithm == SlidingWindow {
+		return e.slidingWindow(now, limit), nil
+	}
+	return e.tokenBucket(now, limit), nil
+}
+
+// Reset removes the state of key, e.g after a successful login.
+func (s *MemoryStore) Reset(key string) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	delete(sh.entries, key)
+}
+
+func (sh *shard) sweep(now time.Time) {
+	for key, e := range sh.entries {
+		if now.After(e.expires) {
+			delete(sh.entries, key)
+		}
+	}
+}
+
+// tokenBucket refills limit.Requests tokens per limit.Period up to the burst size.
+func (e *entry) tokenBucket(now time.Time, limit Limit) Result {
+	capacity := float64(limit.burst())
+	rate := float64(limit.Requests) / float64(limit.Period) // tokens per nanosecond
+
+	e.tokens = math.Min(capacity, e.tokens+float64(now.Sub(e.last))*rate)
+	e.last = now
+
+	result := Result{Limit: limit.burst()}
+	if e.tokens >= 1 {
+		e.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - e.tokens) / rate))
+	}
+
+	result.Remaining = int(e.tokens)
+	result.Reset = time.Duration(math.Ceil((capacity - e.tokens) / rate))
+
+	// Once full again, the entry is the same as a new one.
+	e.expires = now.Add(result.Reset)
+	return result
+}
+
+// slidingWindow estimates the number of requests in the last period from the counts
+// of the current and previous fixed windows, weighting the previous window by its
+// overlap with the sliding window.
+func (e *entry) slidingWindow(now time.Time, limit Limit) Result {
+	period := limit.Period
+
+	if elapsed := now.Sub(e.start); elapsed >= period {
+		windows := elapsed / period
+		if windows == 1 {
+			e.prev = e.curr
+		} else {
+			e.prev = 0
+		}
+		e.curr = 0
+		e.start = e.start.Add(windows * period)
+	}
+
+	elapsed := now.Sub(e.start)
+	weight := 1 - float64(elapsed)/float64(period)
+	estimate := float64(e.prev)*weight + float64(e.curr)
+
+	result := Result{Limit: limit.Requests, Reset: period - elapsed}
+	if estimate+1 <= float64(limit.Requests) {
+		e.curr++
+		estimate++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = e.retryAfter(elapsed, limit)
+	}
+
+	result.Remaining = max(limit.Requests-int(math.Ceil(estimate)), 0)
+	e.expires = e.start.Add(2 * period)
+	return result
+}
+
+// retryAfter returns the time until the estimate allows one more request.
+func (e *entry) retryAfter(elapsed time.Duration, limit Limit) time.Duration {
+	period := float64(limit.Period)
+	allowed := float64(limit.Requests - 1)
+
+	// The previous window decays during the current one.
+	if float64(e.curr) <= allowed && e.prev > 0 {
+		t := period*(1-(allowed-float64(e.curr))/float64(e.prev)) - float64(elapsed)
+		return time.Duration(math.Ceil(math.Max(t, 1)))
+	}
+
+	// Wait for the current window to become the previous one and decay enough.
+	t := period - float64(elapsed)
+	if e.curr > 0 {
+		t += period * math.Max(1-allowed/float64(e.curr), 0)
+	}
+	return time.Duration(math.Ceil(math.Max(t, 1)))
+}
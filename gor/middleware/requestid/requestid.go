@@ -0,0 +1,274 @@
+/*
+Package requestid assigns an ID to each request and propagates the W3C trace context
+(https://www.w3.org/TR/trace-context/) to correlate logs across services.
+
+	r.Use(logger.New(nil), requestid.New())
+
+The logger middleware includes the request ID, trace ID and span ID automatically.
+Use Transport to propagate them to outgoing requests:
+
+	client := &http.Client{Transport: requestid.Transport(nil)}
+
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		out, _ := http.NewRequestWithContext(req.Context(), http.MethodGet, "http://users/api", nil)
+		res, err := client.Do(out)
+		...
+	})
+*/
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+type contextType string
+
+const (
+	requestIDKey = contextType("request_id")
+	traceKey     = contextType("trace_context")
+)
+
+// Header names.
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+)
+
+// maxRequestIDLength is the maximum length of an incoming request ID.
+const maxRequestIDLength = 128
+
+// TraceContext is the W3C trace context of a request.
+type TraceContext struct {
+	TraceID      string // 32 lowercase hex characters.
+	SpanID       string // ID of the span of this request, 16 lowercase hex characters.
+	ParentSpanID string // Span ID of the caller, empty if the trace started here.
+	Flags        byte   // Trace flags. The lowest bit is the sampled flag.
+	State        string // Value of the tracestate header, propagated as is.
+}
+
+// Sampled reports whether the caller records the trace.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&0x01 != 0
+}
+
+// TraceParent returns the traceparent header value for calls made by this request.
+func (tc TraceContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// Config is the configuration of the request ID middleware.
+type Config struct {
+	// Header is the request ID header. Default is "X-Request-ID".
+	Header string
+
+	// Generator returns new request IDs. Default is a random UUID(version 4).
+	Generator func() string
+
+	// IgnoreIncoming always generates a new request ID and trace, e.g for
+	// services exposed to untrusted clients.
+	IgnoreIncoming bool
+}
+
+// New creates a middleware that accepts the request ID and trace context of the
+// request or generates them. Both are stored in the request context(and the locals
+// of gor.CTX as "request_id" and "trace_context") and echoed on the response.
+//
+// A new span ID is generated for every request; the incoming span ID becomes
+// the parent span ID.
+func New(config ...Config) gor.Middleware {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Header == "" {
+		cfg.Header = HeaderRequestID
+	}
+
+	if cfg.Generator == nil {
+		cfg.Generator = NewID
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var id string
+			var tc TraceContext
+			var ok bool
+
+			if !cfg.IgnoreIncoming {
+				id = req.Header.Get(cfg.Header)
+				tc, ok = ParseTraceParent(req.Header.Get(HeaderTraceParent))
+			}
+
+			if !validRequestID(id) {
+				id = cfg.Generator()
+			}
+
+			if ok {
+				tc.ParentSpanID = tc.SpanID
+				tc.State = req.Header.Get(HeaderTraceState)
+			} else {
+				tc = TraceContext{TraceID: randomHex(16)}
+			}
+			tc.SpanID = randomHex(8)
+
+			gor.SetContextValue(req, requestIDKey, id)
+			gor.SetContextValue(req, traceKey, tc)
+
+			w.Header().Set(cfg.Header, id)
+			w.Header().Set(HeaderTraceParent, tc.TraceParent())
+			if tc.State != "" {
+				w.Header().Set(HeaderTraceState, tc.State)
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// Get returns the request ID, or "" if the middleware is not used.
+func Get(req *http.Request) string {
+	id, _ := req.Context().Value(requestIDKey).(string)
+	return id
+}
+
+// Trace returns the trace context of the request.
+func Trace(req *http.Request) (TraceContext, bool) {
+	tc, ok := req.Context().Value(traceKey).(TraceContext)
+	return tc, ok
+}
+
+// ParseTraceParent parses a traceparent header value.
+// It reports false if the value is invalid, in which case a new trace should be started.
+func ParseTraceParent(value string) (TraceContext, bool) {
+	value = strings.TrimSpace(value)
+
+	// version-traceid-parentid-flags, longer values are allowed for future versions.
+	if len(value) < 55 || (len(value) > 55 && value[55] != '-') {
+		return TraceContext{}, false
+	}
+
+	parts := strings.SplitN(value[:55], "-", 4)
+	if len(parts) != 4 {
+		return TraceContext{}, false
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(value) != 55) {
+		return TraceContext{}, false
+	}
+
+	if !isHex(traceID, 32) || isZero(traceID) || !isHex(spanID, 16) || isZero(spanID) || !isHex(flags, 2) {
+		return TraceContext{}, false
+	}
+
+	b, _ := hex.DecodeString(flags)
+	return TraceContext{TraceID: traceID, SpanID: spanID, Flags: b[0]}, true
+}
+
+// isHex reports whether s has n lowercase hex characters.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// validRequestID accepts printable ASCII IDs of a reasonable length,
+// so that they can be logged and echoed safely.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// NewID returns a random UUID(version 4).
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // Variant RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// randomHex returns n random bytes hex encoded. The result is never all zeros.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+
+		s := hex.EncodeToString(b)
+		if !isZero(s) {
+			return s
+		}
+	}
+}
+
+// Transport returns a RoundTripper propagating the request ID and trace context
+// found in the context of outgoing requests. base defaults to http.DefaultTransport.
+//
+// The outgoing traceparent has the span ID of the incoming request as parent.
+// Headers already set on the outgoing request are kept.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id, _ := req.Context().Value(requestIDKey).(string)
+	tc, ok := req.Context().Value(traceKey).(TraceContext)
+	if id == "" && !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the request.
+	req = req.Clone(req.Context())
+
+	if id != "" && req.Header.Get(HeaderRequestID) == "" {
+		req.Header.Set(HeaderRequestID, id)
+	}
+
+	if ok && req.Header.Get(HeaderTraceParent) == "" {
+		req.Header.Set(HeaderTraceParent, tc.TraceParent())
+		if tc.State != "" {
+			req.Header.Set(HeaderTraceState, tc.State)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,173 @@
+package requestid_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/logger"
+	"github.com/abiiranathan/gor/gor/middleware/requestid"
+)
+
+const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceParent(t *testing.T) {
+	tc, ok := requestid.ParseTraceParent(traceParent)
+	if !ok {
+		t.Fatal("expected a valid traceparent")
+	}
+
+	if tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.SpanID != "00f067aa0ba902b7" || !tc.Sampled() {
+		t.Errorf("unexpected trace context %+v", tc)
+	}
+
+	if tc.TraceParent() != traceParent {
+		t.Errorf("expected %q, got %q", traceParent, tc.TraceParent())
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		traceParent + "-extra",
+	}
+
+	for _, value := range invalid {
+		if _, ok := requestid.ParseTraceParent(value); ok {
+			t.Errorf("expected %q to be invalid", value)
+		}
+	}
+
+	// Future versions may append fields.
+	if _, ok := requestid.ParseTraceParent("01" + traceParent[2:] + "-extra"); !ok {
+		t.Error("expected a future version with extra fields to be valid")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var gotID string
+	var gotTrace requestid.TraceContext
+
+	r := gor.NewRouter()
+	r.Use(requestid.New())
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gotID = requestid.Get(req)
+		gotTrace, _ = requestid.Trace(req)
+	})
+
+	// Incoming values are kept.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.HeaderRequestID, "abc-123")
+	req.Header.Set(requestid.HeaderTraceParent, traceParent)
+	req.Header.Set(requestid.HeaderTraceState, "vendor=value")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if gotID != "abc-123" || w.Header().Get(requestid.HeaderRequestID) != "abc-123" {
+		t.Errorf("expected the incoming request ID, got %q", gotID)
+	}
+
+	if gotTrace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || gotTrace.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("expected the incoming trace, got %+v", gotTrace)
+	}
+
+	if gotTrace.SpanID == gotTrace.ParentSpanID || gotTrace.State != "vendor=value" {
+		t.Errorf("expected a new span with the trace state, got %+v", gotTrace)
+	}
+
+	if w.Header().Get(requestid.HeaderTraceParent) != gotTrace.TraceParent() {
+		t.Errorf("expected the traceparent on the response, got %q", w.Header().Get(requestid.HeaderTraceParent))
+	}
+
+	// Missing or invalid values are generated.
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.HeaderRequestID, "bad id\n")
+	req.Header.Set(requestid.HeaderTraceParent, "garbage")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(gotID) {
+		t.Errorf("expected a generated UUID, got %q", gotID)
+	}
+
+	if _, ok := requestid.ParseTraceParent(gotTrace.TraceParent()); !ok || gotTrace.ParentSpanID != "" {
+		t.Errorf("expected a new trace, got %+v", gotTrace)
+	}
+}
+
+func TestLoggerIntegration(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := gor.NewRouter()
+	r.Use(logger.New(&logger.Config{Output: &buf}), requestid.New())
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.HeaderRequestID, "abc-123")
+	req.Header.Set(requestid.HeaderTraceParent, traceParent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	log := buf.String()
+	for _, want := range []string{"request_id=abc-123", "trace_id=4bf92f3577b34da6a3ce929d0e0e4736", "span_id="} {
+		if !strings.Contains(log, want) {
+			t.Errorf("expected %q in the log, got %q", want, log)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var headers http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		headers = req.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: requestid.Transport(nil)}
+
+	var trace requestid.TraceContext
+	r := gor.NewRouter()
+	r.Use(requestid.New())
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		trace, _ = requestid.Trace(req)
+
+		out, err := http.NewRequestWithContext(req.Context(), http.MethodGet, upstream.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Do(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.HeaderRequestID, "abc-123")
+	req.Header.Set(requestid.HeaderTraceParent, traceParent)
+	req.Header.Set(requestid.HeaderTraceState, "vendor=value")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if headers.Get(requestid.HeaderRequestID) != "abc-123" {
+		t.Errorf("expected the request ID to be propagated, got %q", headers.Get(requestid.HeaderRequestID))
+	}
+
+	want := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + trace.SpanID + "-01"
+	if headers.Get(requestid.HeaderTraceParent) != want {
+		t.Errorf("expected traceparent %q, got %q", want, headers.Get(requestid.HeaderTraceParent))
+	}
+
+	if headers.Get(requestid.HeaderTraceState) != "vendor=value" {
+		t.Errorf("expected the trace state to be propagated, got %q", headers.Get(requestid.HeaderTraceState))
+	}
+}
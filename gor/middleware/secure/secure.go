@@ -0,0 +1,198 @@
+/*
+Package secure sets security headers on responses, including a Content-Security-Policy
+with a nonce generated for each request.
+
+	r := gor.NewRouter(gor.PassContextToViews(true))
+	r.Use(secure.New())
+
+Inline scripts and styles must carry the nonce, available in templates as
+{{ .csp_nonce }} when PassContextToViews is enabled, or with secure.Nonce(req):
+
+	<script nonce="{{ .csp_nonce }}">...</script>
+
+Nonces do not apply to inline event handlers(onclick=...). The built-in components
+have none: the close buttons of alerts are handled by the "gor_scripts" component,
+included once in the layout with the nonce:
+
+	{{ template "gor_scripts" Props "nonce" .csp_nonce }}
+
+A config given to New replaces DefaultConfig, empty values are not sent.
+Start from DefaultConfig to change some headers only:
+
+	cfg := secure.DefaultConfig
+	cfg.FrameOptions = "DENY"
+	cfg.CSP = cfg.CSP.Clone()
+	cfg.CSP["img-src"] = append(cfg.CSP["img-src"], "https://images.example.com")
+	r.Use(secure.New(cfg))
+*/
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+type contextType string
+
+// nonceKey is the key of the nonce in the request context and CTX locals.
+const nonceKey = contextType("csp_nonce")
+
+// NonceSource is replaced by 'nonce-<value>' in the CSP sources.
+const NonceSource = "'nonce'"
+
+// CSP maps Content-Security-Policy directives to their sources.
+// Directives without sources(e.g "upgrade-insecure-requests") have an empty slice.
+type CSP map[string][]string
+
+// Clone returns a copy of the policy that can be modified without changing c.
+func (c CSP) Clone() CSP {
+	clone := make(CSP, len(c))
+	for directive, sources := range c {
+		clone[directive] = append([]string{}, sources...)
+	}
+	return clone
+}
+
+// String formats the policy. Directives are sorted, with default-src first.
+func (c CSP) String() string {
+	directives := make([]string, 0, len(c))
+	for directive := range c {
+		directives = append(directives, directive)
+	}
+
+	sort.Slice(directives, func(i, j int) bool {
+		if directives[i] == "default-src" || directives[j] == "default-src" {
+			return directives[i] == "default-src"
+		}
+		return directives[i] < directives[j]
+	})
+
+	parts := make([]string, len(directives))
+	for i, directive := range directives {
+		parts[i] = strings.TrimSpace(directive + " " + strings.Join(c[directive], " "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Config is the configuration of the security headers. Empty values are not sent.
+type Config struct {
+	// Strict-Transport-Security value. Only sent on HTTPS requests(including requests
+	// with "X-Forwarded-Proto: https" from a proxy).
+	HSTS string
+
+	ContentTypeOptions        string // X-Content-Type-Options
+	FrameOptions              string // X-Frame-Options
+	ReferrerPolicy            string // Referrer-Policy
+	PermissionsPolicy         string // Permissions-Policy
+	CrossOriginOpenerPolicy   string // Cross-Origin-Opener-Policy
+	CrossOriginEmbedderPolicy string // Cross-Origin-Embedder-Policy
+	CrossOriginResourcePolicy string // Cross-Origin-Resource-Policy
+
+	// CSP is the Content-Security-Policy. Sources equal to NonceSource are replaced
+	// by the nonce of the request.
+	CSP CSP
+
+	// CSPReportOnly sends the policy with Content-Security-Policy-Report-Only,
+	// to test a policy without enforcing it.
+	CSPReportOnly bool
+}
+
+// DefaultConfig is the configuration used by New when no config is given.
+var DefaultConfig = Config{
+	HSTS:                      "max-age=31536000; includeSubDomains",
+	ContentTypeOptions:        "nosniff",
+	FrameOptions:              "SAMEORIGIN",
+	ReferrerPolicy:            "strict-origin-when-cross-origin",
+	PermissionsPolicy:         "camera=(), microphone=(), geolocation=()",
+	CrossOriginOpenerPolicy:   "same-origin",
+	CrossOriginResourcePolicy: "same-origin",
+	CSP: CSP{
+		"default-src":     {"'self'"},
+		"script-src":      {"'self'", NonceSource},
+		"style-src":       {"'self'", NonceSource},
+		"img-src":         {"'self'", "data:"},
+		"object-src":      {"'none'"},
+		"base-uri":        {"'self'"},
+		"form-action":     {"'self'"},
+		"frame-ancestors": {"'self'"},
+	},
+}
+
+// New creates the security headers middleware. The default config is DefaultConfig.
+// A given config is used as is, it is not merged with DefaultConfig.
+func New(config ...Config) gor.Middleware {
+	cfg := DefaultConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	policy := cfg.CSP.String()
+	useNonce := strings.Contains(policy, NonceSource)
+
+	cspHeader := "Content-Security-Policy"
+	if cfg.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+
+	static := map[string]string{
+		"X-Content-Type-Options":       cfg.ContentTypeOptions,
+		"X-Frame-Options":              cfg.FrameOptions,
+		"Referrer-Policy":              cfg.ReferrerPolicy,
+		"Permissions-Policy":           cfg.PermissionsPolicy,
+		"Cross-Origin-Opener-Policy":   cfg.CrossOriginOpenerPolicy,
+		"Cross-Origin-Embedder-Policy": cfg.CrossOriginEmbedderPolicy,
+		"Cross-Origin-Resource-Policy": cfg.CrossOriginResourcePolicy,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			header := w.Header()
+			for name, value := range static {
+				if value != "" {
+					header.Set(name, value)
+				}
+			}
+
+			if cfg.HSTS != "" && isHTTPS(req) {
+				header.Set("Strict-Transport-Security", cfg.HSTS)
+			}
+
+			if policy != "" {
+				value := policy
+				if useNonce {
+					nonce := newNonce()
+					gor.SetContextValue(req, nonceKey, nonce)
+					value = strings.ReplaceAll(policy, NonceSource, "'nonce-"+nonce+"'")
+				}
+				header.Set(cspHeader, value)
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// Nonce returns the CSP nonce of the request, or "" if the policy has no nonce.
+func Nonce(req *http.Request) string {
+	nonce, _ := req.Context().Value(nonceKey).(string)
+	return nonce
+}
+
+func isHTTPS(req *http.Request) bool {
+	return req.TLS != nil || strings.EqualFold(req.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// newNonce returns 128 random bits, base64url encoded(allowed in CSP nonces),
+// so that no character is escaped in HTML attributes.
+func newNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
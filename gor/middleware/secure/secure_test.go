@@ -0,0 +1,173 @@
+package secure_test
+
+import (
+	"crypto/tls"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/secure"
+)
+
+func TestCSPString(t *testing.T) {
+	csp := secure.CSP{
+		"script-src":                {"'self'", "cdn.example.com"},
+		"upgrade-insecure-requests": {},
+		"default-src":               {"'none'"},
+	}
+
+	want := "default-src 'none'; script-src 'self' cdn.example.com; upgrade-insecure-requests"
+	if csp.String() != want {
+		t.Errorf("expected %q, got %q", want, csp.String())
+	}
+}
+
+func TestSecureHeaders(t *testing.T) {
+	var nonce string
+
+	r := gor.NewRouter()
+	r.Use(secure.New())
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		nonce = secure.Nonce(req)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	headers := map[string]string{
+		"X-Content-Type-Options":       "nosniff",
+		"X-Frame-Options":              "SAMEORIGIN",
+		"Referrer-Policy":              "strict-origin-when-cross-origin",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Resource-Policy": "same-origin",
+		"Cross-Origin-Embedder-Policy": "",
+		"Strict-Transport-Security":    "", // Not HTTPS.
+	}
+
+	for name, want := range headers {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+
+	if nonce == "" {
+		t.Fatal("expected a nonce")
+	}
+
+	csp := w.Header().Get("Content-Security-Policy")
+	if !strings.HasPrefix(csp, "default-src 'self'") || !strings.Contains(csp, "script-src 'self' 'nonce-"+nonce+"'") {
+		t.Errorf("unexpected policy %q", csp)
+	}
+
+	// Nonces are unique per request.
+	first := nonce
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if nonce == first {
+		t.Error("expected a new nonce for each request")
+	}
+
+	if w.Header().Get("Strict-Transport-Security") == "" {
+		t.Error("expected HSTS over HTTPS")
+	}
+}
+
+func TestSecureReportOnly(t *testing.T) {
+	r := gor.NewRouter()
+	r.Use(secure.New(secure.Config{
+		CSP:           secure.CSP{"default-src": {"'self'"}},
+		CSPReportOnly: true,
+	}))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		if secure.Nonce(req) != "" {
+			t.Error("expected no nonce without NonceSource")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Header().Get("Content-Security-Policy") != "" {
+		t.Error("expected the policy not to be enforced")
+	}
+
+	if w.Header().Get("Content-Security-Policy-Report-Only") != "default-src 'self'" {
+		t.Errorf("unexpected policy %q", w.Header().Get("Content-Security-Policy-Report-Only"))
+	}
+
+	if w.Header().Get("X-Frame-Options") != "" {
+		t.Error("expected headers missing in the config not to be sent")
+	}
+}
+
+func TestNonceInTemplates(t *testing.T) {
+	tmpl := template.Must(template.New("page.html").Parse(`<script nonce="{{ .csp_nonce }}">init()</script>`))
+
+	r := gor.NewRouter(gor.WithTemplates(tmpl), gor.PassContextToViews(true))
+	r.Use(secure.New())
+
+	var nonce string
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		nonce = secure.Nonce(req)
+		gor.Render(w, req, "page.html", gor.Map{})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := `<script nonce="` + nonce + `">init()</script>`
+	if w.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, w.Body.String())
+	}
+}
+
+func TestComponentsWithoutInlineHandlers(t *testing.T) {
+	tmpl, err := gor.ParseTemplatesRecursive("testdata", template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gor.NewRouter(gor.WithTemplates(tmpl), gor.PassContextToViews(true))
+	r.Use(secure.New())
+
+	var nonce string
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		nonce = secure.Nonce(req)
+		gor.Render(w, req, "page.html", gor.Map{})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	out := w.Body.String()
+	if strings.Contains(out, "onclick") {
+		t.Errorf("expected no inline event handlers, the default policy blocks them:\n%s", out)
+	}
+
+	for _, want := range []string{`data-gor-dismiss`, `formmethod="dialog"`, `<script nonce="` + nonce + `">`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+}
+
+func TestCSPClone(t *testing.T) {
+	cfg := secure.DefaultConfig
+	cfg.CSP = cfg.CSP.Clone()
+	cfg.CSP["img-src"] = append(cfg.CSP["img-src"], "https://images.example.com")
+
+	if strings.Contains(secure.DefaultConfig.CSP.String(), "images.example.com") {
+		t.Error("expected the default policy not to be modified")
+	}
+
+	if !strings.Contains(cfg.CSP.String(), "img-src 'self' data: https://images.example.com") {
+		t.Errorf("unexpected policy %q", cfg.CSP.String())
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Package timeout limits the time handlers take to respond.
+
+The request context gets a deadline, so that database queries and outgoing
+requests made with it are cancelled. If the handler has not responded at the
+deadline, a 503 Service Unavailable(configurable) is sent instead and later writes
+of the handler fail with http.ErrHandlerTimeout.
+
+Apply different timeouts per group or route:
+
+	api := r.Group("/api", timeout.New(2*time.Second))
+	reports := r.Group("/reports", timeout.New(60*time.Second))
+
+The write deadline of the connection is extended to cover the timeout, so routes
+can take longer than the WriteTimeout of the server(10s with gor.NewServer).
+
+Server-sent events and websocket upgrades are not limited. Other streaming
+routes can be excluded with Config.SkipIf, e.g by route pattern:
+
+	timeout.New(2*time.Second, timeout.Config{
+		SkipIf: func(req *http.Request) bool {
+			return gor.RoutePattern(req) == "GET /api/export"
+		},
+	})
+*/
+package timeout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// writeDeadlineGrace is added to the timeout when extending the write deadline,
+// leaving time to send the timeout response.
+const writeDeadlineGrace = 5 * time.Second
+
+// Config is the configuration of the timeout middleware.
+type Config struct {
+	// Status is the status code of the timeout response. Default is 503 Service Unavailable.
+	// Use http.StatusGatewayTimeout(504) for handlers waiting on upstream services.
+	Status int
+
+	// Body is the body of the timeout response. Default is the status text.
+	Body string
+
+	// Handler writes the timeout response instead of Status and Body.
+	// The request context is already done when it is called.
+	Handler http.HandlerFunc
+
+	// SkipIf is a function that can be used to skip the timeout based on the request.
+	SkipIf func(req *http.Request) bool
+}
+
+// New creates a middleware that cancels the request context after d and sends a
+// timeout response if the handler has not responded yet.
+//
+// The response of the handler is buffered until it returns. If the handler flushes
+// the response, it is sent immediately and cut at the deadline.
+// A panic in the handler is propagated to the middlewares above, e.g recovery.
+func New(d time.Duration, config ...Config) gor.Middleware {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Status == 0 {
+		cfg.Status = http.StatusServiceUnavailable
+	}
+
+	if cfg.Body == "" {
+		cfg.Body = http.StatusText(cfg.Status)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if isStreaming(req) || (cfg.SkipIf != nil && cfg.SkipIf(req)) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(req.Context(), d)
+			defer cancel()
+
+			// Not supported by all writers(e.g httptest.ResponseRecorder).
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(d + writeDeadlineGrace))
+
+			tw := &timeoutWriter{w: w, header: make(http.Header), status: http.StatusOK}
+
+			// The handler may outlive the request, it must not share the pooled CTX.
+			r := gor.DetachContext(req.WithContext(ctx))
+
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.commit()
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				committed := tw.committed
+				tw.mu.Unlock()
+
+				if committed || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					// The response was partly sent or the client went away.
+					return
+				}
+
+				if cfg.Handler != nil {
+					cfg.Handler(w, r)
+					return
+				}
+				http.Error(w, cfg.Body, cfg.Status)
+			}
+		})
+	}
+}
+
+// isStreaming reports whether req opens a server-sent event stream or a websocket.
+func isStreaming(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), gor.ContentTypeEventStream) ||
+		strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
+}
+
+// timeoutWriter buffers the response of the handler. All methods are safe to call
+// from the handler goroutine while the middleware writes the timeout response.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	buf    bytes.Buffer
+
+	mu          sync.Mutex
+	status      int
+	wroteHeader bool
+	committed   bool // The response was sent to w.
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+
+	tw.status = status
+	tw.wroteHeader = true
+
+	if tw.committed {
+		tw.w.WriteHeader(status)
+	}
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	tw.wroteHeader = true
+	if tw.committed {
+		return tw.w.Write(p)
+	}
+	return tw.buf.Write(p)
+}
+
+// Flush sends the buffered response and the following writes directly.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+
+	tw.commit()
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// commit copies the headers and buffered body to the underlying writer.
+// tw.mu must be held.
+func (tw *timeoutWriter) commit() {
+	if tw.committed {
+		return
+	}
+	tw.committed = true
+
+	dst := tw.w.Header()
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+
+	tw.w.WriteHeader(tw.status)
+	if tw.buf.Len() > 0 {
+		tw.w.Write(tw.buf.Bytes())
+		tw.buf.Reset()
+	}
+}
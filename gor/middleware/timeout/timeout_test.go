@@ -0,0 +1,188 @@
+package timeout_test
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+	"github.com/abiiranathan/gor/gor/middleware/recovery"
+	"github.com/abiiranathan/gor/gor/middleware/timeout"
+)
+
+func TestTimeout(t *testing.T) {
+	writeErr := make(chan error, 1)
+
+	r := gor.NewRouter()
+	r.Get("/fast", func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := req.Context().Deadline(); !ok {
+			t.Error("expected a context deadline")
+		}
+		w.Header().Set("X-Handler", "fast")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	}, timeout.New(time.Second))
+
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+
+		w.Header().Set("X-Handler", "slow")
+		_, err := w.Write([]byte("too late"))
+		writeErr <- err
+	}, timeout.New(20*time.Millisecond, timeout.Config{Status: http.StatusGatewayTimeout}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+
+	if w.Code != http.StatusCreated || w.Body.String() != "done" || w.Header().Get("X-Handler") != "fast" {
+		t.Errorf("expected the handler response, got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+
+	if w.Header().Get("X-Handler") != "" {
+		t.Error("expected the headers of the abandoned handler to be discarded")
+	}
+
+	if err := <-writeErr; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("expected http.ErrHandlerTimeout, got %v", err)
+	}
+
+	if w.Body.String() != http.StatusText(http.StatusGatewayTimeout)+"\n" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestTimeoutHandler(t *testing.T) {
+	r := gor.NewRouter()
+	r.Use(timeout.New(10*time.Millisecond, timeout.Config{
+		Handler: func(w http.ResponseWriter, req *http.Request) {
+			if !errors.Is(req.Context().Err(), context.DeadlineExceeded) {
+				t.Errorf("expected the context to be done, got %v", req.Context().Err())
+			}
+			gor.SendJSONError(w, gor.Map{"error": "timeout"}, http.StatusServiceUnavailable)
+		},
+	}))
+
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Content-Type") != gor.ContentTypeJSON {
+		t.Errorf("expected the custom timeout response, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestTimeoutSkip(t *testing.T) {
+	r := gor.NewRouter()
+	r.Use(timeout.New(10*time.Millisecond, timeout.Config{
+		SkipIf: func(req *http.Request) bool {
+			return gor.RoutePattern(req) == "GET /export"
+		},
+	}))
+
+	slow := func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := req.Context().Deadline(); ok {
+			t.Error("expected no deadline")
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}
+
+	r.Get("/export", slow)
+	r.Get("/events", slow)
+
+	for _, tt := range []struct{ path, accept string }{
+		{"/export", ""},
+		{"/events", gor.ContentTypeEventStream},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK || w.Body.String() != "ok" {
+			t.Errorf("%s: expected the handler response, got %d %q", tt.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestTimeoutFlush(t *testing.T) {
+	r := gor.NewRouter()
+	r.Use(timeout.New(20 * time.Millisecond))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte(" more"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("expected the flushed response to be cut at the deadline, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	r := gor.NewRouter()
+	r.Use(recovery.New(false), timeout.New(time.Second))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected the panic to be recovered above the timeout, got %d", w.Code)
+	}
+}
+
+func TestTimeoutLocalsDoNotLeak(t *testing.T) {
+	tmpl := template.Must(template.New("page.html").Parse(`user={{ .user }}`))
+	r := gor.NewRouter(gor.WithTemplates(tmpl), gor.PassContextToViews(true))
+
+	timedOut := make(chan struct{})
+	leaked := make(chan struct{})
+
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+		<-timedOut
+
+		// Runs after Router.ServeHTTP returned and the CTX went back to the pool.
+		gor.SetContextValue(req, "user", "alice")
+		close(leaked)
+	}, timeout.New(10*time.Millisecond))
+
+	r.Get("/page", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "page.html", gor.Map{})
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	close(timedOut)
+	<-leaked
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	if w.Body.String() != "user=" {
+		t.Errorf("expected no locals from the abandoned handler, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,205 @@
+package gor
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Option is an option of the select and radio components.
+type Option struct {
+	Value    string
+	Label    string
+	Disabled bool
+	Selected bool // Set by SelectOptions.
+}
+
+// OptionGroup is a labelled group of options(an <optgroup> in a select).
+// Options that are not grouped are returned in a group without label.
+type OptionGroup struct {
+	Label   string
+	Options []Option
+}
+
+// SelectOptions normalizes the options of the select and radio components and
+// marks the options matching selected.
+//
+// options can be:
+//
+//	[]string, []int...          value and label are the same.
+//	[]Option or []struct        structs with Value and Label fields(and an optional Disabled bool).
+//	map[string]string           value to label, sorted by label.
+//	[]OptionGroup               groups of options.
+//	map[string][]T              group label to options of any of the above, sorted by group.
+//
+// selected is a single value or a slice of values for multiple selection.
+// Values are compared by their string representation.
+//
+// It is available in templates as "SelectOptions".
+func SelectOptions(options any, selected any) ([]OptionGroup, error) {
+	values := selectedValues(selected)
+
+	groups, err := optionGroups(reflect.ValueOf(options))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range groups {
+		for j := range groups[i].Options {
+			_, ok := values[groups[i].Options[j].Value]
+			groups[i].Options[j].Selected = ok
+		}
+	}
+	return groups, nil
+}
+
+// selectedValues returns the set of selected values.
+func selectedValues(selected any) map[string]struct{} {
+	values := make(map[string]struct{})
+
+	rv := reflect.ValueOf(selected)
+	switch rv.Kind() {
+	case reflect.Invalid:
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			values[fmt.Sprint(rv.Index(i).Interface())] = struct{}{}
+		}
+	default:
+		values[fmt.Sprint(selected)] = struct{}{}
+	}
+	return values
+}
+
+func optionGroups(rv reflect.Value) ([]OptionGroup, error) {
+	for rv.Kind() == reflect.Interface || rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Map:
+		return mapOptionGroups(rv)
+	case reflect.Slice, reflect.Array:
+		var groups []OptionGroup
+		ungrouped := OptionGroup{}
+
+		for i := 0; i < rv.Len(); i++ {
+			elem := rv.Index(i)
+			if group, ok := elem.Interface().(OptionGroup); ok {
+				// Keep ungrouped options before the group, in order.
+				if len(ungrouped.Options) > 0 {
+					groups = append(groups, ungrouped)
+					ungrouped = OptionGroup{}
+				}
+				groups = append(groups, group)
+				continue
+			}
+
+			option, err := toOption(elem)
+			if err != nil {
+				return nil, err
+			}
+			ungrouped.Options = append(ungrouped.Options, option)
+		}
+
+		if len(ungrouped.Options) > 0 {
+			groups = append(groups, ungrouped)
+		}
+		return groups, nil
+	default:
+		return nil, fmt.Errorf("options must be a slice or map, got %s", rv.Type())
+	}
+}
+
+// mapOptionGroups converts map[string]string(value to label) to a single group and
+// maps of slices(group label to options) to one group per key.
+func mapOptionGroups(rv reflect.Value) ([]OptionGroup, error) {
+	keys := rv.MapKeys()
+
+	elemKind := rv.Type().Elem().Kind()
+	if elemKind == reflect.Interface && len(keys) > 0 {
+		elemKind = rv.MapIndex(keys[0]).Elem().Kind()
+	}
+
+	if elemKind == reflect.Slice || elemKind == reflect.Array {
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		groups := make([]OptionGroup, 0, len(keys))
+		for _, key := range keys {
+			inner, err := optionGroups(rv.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+
+			group := OptionGroup{Label: fmt.Sprint(key.Interface())}
+			for _, g := range inner {
+				group.Options = append(group.Options, g.Options...)
+			}
+			groups = append(groups, group)
+		}
+		return groups, nil
+	}
+
+	options := make([]Option, 0, len(keys))
+	for _, key := range keys {
+		options = append(options, Option{
+			Value: fmt.Sprint(key.Interface()),
+			Label: fmt.Sprint(rv.MapIndex(key).Interface()),
+		})
+	}
+
+	sort.Slice(options, func(i, j int) bool {
+		if options[i].Label == options[j].Label {
+			return options[i].Value < options[j].Value
+		}
+		return options[i].Label < options[j].Label
+	})
+	return []OptionGroup{{Options: options}}, nil
+}
+
+// toOption converts a scalar or struct with Value and Label fields to an Option.
+func toOption(rv reflect.Value) (Option, error) {
+	for rv.Kind() == reflect.Interface || rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return Option{}, fmt.Errorf("nil option")
+		}
+		rv = rv.Elem()
+	}
+
+	if option, ok := rv.Interface().(Option); ok {
+		if option.Label == "" {
+			option.Label = option.Value
+		}
+		return option, nil
+	}
+
+	if rv.Kind() != reflect.Struct {
+		value := fmt.Sprint(rv.Interface())
+		return Option{Value: value, Label: value}, nil
+	}
+
+	value := rv.FieldByName("Value")
+	if !value.IsValid() {
+		return Option{}, fmt.Errorf("option %s has no Value field", rv.Type())
+	}
+
+	option := Option{Value: fmt.Sprint(value.Interface())}
+	if label := rv.FieldByName("Label"); label.IsValid() {
+		option.Label = fmt.Sprint(label.Interface())
+	}
+
+	if option.Label == "" {
+		option.Label = option.Value
+	}
+
+	if disabled := rv.FieldByName("Disabled"); disabled.IsValid() && disabled.Kind() == reflect.Bool {
+		option.Disabled = disabled.Bool()
+	}
+	return option, nil
+}
@@ -0,0 +1,146 @@
+package gor_test
+
+import (
+	"bytes"
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+func TestSelectOptions(t *testing.T) {
+	type size struct {
+		Value    int
+		Label    string
+		Disabled bool
+	}
+
+	tests := []struct {
+		name     string
+		options  any
+		selected any
+		expected []gor.OptionGroup
+	}{
+		{
+			name:     "strings",
+			options:  []string{"red", "green"},
+			selected: "green",
+			expected: []gor.OptionGroup{{Options: []gor.Option{
+				{Value: "red", Label: "red"},
+				{Value: "green", Label: "green", Selected: true},
+			}}},
+		},
+		{
+			name:     "structs",
+			options:  []size{{1, "Small", false}, {2, "Large", true}},
+			selected: 1,
+			expected: []gor.OptionGroup{{Options: []gor.Option{
+				{Value: "1", Label: "Small", Selected: true},
+				{Value: "2", Label: "Large", Disabled: true},
+			}}},
+		},
+		{
+			name:     "map sorted by label",
+			options:  map[string]string{"ug": "Uganda", "ke": "Kenya"},
+			selected: []string{"ug", "ke"},
+			expected: []gor.OptionGroup{{Options: []gor.Option{
+				{Value: "ke", Label: "Kenya", Selected: true},
+				{Value: "ug", Label: "Uganda", Selected: true},
+			}}},
+		},
+		{
+			name: "groups",
+			options: map[string][]gor.Option{
+				"Fruits":     {{Value: "apple"}, {Value: "mango", Label: "Mango"}},
+				"Vegetables": {{Value: "kale", Label: "Kale"}},
+			},
+			selected: []string{"mango", "kale"},
+			expected: []gor.OptionGroup{
+				{Label: "Fruits", Options: []gor.Option{
+					{Value: "apple", Label: "apple"},
+					{Value: "mango", Label: "Mango", Selected: true},
+				}},
+				{Label: "Vegetables", Options: []gor.Option{
+					{Value: "kale", Label: "Kale", Selected: true},
+				}},
+			},
+		},
+		{
+			name:    "mixed groups",
+			options: []any{"none", gor.OptionGroup{Label: "Numbers", Options: []gor.Option{{Value: "1", Label: "One"}}}},
+			expected: []gor.OptionGroup{
+				{Options: []gor.Option{{Value: "none", Label: "none"}}},
+				{Label: "Numbers", Options: []gor.Option{{Value: "1", Label: "One"}}},
+			},
+		},
+		{
+			name:     "nil",
+			options:  nil,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := gor.SelectOptions(tt.options, tt.selected)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(groups, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, groups)
+			}
+		})
+	}
+
+	if _, err := gor.SelectOptions(42, nil); err == nil {
+		t.Error("expected an error for invalid options")
+	}
+}
+
+func TestSelectAndRadioComponents(t *testing.T) {
+	templ, err := gor.ParseTemplatesRecursive("testdata/options", gor.ClasslessTheme.Funcs(template.FuncMap{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = templ.ExecuteTemplate(&buf, "form.html", gor.Map{
+		"Colors": []string{"red", "green"},
+		"Color":  "green",
+		"Tags": map[string][]string{
+			"Go":  {"generics", "iterators"},
+			"Web": {"htmx"},
+		},
+		"Selected": []string{"generics", "htmx"},
+		"Sizes":    []gor.Option{{Value: "s", Label: "Small"}, {Value: "l", Label: "Large", Disabled: true}},
+		"Size":     "s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`<select id="color" name="color">`,
+		`<option value="">Pick a color</option>`,
+		`<option value="red">red</option>`,
+		`<option value="green" selected>green</option>`,
+		`<select id="tags" name="tags" multiple>`,
+		`<optgroup label="Go">`,
+		`<option value="generics" selected>generics</option>`,
+		`<option value="iterators">iterators</option>`,
+		`<optgroup label="Web">`,
+		`<option value="htmx" selected>htmx</option>`,
+		`<label for="size_0_0">`,
+		`<input type="radio" id="size_0_0" name="size" value="s" checked>`,
+		`<span>Small</span>`,
+		`<input type="radio" id="size_0_1" name="size" value="l" disabled>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package gor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentTypeProblemJSON is the media type for RFC 9457 problem details.
+const ContentTypeProblemJSON string = "application/problem+json"
+
+// Problem is an RFC 9457 problem details document.
+// See https://www.rfc-editor.org/rfc/rfc9457.
+//
+// Extensions are serialized as top-level members alongside the standard ones.
+// A Problem is also an error, so it can be returned from helpers and later
+// passed to SendError or SendProblem.
+type Problem struct {
+	Type       string         // URI reference identifying the problem type. Default is "about:blank".
+	Title      string         // Short human-readable summary of the problem type.
+	Status     int            // HTTP status code for this occurrence.
+	Detail     string         // Human-readable explanation specific to this occurrence.
+	Instance   string         // URI reference identifying this occurrence(e.g the request path).
+	Extensions map[string]any // Additional members. Keys clashing with the standard members are ignored.
+}
+
+// FieldProblem describes a single invalid field in a problem document.
+// A list of these is stored under the "errors" extension member.
+type FieldProblem struct {
+	Field  string `json:"field"`          // Struct field name.
+	Kind   string `json:"kind,omitempty"` // FormErrorKind of the error.
+	Detail string `json:"detail"`         // Error message.
+}
+
+// ValidationErrors is a collection of FormErrors, one per invalid field.
+// It can be used to report all invalid fields at once instead of failing on the first.
+type ValidationErrors []FormError
+
+// Error implements the error interface.
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// NewProblem creates a new problem with the given status and detail.
+// The title defaults to the status text.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// With sets an extension member on the problem and returns it for chaining.
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Error implements the error interface.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// MarshalJSON flattens the extensions into the problem document.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+
+	if p.Type != "" {
+		m["type"] = p.Type
+	} else {
+		m["type"] = "about:blank"
+	}
+
+	if p.Title != "" {
+		m["title"] = p.Title
+	} else {
+		delete(m, "title")
+	}
+
+	if p.Status != 0 {
+		m["status"] = p.Status
+	} else {
+		delete(m, "status")
+	}
+
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	} else {
+		delete(m, "detail")
+	}
+
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	} else {
+		delete(m, "instance")
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes a problem document, collecting unknown members in Extensions.
+func (p *Problem) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	fields := map[string]any{
+		"type":     &p.Type,
+		"title":    &p.Title,
+		"status":   &p.Status,
+		"detail":   &p.Detail,
+		"instance": &p.Instance,
+	}
+
+	for k, raw := range m {
+		if dst, ok := fields[k]; ok {
+			if err := json.Unmarshal(raw, dst); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]any)
+		}
+		p.Extensions[k] = v
+	}
+	return nil
+}
+
+// ProblemFromError converts err into a problem document with the given status.
+//
+// A *Problem is returned as is(with the status filled in if missing).
+// FormError and ValidationErrors produce a problem with an "errors" extension
+// containing one FieldProblem per invalid field.
+// Any other error uses err.Error() as the detail.
+func ProblemFromError(err error, status int) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		cp := *p // do not mutate the caller's problem
+		p = &cp
+		if p.Status == 0 {
+			p.Status = status
+		}
+		if p.Title == "" {
+			p.Title = http.StatusText(p.Status)
+		}
+		return p
+	}
+
+	var verrs ValidationErrors
+	if errors.As(err, &verrs) {
+		p = NewProblem(status, "One or more fields are invalid.")
+		fields := make([]FieldProblem, 0, len(verrs))
+		for _, e := range verrs {
+			fields = append(fields, fieldProblem(e))
+		}
+		return p.With("errors", fields)
+	}
+
+	var ferr FormError
+	if errors.As(err, &ferr) {
+		fp := fieldProblem(ferr)
+		p = NewProblem(status, fp.Detail)
+		if fp.Field != "" {
+			p.With("errors", []FieldProblem{fp})
+		}
+		return p
+	}
+	return NewProblem(status, err.Error())
+}
+
+func fieldProblem(e FormError) FieldProblem {
+	// unwrap nested form errors returned by setField.
+	if inner, ok := e.Err.(FormError); ok {
+		e = inner
+	}
+
+	fp := FieldProblem{Field: e.Field, Kind: string(e.Kind)}
+	if e.Err != nil {
+		fp.Detail = e.Err.Error()
+	}
+	return fp
+}
+
+// SendProblem writes p as application/problem+json.
+// If p.Status is 0, 500 is assumed. p is not modified, so it can be shared.
+func SendProblem(w http.ResponseWriter, problem *Problem) error {
+	p := *problem
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProblemJSON)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(&p)
+}
+
+// AcceptsProblemJSON reports whether the client listed application/problem+json
+// in its Accept header, with a quality other than 0.
+func AcceptsProblemJSON(req *http.Request) bool {
+	for _, accept := range req.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, _ := strings.Cut(part, ";")
+			if !strings.EqualFold(strings.TrimSpace(mediaType), ContentTypeProblemJSON) {
+				continue
+			}
+
+			if !refused(params) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// refused reports whether the parameters of a media range have a "q" of 0.
+func refused(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		key, value, ok := strings.Cut(param, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			return err == nil && q == 0
+		}
+	}
+	return false
+}
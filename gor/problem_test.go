@@ -0,0 +1,172 @@
+package gor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProblemMarshalJSON(t *testing.T) {
+	p := NewProblem(http.StatusForbidden, "insufficient credit").
+		With("balance", 30).
+		With("title", "ignored")
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]any
+	json.Unmarshal(b, &m)
+
+	if m["type"] != "about:blank" {
+		t.Errorf("expected type about:blank, got %v", m["type"])
+	}
+
+	if m["title"] != "Forbidden" {
+		t.Errorf("expected title Forbidden, got %v", m["title"])
+	}
+
+	if m["status"] != float64(http.StatusForbidden) {
+		t.Errorf("expected status 403, got %v", m["status"])
+	}
+
+	if m["balance"] != float64(30) {
+		t.Errorf("expected balance extension 30, got %v", m["balance"])
+	}
+
+	if _, ok := m["instance"]; ok {
+		t.Errorf("expected empty instance to be omitted")
+	}
+
+	var decoded Problem
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Detail != "insufficient credit" || decoded.Extensions["balance"] != float64(30) {
+		t.Errorf("round trip failed: %+v", decoded)
+	}
+}
+
+func TestProblemFromError(t *testing.T) {
+	p := ProblemFromError(errors.New("boom"), http.StatusBadGateway)
+	if p.Status != http.StatusBadGateway || p.Detail != "boom" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+
+	original := &Problem{Title: "Out of stock"}
+	p = ProblemFromError(original, http.StatusConflict)
+	if p.Status != http.StatusConflict || p.Title != "Out of stock" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+
+	if original.Status != 0 {
+		t.Errorf("ProblemFromError must not modify the original problem")
+	}
+
+	verrs := ValidationErrors{
+		{Field: "Name", Kind: RequiredFieldMissing, Err: errors.New("field 'name' is required")},
+		{Field: "Age", Kind: ParseError, Err: errors.New("invalid syntax")},
+	}
+
+	p = ProblemFromError(verrs, http.StatusUnprocessableEntity)
+	fields, ok := p.Extensions["errors"].([]FieldProblem)
+	if !ok || len(fields) != 2 {
+		t.Fatalf("expected 2 field problems, got %v", p.Extensions["errors"])
+	}
+
+	if fields[1].Field != "Age" || fields[1].Kind != string(ParseError) {
+		t.Errorf("unexpected field problem %+v", fields[1])
+	}
+}
+
+func TestSendErrorProblemJSON(t *testing.T) {
+	type form struct {
+		Name string `form:"name,required"`
+	}
+
+	r := NewRouter()
+	r.Post("/users", func(w http.ResponseWriter, req *http.Request) {
+		var f form
+		if err := BodyParser(req, &f); err != nil {
+			SendError(w, req, err, http.StatusUnprocessableEntity)
+			return
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(url.Values{}.Encode()))
+	req.Header.Set("Content-Type", ContentTypeUrlEncoded)
+	req.Header.Set("Accept", "application/json, application/problem+json;q=0.9")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", w.Code)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeProblemJSON {
+		t.Errorf("expected Content-Type %s, got %s", ContentTypeProblemJSON, ct)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Instance != "/users" {
+		t.Errorf("expected instance /users, got %q", p.Instance)
+	}
+
+	fields, ok := p.Extensions["errors"].([]any)
+	if !ok || len(fields) != 1 {
+		t.Fatalf("expected 1 field error, got %v", p.Extensions["errors"])
+	}
+
+	if field := fields[0].(map[string]any)["field"]; field != "Name" {
+		t.Errorf("expected field Name, got %v", field)
+	}
+}
+
+func TestSendProblemDoesNotModify(t *testing.T) {
+	shared := &Problem{Detail: "out of stock"}
+
+	w := httptest.NewRecorder()
+	if err := SendProblem(w, shared); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"title":"Internal Server Error"`) {
+		t.Errorf("expected the default status and title, got %d %s", w.Code, w.Body.String())
+	}
+
+	if shared.Status != 0 || shared.Title != "" {
+		t.Errorf("expected the problem to be left unchanged, got %+v", shared)
+	}
+}
+
+func TestAcceptsProblemJSON(t *testing.T) {
+	tests := map[string]bool{
+		"":                                    false,
+		"application/json":                    false,
+		"application/problem+json":            true,
+		"application/problem+json;q=0.5":      true,
+		"Application/Problem+JSON ; q=1":      true,
+		"application/problem+json;q=0":        false,
+		"application/problem+json; q=0.000":   false,
+		"text/html, application/problem+json": true,
+	}
+
+	for accept, want := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+
+		if got := AcceptsProblemJSON(req); got != want {
+			t.Errorf("AcceptsProblemJSON(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
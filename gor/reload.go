@@ -0,0 +1,318 @@
+package gor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TemplateReloadInterval is the minimum time between two checks for modified
+// templates in development mode. The default is 500ms.
+var TemplateReloadInterval = 500 * time.Millisecond
+
+// TemplateDir parses all templates in rootDir recursively with ParseTemplatesRecursive
+// when the router is created and panics if parsing fails.
+//
+// In development mode(see DevMode) the templates are re-parsed from disk whenever a
+// file is added, removed or modified, and parse errors are shown in the browser
+// instead of panicking.
+//
+// Example:
+//
+//	r := gor.NewRouter(
+//		gor.TemplateDir("templates", template.FuncMap{}),
+//		gor.DevMode(os.Getenv("ENV") == "development"),
+//	)
+func TemplateDir(rootDir string, funcMap template.FuncMap, suffix ...string) RouterOption {
+	return func(r *Router) {
+		ext := ".html"
+		if len(suffix) > 0 {
+			ext = suffix[0]
+		}
+
+		if funcMap == nil {
+			funcMap = template.FuncMap{}
+		}
+
+		r.reloader = &templateReloader{
+			rootDir: filepath.Clean(rootDir),
+			ext:     ext,
+			funcMap: funcMap,
+		}
+	}
+}
+
+// DevMode enables or disables development mode.
+// In development mode, templates configured with TemplateDir are reloaded when they
+// change on disk and template errors are rendered as a page with the file, line
+// and source of the error. Production mode keeps the cached template set.
+// The default value is `false`.
+//
+// Example:
+//
+//	r := NewRouter(gor.DevMode(true))
+func DevMode(enabled bool) RouterOption {
+	return func(r *Router) {
+		r.devMode = enabled
+	}
+}
+
+// templateReloader re-parses a template directory when the modification times
+// of its files change.
+type templateReloader struct {
+	rootDir string
+	ext     string
+	funcMap template.FuncMap
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	modTimes  map[string]time.Time
+	err       error // Last parse error. Cleared on successful parse.
+}
+
+// snapshot returns the modification times of all template files.
+func (l *templateReloader) snapshot() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	err := filepath.WalkDir(l.rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !strings.HasSuffix(path, l.ext) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		modTimes[path] = info.ModTime()
+		return nil
+	})
+	return modTimes, err
+}
+
+// changed reports whether any file was added, removed or modified since the last snapshot.
+func (l *templateReloader) changed(modTimes map[string]time.Time) bool {
+	if len(modTimes) != len(l.modTimes) {
+		return true
+	}
+
+	for path, modTime := range modTimes {
+		if prev, ok := l.modTimes[path]; !ok || !prev.Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// load parses the templates and records the modification times of the files.
+func (l *templateReloader) load() (*template.Template, error) {
+	modTimes, err := l.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	l.modTimes = modTimes
+	l.lastCheck = time.Now()
+
+	t, err := ParseTemplatesRecursive(l.rootDir, l.funcMap, l.ext)
+	if err != nil {
+		l.err = newTemplateError(l.rootDir, err)
+		return nil, l.err
+	}
+	l.err = nil
+	return t, nil
+}
+
+// initTemplates parses the templates configured with TemplateDir.
+// It is called once after the router options are applied.
+func (r *Router) initTemplates() {
+	if r.reloader == nil {
+		return
+	}
+
+	r.reloader.mu.Lock()
+	defer r.reloader.mu.Unlock()
+
+	t, err := r.reloader.load()
+	if err != nil {
+		if !r.devMode {
+			panic(err)
+		}
+		log.Println(err)
+		return
+	}
+	r.template = t
+}
+
+// reloadTemplates re-parses the templates if they changed on disk.
+// It returns the parse error of the current templates, if any.
+// Outside development mode it does nothing.
+func (r *Router) reloadTemplates() error {
+	if !r.devMode || r.reloader == nil {
+		return nil
+	}
+
+	l := r.reloader
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Since(l.lastCheck) < TemplateReloadInterval {
+		return l.err
+	}
+	l.lastCheck = time.Now()
+
+	modTimes, err := l.snapshot()
+	if err != nil {
+		return err
+	}
+
+	if !l.changed(modTimes) {
+		return l.err
+	}
+
+	t, err := l.load()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	r.templateMu.Lock()
+	r.template = t
+	r.templateMu.Unlock()
+	return nil
+}
+
+// templates returns the current template set.
+func (r *Router) templates() *template.Template {
+	r.templateMu.RLock()
+	defer r.templateMu.RUnlock()
+	return r.template
+}
+
+// TemplateError is a template parse or execution error with the location of
+// the error in the source file.
+type TemplateError struct {
+	File    string // Path of the template file. Empty if unknown.
+	Line    int    // Line number of the error(1-based). 0 if unknown.
+	Snippet []SourceLine
+	Err     error
+}
+
+// SourceLine is a numbered line of template source.
+type SourceLine struct {
+	Number  int
+	Text    string
+	IsError bool // This is the line with the error.
+}
+
+func (e *TemplateError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// errorLocation matches "template: users/list.html:12:" in template errors.
+var errorLocation = regexp.MustCompile(`template: ([^:\s]+):(\d+):`)
+
+// snippetContext is the number of lines shown before and after the error line.
+const snippetContext = 3
+
+// newTemplateError locates err in the template files under rootDir.
+func newTemplateError(rootDir string, err error) *TemplateError {
+	var tErr *TemplateError
+	if errors.As(err, &tErr) {
+		return tErr
+	}
+
+	tErr = &TemplateError{Err: err}
+
+	m := errorLocation.FindStringSubmatch(err.Error())
+	if m == nil {
+		return tErr
+	}
+
+	name := strings.SplitN(m[1], fragmentSeparator, 2)[0]
+	line, _ := strconv.Atoi(m[2])
+	tErr.Line = line
+
+	path := filepath.Join(rootDir, filepath.FromSlash(name))
+	b, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return tErr
+	}
+	tErr.File = path
+
+	lines := strings.Split(string(b), "\n")
+	start := max(line-snippetContext, 1)
+	end := min(line+snippetContext, len(lines))
+	for n := start; n <= end; n++ {
+		tErr.Snippet = append(tErr.Snippet, SourceLine{
+			Number:  n,
+			Text:    lines[n-1],
+			IsError: n == line,
+		})
+	}
+	return tErr
+}
+
+var devErrorTemplate = template.Must(template.New("gor_dev_error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Template error</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #1f2937; }
+h1 { color: #b91c1c; font-size: 1.5rem; }
+.message { background: #fef2f2; border: 1px solid #fecaca; padding: 1rem; white-space: pre-wrap; }
+pre { background: #111827; color: #e5e7eb; padding: 1rem; overflow-x: auto; }
+.line { display: block; }
+.line.error { background: #7f1d1d; }
+.number { display: inline-block; width: 3rem; color: #9ca3af; user-select: none; }
+</style>
+</head>
+<body>
+<h1>Template error</h1>
+{{ if .File }}<p><strong>{{ .File }}</strong>{{ if .Line }}, line {{ .Line }}{{ end }}</p>{{ end }}
+<div class="message">{{ .Err }}</div>
+{{ if .Snippet }}<pre>{{ range .Snippet }}<span class="line{{ if .IsError }} error{{ end }}"><span class="number">{{ .Number }}</span>{{ .Text }}</span>{{ end }}</pre>{{ end }}
+<p><small>This page is only shown in development mode.</small></p>
+</body>
+</html>`))
+
+// writeDevError renders the development error page for err.
+func (r *Router) writeDevError(w io.Writer, err error) {
+	rootDir := ""
+	if r.reloader != nil {
+		rootDir = r.reloader.rootDir
+	}
+
+	writer, ok := w.(http.ResponseWriter)
+	if !ok {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if execErr := devErrorTemplate.Execute(buf, newTemplateError(rootDir, err)); execErr != nil {
+		buf.Reset()
+		fmt.Fprint(buf, template.HTMLEscapeString(err.Error()))
+	}
+
+	writer.Header().Set("Content-Type", ContentTypeHTML)
+	writer.WriteHeader(http.StatusInternalServerError)
+	writer.Write(buf.Bytes())
+}
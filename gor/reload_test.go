@@ -0,0 +1,104 @@
+package gor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// writeTemplate writes a template file and moves its mtime forward so that
+// the change is detected even on filesystems with coarse timestamps.
+func writeTemplate(t *testing.T, path, content string, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime := time.Now().Add(age)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func renderHome(r *gor.Router) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestTemplateReload(t *testing.T) {
+	interval := gor.TemplateReloadInterval
+	gor.TemplateReloadInterval = 0
+	defer func() { gor.TemplateReloadInterval = interval }()
+
+	dir := t.TempDir()
+	page := filepath.Join(dir, "home.html")
+	writeTemplate(t, page, "<h1>Hello {{ .Name }}</h1>", -time.Hour)
+
+	r := gor.NewRouter(gor.TemplateDir(dir, nil), gor.DevMode(true))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "home.html", gor.Map{"Name": "gor"})
+	})
+
+	if w := renderHome(r); w.Body.String() != "<h1>Hello gor</h1>" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+
+	writeTemplate(t, page, "<h1>Hi {{ .Name }}</h1>", -time.Minute)
+	if w := renderHome(r); w.Body.String() != "<h1>Hi gor</h1>" {
+		t.Fatalf("expected reloaded template, got %q", w.Body.String())
+	}
+
+	writeTemplate(t, page, "<h1>\n{{ if .Name }}\nHi\n", 0)
+	w := renderHome(r)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{page, "line 4", `<span class="number">2</span>{{ if .Name }}`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected error page to contain %q, got %s", want, body)
+		}
+	}
+
+	// Fixing the template clears the error.
+	writeTemplate(t, page, "<h1>Fixed</h1>", time.Minute)
+	if w := renderHome(r); w.Code != http.StatusOK || w.Body.String() != "<h1>Fixed</h1>" {
+		t.Fatalf("expected fixed template, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTemplateDirProduction(t *testing.T) {
+	interval := gor.TemplateReloadInterval
+	gor.TemplateReloadInterval = 0
+	defer func() { gor.TemplateReloadInterval = interval }()
+
+	dir := t.TempDir()
+	page := filepath.Join(dir, "home.html")
+	writeTemplate(t, page, "<h1>Cached</h1>", -time.Hour)
+
+	r := gor.NewRouter(gor.TemplateDir(dir, nil))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "home.html", gor.Map{})
+	})
+
+	writeTemplate(t, page, "<h1>Changed</h1>", 0)
+	if w := renderHome(r); w.Body.String() != "<h1>Cached</h1>" {
+		t.Fatalf("expected cached template, got %q", w.Body.String())
+	}
+
+	writeTemplate(t, page, "{{ if }}", 0)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected TemplateDir to panic on a parse error outside development mode")
+		}
+	}()
+	gor.NewRouter(gor.TemplateDir(dir, nil))
+}
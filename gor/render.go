@@ -0,0 +1,139 @@
+package gor
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which buffers are not returned to
+// the pool, so that one very large page does not pin its memory forever.
+const maxPooledBufferSize = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns buf to the pool. buf must not be used afterwards.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// StreamLayout enables or disables streaming of layouts.
+// When enabled, the part of the layout before the content block(usually the <head>
+// with stylesheets and scripts) is sent and flushed before the page is rendered,
+// so the browser can start fetching assets earlier.
+//
+// Layouts are still rendered completely before anything is written, so errors in a
+// layout produce a normal error response. If the page itself fails, the status(200)
+// and head have already been sent: the error is logged, the rest of the layout is
+// written and, in development mode, the error message is included in the page.
+//
+// Streaming needs a writer that implements http.Flusher. Otherwise the page is buffered.
+// The default value is `false`.
+//
+// Example:
+//
+//	r := NewRouter(gor.StreamLayout(true))
+func StreamLayout(enabled bool) RouterOption {
+	return func(r *Router) {
+		r.streamLayout = enabled
+	}
+}
+
+// streamMarker is rendered in place of the content block to split the layout.
+const streamMarker = "<!--gor:stream-content-->"
+
+// streamedError is an error that occurred after the response was committed.
+type streamedError struct {
+	err error
+}
+
+func (e *streamedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *streamedError) Unwrap() error {
+	return e.err
+}
+
+// renderShell renders the layouts around streamMarker, from the innermost to the
+// outermost, and splits the result at the marker. The caller must put the shell back
+// in the pool. split is false if the marker is missing or repeated, e.g when a layout
+// renders the content block conditionally or more than once.
+func (r *Router) renderShell(layouts []*template.Template, data Map) (shell *bytes.Buffer, head, tail []byte, split bool, err error) {
+	content := template.HTML(streamMarker)
+	shell = getBuffer()
+
+	for i, layout := range layouts {
+		if i > 0 {
+			content = template.HTML(shell.String())
+			shell.Reset()
+		}
+
+		data[r.contentBlock] = content
+		if err := layout.Execute(shell, data); err != nil {
+			return shell, nil, nil, false, err
+		}
+	}
+
+	head, tail, found := bytes.Cut(shell.Bytes(), []byte(streamMarker))
+	if !found || bytes.Contains(tail, []byte(streamMarker)) {
+		return shell, nil, nil, false, nil
+	}
+	return shell, head, tail, true, nil
+}
+
+// streamPage writes and flushes the head of the layouts, then renders the page
+// followed by the tail.
+func (r *Router) streamPage(w http.ResponseWriter, flusher http.Flusher, page *template.Template, head, tail []byte, data Map) error {
+	w.Header().Set("Content-Type", ContentTypeHTML)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(head); err != nil {
+		return &streamedError{err}
+	}
+	flusher.Flush()
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	pageErr := page.Execute(buf, data)
+	if pageErr != nil {
+		buf.Reset()
+		if r.devMode {
+			buf.WriteString("<pre>")
+			buf.WriteString(template.HTMLEscapeString(pageErr.Error()))
+			buf.WriteString("</pre>")
+		}
+	}
+	buf.Write(tail)
+
+	if _, err := w.Write(buf.Bytes()); err != nil && pageErr == nil {
+		pageErr = err
+	}
+
+	if pageErr != nil {
+		return &streamedError{pageErr}
+	}
+	return nil
+}
+
+// isStreamedError reports whether err occurred after the response was committed.
+func isStreamedError(err error) bool {
+	var streamed *streamedError
+	return errors.As(err, &streamed)
+}
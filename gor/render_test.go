@@ -0,0 +1,93 @@
+package gor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+func newStreamRouter(t testing.TB, stream bool) *gor.Router {
+	templ, err := gor.ParseTemplatesRecursive("testdata/layouts", template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gor.NewRouter(
+		gor.WithTemplates(templ),
+		gor.BaseLayout("layouts/root.html"),
+		gor.LayoutParent("layouts/dashboard.html", "layouts/root.html"),
+		gor.LayoutBlocks("Title", "Scripts"),
+		gor.StreamLayout(stream),
+	)
+
+	r.Get("/stats", func(w http.ResponseWriter, req *http.Request) {
+		gor.RenderLayout(w, req, "pages/stats.html", "layouts/dashboard.html", gor.Map{})
+	})
+
+	r.Get("/broken", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "pages/broken.html", gor.Map{"User": "John"})
+	})
+
+	r.Get("/missing-layout", func(w http.ResponseWriter, req *http.Request) {
+		gor.RenderLayout(w, req, "pages/stats.html", "layouts/missing.html", gor.Map{})
+	})
+	return r
+}
+
+func TestStreamLayout(t *testing.T) {
+	buffered := httptest.NewRecorder()
+	newStreamRouter(t, false).ServeHTTP(buffered, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	r := newStreamRouter(t, true)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	if !w.Flushed {
+		t.Error("expected the layout head to be flushed")
+	}
+
+	if w.Body.String() != buffered.Body.String() {
+		t.Errorf("expected streamed body %q to equal buffered body %q", w.Body.String(), buffered.Body.String())
+	}
+
+	// The page fails after the head was sent: the layout is still completed.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/broken", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	expected := "<html><head><title></title></head><body></body></html>\n"
+	if w.Body.String() != expected {
+		t.Errorf("expected %q, got %q", expected, w.Body.String())
+	}
+
+	// Layout errors happen before anything is written.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing-layout", nil))
+
+	if w.Code != http.StatusInternalServerError || w.Flushed {
+		t.Errorf("expected an unflushed 500 response, got %d(flushed=%v)", w.Code, w.Flushed)
+	}
+}
+
+func TestRenderErrorMidway(t *testing.T) {
+	r := newStreamRouter(t, false)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/broken", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+
+	// Nothing of the layout is written.
+	if body := w.Body.String(); len(body) == 0 || body[0] == '<' {
+		t.Errorf("expected only the error message, got %q", body)
+	}
+}
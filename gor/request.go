@@ -68,6 +68,8 @@ func SendString(w http.ResponseWriter, s string) error {
 }
 
 // Sends the error message to the client as html.
+// If the client accepts application/problem+json, an RFC 9457 problem document is sent instead.
+// See ProblemFromError for how errors are converted.
 // If the Router has errorTemplate configured, the error template will be rendered instead.
 // You can also pass a status code to be used.
 // Yo do not need to call SendError after template rendering since the template will be rendered
@@ -81,6 +83,16 @@ func SendError(w http.ResponseWriter, req *http.Request, err error, status ...in
 	// Print the error stack trace
 	debug.PrintStack()
 
+	// Clients that accept problem details get an RFC 9457 document.
+	if AcceptsProblemJSON(req) {
+		problem := ProblemFromError(err, statusCode)
+		if problem.Instance == "" {
+			problem.Instance = req.URL.Path
+		}
+		SendProblem(w, problem)
+		return
+	}
+
 	// In case its htmx, return the error as is
 	isHtmx := req.Header.Get("HX-Request") == "true"
 	if isHtmx {
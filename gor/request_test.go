@@ -29,6 +29,24 @@ func TestSetAndGetContextValue(t *testing.T) {
 
 }
 
+func TestRoutePattern(t *testing.T) {
+	r := NewRouter()
+
+	var pattern string
+	r.Get("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		pattern = RoutePattern(req)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	if pattern != "GET /users/{id}" {
+		t.Errorf("expected the route pattern, got %q", pattern)
+	}
+
+	if got := RoutePattern(httptest.NewRequest(http.MethodGet, "/", nil)); got != "" {
+		t.Errorf("expected no pattern outside the router, got %q", got)
+	}
+}
+
 func TestSendJSON(t *testing.T) {
 	r := NewRouter()
 
@@ -0,0 +1,386 @@
+package gor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStreamClosed is returned when writing to an EventStream that is closed
+// or whose client has disconnected.
+var ErrStreamClosed = errors.New("event stream closed")
+
+// Event is a single server-sent event.
+// See https://html.spec.whatwg.org/multipage/server-sent-events.html.
+type Event struct {
+	ID    string        // Event id. Sent to the server as Last-Event-ID on reconnect.
+	Event string        // Event type. The browser dispatches "message" if empty.
+	Data  any           // string and []byte are sent as is. Other values are JSON encoded.
+	Retry time.Duration // Reconnection time for the client. Ignored if 0.
+}
+
+// EventStream writes server-sent events to a client.
+// It is safe for concurrent use.
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	ctx     context.Context
+	lastID  string
+
+	mu     sync.Mutex
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// SSE starts a server-sent event stream on w.
+// It sends the text/event-stream headers and flushes them immediately.
+// The ResponseWriter must implement http.Flusher, gor.ResponseWriter does.
+//
+// Call Close(usually deferred) before the handler returns.
+//
+//	stream, err := gor.SSE(w, req)
+//	if err != nil {
+//		gor.SendError(w, req, err)
+//		return
+//	}
+//	defer stream.Close()
+//	stream.Heartbeat(15 * time.Second)
+//
+//	for {
+//		select {
+//		case <-stream.Done():
+//			return
+//		case msg := <-messages:
+//			stream.Send(gor.Event{Event: "message", Data: msg})
+//		}
+//	}
+func SSE(w http.ResponseWriter, req *http.Request) (*EventStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming is not supported: http.Flusher is not implemented")
+	}
+
+	lastID := req.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		// EventSource polyfills can not set headers.
+		lastID = req.URL.Query().Get("lastEventId")
+	}
+
+	w.Header().Set("Content-Type", ContentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &EventStream{
+		w:       w,
+		flusher: flusher,
+		ctx:     req.Context(),
+		lastID:  lastID,
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// LastEventID returns the Last-Event-ID sent by a reconnecting client.
+func (s *EventStream) LastEventID() string {
+	return s.lastID
+}
+
+// Done returns a channel that is closed when the client disconnects.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Send writes the event to the client and flushes it.
+func (s *EventStream) Send(ev Event) error {
+	var buf bytes.Buffer
+
+	if ev.ID != "" {
+		buf.WriteString("id: " + singleLine(ev.ID) + "\n")
+	}
+
+	if ev.Event != "" {
+		buf.WriteString("event: " + singleLine(ev.Event) + "\n")
+	}
+
+	if ev.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	var data string
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// Comment writes a comment line. Clients ignore comments but they keep the
+// connection and intermediate proxies alive.
+func (s *EventStream) Comment(text string) error {
+	return s.write([]byte(": " + singleLine(text) + "\n\n"))
+}
+
+// Heartbeat sends a comment every interval until the stream is closed
+// or the client disconnects.
+func (s *EventStream) Heartbeat(interval time.Duration) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				if s.Comment("heartbeat") != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the heartbeat and prevents further writes.
+// It must be called before the handler returns.
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *EventStream) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// Broker is an in-process publish/subscribe hub for server-sent events.
+// Each topic keeps the last BufferSize events so that reconnecting clients
+// can be replayed the events they missed using Last-Event-ID.
+//
+// Topics are created on demand and removed when they have neither subscribers
+// nor buffered events, so clients subscribing to arbitrary topics(e.g from a
+// path value) do not accumulate them.
+type Broker struct {
+	// Interval between heartbeat comments sent by Handler. Default is 15 seconds.
+	HeartbeatInterval time.Duration
+
+	bufferSize int
+	mu         sync.Mutex
+	topics     map[string]*topic
+}
+
+type topic struct {
+	seq         uint64
+	buffer      []Event
+	subscribers map[chan Event]struct{}
+}
+
+// subscriberBuffer is the number of events queued for a subscriber
+// before it is considered too slow and disconnected.
+const subscriberBuffer = 32
+
+// NewBroker creates a new broker that keeps up to bufferSize events per topic for replay.
+func NewBroker(bufferSize int) *Broker {
+	return &Broker{
+		HeartbeatInterval: 15 * time.Second,
+		bufferSize:        bufferSize,
+		topics:            make(map[string]*topic),
+	}
+}
+
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{subscribers: make(map[chan Event]struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// removeIfUnused removes the topic if it has neither subscribers nor buffered events.
+func (b *Broker) removeIfUnused(name string, t *topic) {
+	if len(t.subscribers) == 0 && len(t.buffer) == 0 && b.topics[name] == t {
+		delete(b.topics, name)
+	}
+}
+
+// Publish sends ev to all subscribers of the topic and returns the published event.
+// If ev.ID is empty, a sequential id is assigned.
+//
+// Subscribers that can not keep up are disconnected. Browsers reconnect automatically
+// and are replayed the missed events from the buffer.
+func (b *Broker) Publish(topicName string, ev Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topicName)
+	t.seq++
+	if ev.ID == "" {
+		ev.ID = strconv.FormatUint(t.seq, 10)
+	}
+
+	if b.bufferSize > 0 {
+		if len(t.buffer) == b.bufferSize {
+			t.buffer = append(t.buffer[:0], t.buffer[1:]...)
+		}
+		t.buffer = append(t.buffer, ev)
+	}
+
+	for ch := range t.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	b.removeIfUnused(topicName, t)
+	return ev
+}
+
+// Subscribe registers a new subscriber for the topic.
+// It returns the events published after lastEventID that are still buffered,
+// a channel of new events and a function to unsubscribe.
+// If lastEventID is not in the buffer, all buffered events are replayed.
+// The channel is closed when the subscriber is too slow or unsubscribes.
+func (b *Broker) Subscribe(topicName, lastEventID string) (replay []Event, events <-chan Event, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topicName)
+
+	if lastEventID != "" {
+		start := 0
+		for i, ev := range t.buffer {
+			if ev.ID == lastEventID {
+				start = i + 1
+				break
+			}
+		}
+		replay = append(replay, t.buffer[start:]...)
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	t.subscribers[ch] = struct{}{}
+
+	unsubscribe = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := t.subscribers[ch]; ok {
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+		b.removeIfUnused(topicName, t)
+	}
+	return replay, ch, unsubscribe
+}
+
+// Subscribers returns the number of active subscribers of the topic.
+func (b *Broker) Subscribers(topicName string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t, ok := b.topics[topicName]; ok {
+		return len(t.subscribers)
+	}
+	return 0
+}
+
+// Handler returns a handler that streams the events of a topic to the client.
+// topicName is called per request to determine the topic, for example from a path value.
+//
+//	broker := gor.NewBroker(100)
+//	r.Get("/events/{topic}", broker.Handler(func(req *http.Request) string {
+//		return req.PathValue("topic")
+//	}))
+func (b *Broker) Handler(topicName func(req *http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		stream, err := SSE(w, req)
+		if err != nil {
+			SendError(w, req, err, http.StatusInternalServerError)
+			return
+		}
+		defer stream.Close()
+
+		replay, events, unsubscribe := b.Subscribe(topicName(req), stream.LastEventID())
+		defer unsubscribe()
+
+		for _, ev := range replay {
+			if stream.Send(ev) != nil {
+				return
+			}
+		}
+
+		if b.HeartbeatInterval > 0 {
+			stream.Heartbeat(b.HeartbeatInterval)
+		}
+
+		for {
+			select {
+			case <-stream.Done():
+				return
+			case ev, ok := <-events:
+				if !ok || stream.Send(ev) != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ServeTopic returns a handler that streams the events of a fixed topic.
+func (b *Broker) ServeTopic(topicName string) http.HandlerFunc {
+	return b.Handler(func(*http.Request) string { return topicName })
+}
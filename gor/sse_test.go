@@ -0,0 +1,139 @@
+package gor
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStreamSend(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	stream, err := SSE(w, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream.Send(Event{ID: "1", Event: "update", Data: "line1\nline2", Retry: 3 * time.Second})
+	stream.Send(Event{Data: map[string]int{"count": 2}})
+	stream.Comment("ping")
+
+	expected := "id: 1\nevent: update\nretry: 3000\ndata: line1\ndata: line2\n\n" +
+		"data: {\"count\":2}\n\n" +
+		": ping\n\n"
+
+	if w.Body.String() != expected {
+		t.Errorf("expected %q, got %q", expected, w.Body.String())
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeEventStream {
+		t.Errorf("expected Content-Type %s, got %s", ContentTypeEventStream, ct)
+	}
+
+	cancel()
+	if err := stream.Send(Event{Data: "late"}); err != ErrStreamClosed {
+		t.Errorf("expected ErrStreamClosed after disconnect, got %v", err)
+	}
+	stream.Close()
+}
+
+func TestBrokerReplay(t *testing.T) {
+	b := NewBroker(3)
+	for i := 0; i < 5; i++ {
+		b.Publish("orders", Event{Data: i})
+	}
+
+	replay, _, unsubscribe := b.Subscribe("orders", "3")
+	defer unsubscribe()
+
+	if len(replay) != 2 || replay[0].ID != "4" || replay[1].ID != "5" {
+		t.Errorf("expected events 4 and 5 to be replayed, got %v", replay)
+	}
+
+	// id 1 has been evicted from the buffer, replay everything we have.
+	replay, _, unsubscribe2 := b.Subscribe("orders", "1")
+	defer unsubscribe2()
+
+	if len(replay) != 3 || replay[0].ID != "3" {
+		t.Errorf("expected full buffer to be replayed, got %v", replay)
+	}
+}
+
+func TestBrokerRemovesUnusedTopics(t *testing.T) {
+	b := NewBroker(1)
+
+	for i := 0; i < 100; i++ {
+		_, _, unsubscribe := b.Subscribe(strconv.Itoa(i), "")
+		unsubscribe()
+	}
+
+	_, _, unsubscribe := b.Subscribe("orders", "")
+	b.Publish("orders", Event{Data: "buffered"})
+	unsubscribe()
+
+	// Publishing without subscribers nor buffer does not keep the topic.
+	unbuffered := NewBroker(0)
+	unbuffered.Publish("orders", Event{Data: "dropped"})
+	if len(unbuffered.topics) != 0 {
+		t.Errorf("expected no topics without buffer, got %d", len(unbuffered.topics))
+	}
+
+	if len(b.topics) != 1 || b.topics["orders"] == nil {
+		t.Errorf("expected only the topic with buffered events to be kept, got %d topics", len(b.topics))
+	}
+
+	replay, _, unsubscribe := b.Subscribe("orders", "0")
+	defer unsubscribe()
+	if len(replay) != 1 {
+		t.Errorf("expected the buffered event to be replayed, got %v", replay)
+	}
+}
+
+func TestBrokerHandler(t *testing.T) {
+	b := NewBroker(10)
+	b.Publish("news", Event{Event: "headline", Data: "missed"})
+
+	r := NewRouter()
+	r.Get("/events/{topic}", b.Handler(func(req *http.Request) string {
+		return req.PathValue("topic")
+	}))
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/events/news", nil)
+	req.Header.Set("Last-Event-ID", "0")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	// Wait for the subscription before publishing.
+	for i := 0; i < 100 && b.Subscribers("news") == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	b.Publish("news", Event{Event: "headline", Data: "live"})
+
+	var data []string
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() && len(data) < 2 {
+		if line, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			data = append(data, line)
+		}
+	}
+
+	if strings.Join(data, ",") != "missed,live" {
+		t.Errorf("expected missed,live got %v", data)
+	}
+}
@@ -0,0 +1,422 @@
+package gor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ContentTypeNDJSON is the media type for newline delimited JSON.
+const ContentTypeNDJSON string = "application/x-ndjson"
+
+// StreamOptions configures the streaming response writers
+// SendCSV, SendNDJSON and SendXMLStream.
+type StreamOptions struct {
+	// If set, the response is sent as an attachment with this filename.
+	// See Attachment.
+	Filename string
+
+	// Number of rows written between flushes. Default is 100.
+	// A negative value disables periodic flushing.
+	FlushEvery int
+
+	// CSV field delimiter. Default is ','.
+	Comma rune
+
+	// Do not write the CSV header row.
+	NoHeader bool
+
+	// Layout used to format time.Time values in CSV. Default is time.RFC3339.
+	TimeFormat string
+
+	// Name of the XML root element. Default is "items".
+	Root string
+}
+
+func (o *StreamOptions) defaults() {
+	if o.FlushEvery == 0 {
+		o.FlushEvery = 100
+	}
+
+	if o.Comma == 0 {
+		o.Comma = ','
+	}
+
+	if o.TimeFormat == "" {
+		o.TimeFormat = time.RFC3339
+	}
+
+	if o.Root == "" {
+		o.Root = "items"
+	}
+}
+
+// Attachment sets the Content-Disposition header so that the browser downloads
+// the response as filename. Path components, quotes and control characters are
+// stripped from filename. Non-ASCII names are sent with the RFC 6266 filename* parameter
+// and an ASCII fallback.
+func Attachment(w http.ResponseWriter, filename string) {
+	name := SafeFilename(filename)
+
+	ascii := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return '_'
+		}
+		return r
+	}, name)
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": ascii})
+	if ascii != name {
+		disposition += "; filename*=UTF-8''" + encodeRFC5987(name)
+	}
+	w.Header().Set("Content-Disposition", disposition)
+}
+
+// SafeFilename returns the base name of filename without characters that are unsafe
+// in a Content-Disposition header. If nothing is left, "download" is returned.
+func SafeFilename(filename string) string {
+	// Treat both separators as path separators regardless of OS.
+	filename = filename[strings.LastIndexAny(filename, `/\`)+1:]
+
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == ';' || r == '%' {
+			return -1
+		}
+		return r
+	}, filename)
+
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "download"
+	}
+	return name
+}
+
+// encodeRFC5987 percent-encodes s for use in an ext-value.
+func encodeRFC5987(s string) string {
+	const attrChars = "!#$&+-.^_`|~"
+
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c < unicode.MaxASCII && (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte(attrChars, c) >= 0) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// SendCSV streams rows as text/csv.
+//
+// rows can be a slice, an array, a channel(read until closed) or an iterator function
+// of the form func(yield func(T) bool). Elements may be structs(or pointers to structs),
+// or []string.
+//
+// For struct elements, the header row is built from exported fields using the "csv" tag,
+// then the "json" tag, then the field name. A tag of "-" skips the field.
+// Slices of strings are written as is and have no header.
+//
+// The response is flushed every opts.FlushEvery rows.
+func SendCSV(w http.ResponseWriter, rows any, opts ...StreamOptions) error {
+	var o StreamOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o.defaults()
+
+	w.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+	if o.Filename != "" {
+		Attachment(w, o.Filename)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = o.Comma
+
+	var fields []csvField
+	headerWritten := o.NoHeader
+	record := []string{}
+	n := 0
+
+	err := eachRow(rows, func(row reflect.Value) error {
+		row = indirect(row)
+
+		if row.Kind() == reflect.Slice && row.Type().Elem().Kind() == reflect.String {
+			record = record[:0]
+			for i := 0; i < row.Len(); i++ {
+				record = append(record, row.Index(i).String())
+			}
+		} else {
+			if row.Kind() != reflect.Struct {
+				return fmt.Errorf("gor: unsupported CSV row type %s", row.Type())
+			}
+
+			if fields == nil {
+				fields = csvFields(row.Type())
+			}
+
+			if !headerWritten {
+				header := make([]string, len(fields))
+				for i, f := range fields {
+					header[i] = f.name
+				}
+
+				if err := cw.Write(header); err != nil {
+					return err
+				}
+				headerWritten = true
+			}
+
+			record = record[:0]
+			for _, f := range fields {
+				record = append(record, formatCSVValue(row.FieldByIndex(f.index), o.TimeFormat))
+			}
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+
+		n++
+		if o.FlushEvery > 0 && n%o.FlushEvery == 0 {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			flush(w)
+		}
+		return nil
+	})
+
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// SendNDJSON streams rows as newline delimited JSON, one JSON document per line.
+// rows accepts the same types as SendCSV. Elements are encoded with encoding/json.
+func SendNDJSON(w http.ResponseWriter, rows any, opts ...StreamOptions) error {
+	var o StreamOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o.defaults()
+
+	w.Header().Set("Content-Type", ContentTypeNDJSON)
+	if o.Filename != "" {
+		Attachment(w, o.Filename)
+	}
+
+	enc := json.NewEncoder(w)
+	n := 0
+
+	return eachRow(rows, func(row reflect.Value) error {
+		if err := enc.Encode(row.Interface()); err != nil {
+			return err
+		}
+
+		n++
+		if o.FlushEvery > 0 && n%o.FlushEvery == 0 {
+			flush(w)
+		}
+		return nil
+	})
+}
+
+// SendXMLStream streams rows as XML elements wrapped in a root element named opts.Root.
+// rows accepts the same types as SendCSV. Elements are encoded with encoding/xml.
+func SendXMLStream(w http.ResponseWriter, rows any, opts ...StreamOptions) error {
+	var o StreamOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o.defaults()
+
+	w.Header().Set("Content-Type", ContentTypeXML)
+	if o.Filename != "" {
+		Attachment(w, o.Filename)
+	}
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	root := xml.StartElement{Name: xml.Name{Local: o.Root}}
+	if err := enc.EncodeToken(root); err != nil {
+		return err
+	}
+
+	n := 0
+	err := eachRow(rows, func(row reflect.Value) error {
+		if err := enc.Encode(row.Interface()); err != nil {
+			return err
+		}
+
+		n++
+		if o.FlushEvery > 0 && n%o.FlushEvery == 0 {
+			if err := enc.Flush(); err != nil {
+				return err
+			}
+			flush(w)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// eachRow calls fn for every element of rows.
+// rows must be a slice, array, channel or func(yield func(T) bool).
+func eachRow(rows any, fn func(row reflect.Value) error) error {
+	rv := reflect.ValueOf(rows)
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := fn(rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Chan:
+		for {
+			v, ok := rv.Recv()
+			if !ok {
+				return nil
+			}
+
+			if err := fn(v); err != nil {
+				// Drain the channel in a separate goroutine so that the producer is not blocked forever.
+				go func() {
+					for {
+						if _, ok := rv.Recv(); !ok {
+							return
+						}
+					}
+				}()
+				return err
+			}
+		}
+	case reflect.Func:
+		t := rv.Type()
+		if t.NumIn() != 1 || t.NumOut() != 0 {
+			break
+		}
+
+		yieldType := t.In(0)
+		if yieldType.Kind() != reflect.Func || yieldType.NumIn() != 1 || yieldType.NumOut() != 1 ||
+			yieldType.Out(0).Kind() != reflect.Bool {
+			break
+		}
+
+		var err error
+		yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+			err = fn(args[0])
+			return []reflect.Value{reflect.ValueOf(err == nil)}
+		})
+		rv.Call([]reflect.Value{yield})
+		return err
+	}
+	return fmt.Errorf("gor: rows must be a slice, array, channel or iterator function, got %T", rows)
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+type csvField struct {
+	name  string
+	index []int
+}
+
+// csvFields returns the exported fields of struct type t in declaration order.
+func csvFields(t reflect.Type) []csvField {
+	fields := make([]csvField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Tag.Get("csv")
+		if name == "" {
+			name = field.Tag.Get("json")
+		}
+
+		name = strings.TrimSpace(strings.Split(name, ",")[0])
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, csvField{name: name, index: field.Index})
+	}
+	return fields
+}
+
+func formatCSVValue(v reflect.Value, timeFormat string) string {
+	v = indirect(v)
+	if !v.IsValid() || ((v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil()) {
+		return ""
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(timeFormat)
+	case fmt.Stringer:
+		return value.String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,144 @@
+package gor
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type reportRow struct {
+	ID      int       `csv:"id"`
+	Name    string    `json:"name"`
+	Amount  float64   `csv:"amount"`
+	Created time.Time `csv:"created"`
+	Secret  string    `csv:"-"`
+	Note    *string
+}
+
+func TestSendCSV(t *testing.T) {
+	note := "paid"
+	rows := []reportRow{
+		{ID: 1, Name: "Alice", Amount: 10.5, Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Note: &note},
+		{ID: 2, Name: "Bob, Jr.", Amount: 3, Secret: "hidden"},
+	}
+
+	r := NewRouter()
+	r.Get("/report", func(w http.ResponseWriter, req *http.Request) {
+		if err := SendCSV(w, rows, StreamOptions{Filename: "../report.csv", FlushEvery: 1}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/report", nil))
+
+	expected := "id,name,amount,created,Note\n" +
+		"1,Alice,10.5,2024-01-02T03:04:05Z,paid\n" +
+		"2,\"Bob, Jr.\",3,,\n"
+
+	if w.Body.String() != expected {
+		t.Errorf("expected %q, got %q", expected, w.Body.String())
+	}
+
+	if !w.Flushed {
+		t.Errorf("expected response to be flushed")
+	}
+
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=report.csv` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+}
+
+func TestSendCSVChannel(t *testing.T) {
+	ch := make(chan []string)
+	go func() {
+		defer close(ch)
+		ch <- []string{"a", "b"}
+		ch <- []string{"c", "d"}
+	}()
+
+	w := httptest.NewRecorder()
+	if err := SendCSV(w, ch, StreamOptions{Comma: ';'}); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Body.String() != "a;b\nc;d\n" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestSendNDJSON(t *testing.T) {
+	seq := func(yield func(reportRow) bool) {
+		for i := 1; i <= 3; i++ {
+			if !yield(reportRow{ID: i}) {
+				return
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	if err := SendNDJSON(w, seq); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeNDJSON {
+		t.Errorf("expected Content-Type %s, got %s", ContentTypeNDJSON, ct)
+	}
+
+	scanner := bufio.NewScanner(w.Body)
+	lines := 0
+	for scanner.Scan() {
+		var row reportRow
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatal(err)
+		}
+		lines++
+		if row.ID != lines {
+			t.Errorf("expected id %d, got %d", lines, row.ID)
+		}
+	}
+
+	if lines != 3 {
+		t.Errorf("expected 3 lines, got %d", lines)
+	}
+}
+
+func TestSendXMLStream(t *testing.T) {
+	type item struct {
+		ID int `xml:"id,attr"`
+	}
+
+	w := httptest.NewRecorder()
+	if err := SendXMLStream(w, []item{{1}, {2}}, StreamOptions{Root: "items"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(w.Body.String(), `<items><item id="1"></item><item id="2"></item></items>`) {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{"report.csv", `attachment; filename=report.csv`},
+		{`C:\tmp\"evil";.csv`, `attachment; filename=evil.csv`},
+		{"ripoti ya mwezi.csv", `attachment; filename="ripoti ya mwezi.csv"`},
+		{"ëxport.csv", `attachment; filename=_xport.csv; filename*=UTF-8''%C3%ABxport.csv`},
+		{"../", `attachment; filename=download`},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		Attachment(w, tt.filename)
+		if got := w.Header().Get("Content-Disposition"); got != tt.expected {
+			t.Errorf("Attachment(%q) = %q, expected %q", tt.filename, got, tt.expected)
+		}
+	}
+}
@@ -64,6 +64,23 @@ func PassContextToViews(passContextToViews bool) RouterOption {
 	}
 }
 
+// SkipLayoutForHTMX enables or disables rendering htmx requests without the base layout.
+// When enabled, Render executes only the page template for requests with the
+// "HX-Request" header, unless they are boosted("HX-Boosted") or restore the history
+// after a cache miss("HX-History-Restore-Request"), which need the full page.
+// Both the full page and the partial are sent with "Vary: HX-Request, HX-Boosted,
+// HX-History-Restore-Request".
+// The default value is `true`.
+//
+// Example:
+//
+//	r := NewRouter(gor.SkipLayoutForHTMX(false)) // always render the layout
+func SkipLayoutForHTMX(skip bool) RouterOption {
+	return func(r *Router) {
+		r.htmxSkipLayout = skip
+	}
+}
+
 // WithTemplates sets the template for the router.
 // This template will be used to render views.
 //
@@ -96,22 +113,59 @@ Available components are:
 
 input: props(id, name, value, label, required, disabled, readonly, placeholder).
 
-select: Like input, also has "options" []string prop.
+select: Like input, also has "options", "placeholder" and "multiple" props.
+"options" is any value accepted by SelectOptions: []string, []gor.Option, maps or option groups.
+"value" is the selected value, or a slice of values if "multiple" is true.
 
 textarea: Like input.
 
 checkbox: Like input, also has "checked" prop(A bool or string("true"/"on" are true otherwise false))
 
-radio: Same as checkbox. also has "options" []string prop
+radio: Like select(without "multiple"). The option matching "value" is checked.
+
+button: Props(ID, Type, Disabled, Variant(primary, secondary, success, danger, warning, info))
+
+table: Props(table(a gor.Table, see NewTable), empty)
+
+pagination: Props(pagination(a gor.Pagination, see NewPagination))
+
+alert: Props(kind(info, success, warning, danger), message, dismissible). Dismissible alerts need gor_scripts.
+
+alerts: Props(alerts([]gor.Alert, see Flashes))
+
+modal: Props(id, title, body, confirm, cancel, action, csrf). Opened by a button with
+data-gor-open="<id>"(needs gor_scripts) or with document.getElementById(id).showModal().
+
+breadcrumbs: Props(crumbs([]gor.Crumb, see BreadcrumbsFromPath))
 
-button: Props(ID, Type, Disabled)
+csrf: Props(token, name)
+
+gor_scripts: Props(nonce). Script handling the buttons of alerts and modals without inline
+event handlers, which a Content-Security-Policy blocks. Include it once, e.g in the layout.
+
+input, textarea, select and checkbox also accept an "error" prop: the message shown
+below the control, which is then marked invalid. Forms generated with the "form"
+function and RenderForm render their fields with these components.
+
+Every component accepts a "class" prop that replaces the class of its control.
+The other classes come from the theme. See Theme.
 */
 func parseComponents(funcMap template.FuncMap) *template.Template {
-	return template.Must(template.New(componentName).Funcs(funcMap).Parse(components))
+	t := template.Must(template.New(componentName).Funcs(funcMap).Parse(components))
+
+	// Markup of the theme.
+	if theme, ok := funcMap["theme"].(func() *Theme); ok {
+		for name, text := range theme().Templates {
+			template.Must(t.New(name).Parse(text))
+		}
+	}
+	return t
 }
 
 // ParseTemplatesRecursive parses all templates in a directory recursively.
 // It uses the specified `funcMap` to define custom template functions.
+// Blocks defined in a page are also registered under "page#block", see RenderFragment.
+// The built-in components use TailwindTheme unless a theme is selected with Theme.Funcs.
 // The `suffix` argument can be used to specify a different file extension for the templates.
 // The default file extension is ".html".
 //
@@ -134,6 +188,8 @@ func ParseTemplatesRecursive(rootDir string, funcMap template.FuncMap, suffix ..
 
 	funcMap["Props"] = Props
 	funcMap["IsTrue"] = isTrue
+	funcMap["SelectOptions"] = SelectOptions
+	addThemeFuncs(funcMap)
 	components := parseComponents(funcMap)
 
 	cleanRoot := filepath.Clean(rootDir)
@@ -159,18 +215,23 @@ func ParseTemplatesRecursive(rootDir string, funcMap template.FuncMap, suffix ..
 				return err
 			}
 
-			t := root.New(path[pfx:]).Funcs(funcMap)
-			_, err = t.Parse(string(b))
-			return err
+			name := path[pfx:]
+			t := root.New(name).Funcs(funcMap)
+			if _, err = t.Parse(string(b)); err != nil {
+				return err
+			}
+			return addFragments(root, name, string(b))
 		}
 		return nil
 	})
 
+	bindFormTemplate(root, funcMap)
 	return root, err
 }
 
 // ParseTemplatesRecursiveFS parses all templates in a directory recursively from a given filesystem.
 // It uses the specified `funcMap` to define custom template functions.
+// The built-in components use TailwindTheme unless a theme is selected with Theme.Funcs.
 // The `suffix` argument can be used to specify a different file extension for the templates.
 // The default file extension is ".html".
 //
@@ -194,6 +255,8 @@ func ParseTemplatesRecursiveFS(root fs.FS, rootDir string, funcMap template.Func
 
 	funcMap["Props"] = Props
 	funcMap["IsTrue"] = isTrue
+	funcMap["SelectOptions"] = SelectOptions
+	addThemeFuncs(funcMap)
 	components := parseComponents(funcMap)
 
 	pfx := len(rootDir) + 1  // +1 for the trailing slash
@@ -222,13 +285,17 @@ func ParseTemplatesRecursiveFS(root fs.FS, rootDir string, funcMap template.Func
 				return err
 			}
 
-			t := tmpl.New(rootDir + "/" + path[pfx:]).Funcs(funcMap)
-			_, err = t.Parse(string(b))
-
-			return err
+			name := rootDir + "/" + path[pfx:]
+			t := tmpl.New(name).Funcs(funcMap)
+			if _, err = t.Parse(string(b)); err != nil {
+				return err
+			}
+			return addFragments(tmpl, name, string(b))
 		}
 		return nil
 	})
+
+	bindFormTemplate(tmpl, funcMap)
 	return tmpl, err
 }
 
@@ -22,29 +22,31 @@ var components = `
   {{- $autocomplete := .autocomplete }}
   {{- $autofocus := IsTrue .autofocus }}
   {{- $class := .class }}
-  {{ if not $class }}
-  {{ $class = "py-2 px-3 mt-1 block w-full rounded-md border border-gray-300 shadow-sm focus:border-indigo-300 focus:ring focus:ring-indigo-200 focus:ring-opacity-50" }}
-  {{ end}}
-
-  <div class="mb-4">
-    <label for="{{ $ID }}">{{ .label }}</label>
-    <input
-      type="{{ $type }}"
-      id="{{ $ID }}"
-      name="{{ .name }}"
-      {{- if $disabled }} disabled{{ end }}
-      {{- if $readonly }} readonly{{ end }}
-      {{- if $required }} required{{ end }}
-      {{- if $placeholder }} placeholder="{{ $placeholder }}"{{ end }}
+  {{- if not $class }}
+  {{- if .error }}
+  {{- $class = themeClass "input" "control" "invalid" }}
+  {{- else }}
+  {{- $class = themeClass "input" "control" }}
+  {{- end }}
+  {{- end }}
+
+  <div{{ with themeClass "input" "wrapper" }} class="{{ . }}"{{ end }}>
+    <label for="{{ $ID }}"{{ with themeClass "input" "label" }} class="{{ . }}"{{ end }}>{{ .label }}</label>
+    <input type="{{ $type }}" id="{{ $ID }}" name="{{ .name }}"
       {{- if $value }} value="{{ $value }}"{{ end }}
       {{- if $min }} min="{{ $min }}"{{ end }}
       {{- if $max }} max="{{ $max }}"{{ end }}
       {{- if $step }} step="{{ $step }}"{{ end }}
       {{- if $pattern }} pattern="{{ $pattern }}"{{ end }}
+      {{- if $class }} class="{{ $class }}"{{ end }}
+      {{- if $placeholder }} placeholder="{{ $placeholder }}"{{ end }}
       {{- if $autocomplete }} autocomplete="{{ $autocomplete }}"{{ end }}
       {{- if $autofocus }} autofocus{{ end }}
-      {{- if $class }} class="{{ $class }}"{{ end }}
-    >
+      {{- if $disabled }} disabled{{ end }}
+      {{- if $readonly }} readonly{{ end }}
+      {{- if $required }} required{{ end }}
+      {{- if .error }} aria-invalid="true"{{ end }}>
+    {{- template "field_error" . }}
   </div>
 {{ end }}
 
@@ -57,14 +59,25 @@ var components = `
 {{- $disabled := IsTrue .disabled }}
 {{- $readonly := IsTrue .readonly }}
 {{- $required := IsTrue .required }}
+{{- $class := .class }}
+{{- if not $class }}
+{{- if .error }}
+{{- $class = themeClass "textarea" "control" "invalid" }}
+{{- else }}
+{{- $class = themeClass "textarea" "control" }}
+{{- end }}
+{{- end }}
 
-<div class="mb-4">
-    <label for="{{ $ID }}" class="block text-base font-medium text-gray-800 mb-1">{{.label}}</label>
-    <textarea id="{{ $ID }}" 
-              name="{{ .name }}"
-              placeholder="{{ .placeholder }}"
-              class="py-2 px-3 mt-1 block w-full rounded-md border border-gray-300 shadow-sm focus:border-indigo-300 focus:ring focus:ring-indigo-200 focus:ring-opacity-50"
-              {{ if $required}}required{{ end }} {{ if $readonly}}readonly{{ end }} {{ if $disabled}}disabled{{ end }}>{{- .value -}}</textarea>
+<div{{ with themeClass "textarea" "wrapper" }} class="{{ . }}"{{ end }}>
+    <label for="{{ $ID }}"{{ with themeClass "textarea" "label" }} class="{{ . }}"{{ end }}>{{.label}}</label>
+    <textarea id="{{ $ID }}" name="{{ .name }}"
+              {{- if $class }} class="{{ $class }}"{{ end }}
+              {{- with .placeholder }} placeholder="{{ . }}"{{ end }}
+              {{- if $required }} required{{ end }}
+              {{- if $readonly }} readonly{{ end }}
+              {{- if $disabled }} disabled{{ end }}
+              {{- if .error }} aria-invalid="true"{{ end }}>{{- .value -}}</textarea>
+    {{- template "field_error" . }}
 </div>
 {{ end }}
 
@@ -75,23 +88,41 @@ var components = `
 {{- end }}
 
 {{- $disabled := IsTrue .disabled }}
-{{- $readonly := IsTrue .readonly }}
 {{- $required := IsTrue .required }}
+{{- $multiple := IsTrue .multiple }}
+{{- $class := .class }}
+{{- if not $class }}
+{{- if .error }}
+{{- $class = themeClass "select" "control" "invalid" }}
+{{- else }}
+{{- $class = themeClass "select" "control" }}
+{{- end }}
+{{- end }}
 
-<div class="mb-4">
-    <label for="{{ $ID }}" class="block text-base font-medium text-gray-800 mb-1">{{.label}}</label>
-    <select id="{{ $ID }}" 
-            name="{{ .name }}"
-			value={{ $.Value }}
-            class="py-2 px-3 mt-1 block w-full rounded-md border bg-white border-gray-300 shadow-sm focus:border-indigo-300 focus:ring focus:ring-indigo-200 focus:ring-opacity-50"
-            {{ if $required}}required{{ end }} {{ if $readonly}}readonly{{ end }} {{ if $disabled}}disabled{{ end }}>
-		{{ if .Placeholder }}
-        	<option value="">{{.Placeholder}}</option>
-		{{ end }}
-        {{ range .options }}
-        <option value="{{ . }}" {{ if eq . $.value }}selected{{ end }}>{{.}}</option>
-        {{ end }}
+<div{{ with themeClass "select" "wrapper" }} class="{{ . }}"{{ end }}>
+    <label for="{{ $ID }}"{{ with themeClass "select" "label" }} class="{{ . }}"{{ end }}>{{ .label }}</label>
+    <select id="{{ $ID }}" name="{{ .name }}"
+            {{- if $class }} class="{{ $class }}"{{ end }}
+            {{- if $multiple }} multiple{{ end }}
+            {{- if $required }} required{{ end }}
+            {{- if $disabled }} disabled{{ end }}
+            {{- if .error }} aria-invalid="true"{{ end }}>
+        {{- with .placeholder }}
+        <option value="">{{ . }}</option>
+        {{- end }}
+        {{- range SelectOptions .options .value }}
+        {{- if .Label }}
+        <optgroup label="{{ .Label }}">
+        {{- end }}
+        {{- range .Options }}
+        <option value="{{ .Value }}"{{ if .Selected }} selected{{ end }}{{ if .Disabled }} disabled{{ end }}>{{ .Label }}</option>
+        {{- end }}
+        {{- if .Label }}
+        </optgroup>
+        {{- end }}
+        {{- end }}
     </select>
+    {{- template "field_error" . }}
 </div>
 {{ end }}
 
@@ -105,18 +136,27 @@ var components = `
 {{- $readonly := IsTrue .readonly }}
 {{- $required := IsTrue .required }}
 {{- $checked := IsTrue .checked }}
+{{- $class := .class }}
+{{- if not $class }}
+{{- if .error }}
+{{- $class = themeClass "checkbox" "control" "invalid" }}
+{{- else }}
+{{- $class = themeClass "checkbox" "control" }}
+{{- end }}
+{{- end }}
 
-<div class="mb-4">
-    <label for="{{ $ID }}" class="inline-flex items-center gap-2">
-        <input type="checkbox" class="py-2 px-3 rounded border border-gray-300 text-indigo-600 shadow-sm focus:border-indigo-300 focus:ring focus:ring-indigo-200 focus:ring-opacity-50"
-               id="{{ $ID }}" name="{{ .name }}" value="{{ .value }}"
-               {{ if $checked}}checked{{ end }}
-			   {{ if $required}}required{{ end }} 
-			   {{ if $readonly}}readonly{{ end }} 
-			   {{ if $disabled}}disabled{{ end }}  
-			   >
-        <span class="ml-2 text-base text-gray-800">{{ .label }}</span>
+<div{{ with themeClass "checkbox" "wrapper" }} class="{{ . }}"{{ end }}>
+    <label for="{{ $ID }}"{{ with themeClass "checkbox" "option" }} class="{{ . }}"{{ end }}>
+        <input type="checkbox" id="{{ $ID }}" name="{{ .name }}" value="{{ .value }}"
+               {{- if $class }} class="{{ $class }}"{{ end }}
+               {{- if $checked }} checked{{ end }}
+               {{- if $required }} required{{ end }}
+               {{- if $readonly }} readonly{{ end }}
+               {{- if $disabled }} disabled{{ end }}
+               {{- if .error }} aria-invalid="true"{{ end }}>
+        <span{{ with themeClass "checkbox" "text" }} class="{{ . }}"{{ end }}>{{ .label }}</span>
     </label>
+    {{- template "field_error" . }}
 </div>
 {{ end }}
 
@@ -125,66 +165,224 @@ var components = `
 {{- if not $ID }}
 {{- $ID = .name }}
 {{- end }}
+{{- $name := .name }}
 
 {{- $disabled := IsTrue .disabled }}
-{{- $readonly := IsTrue .readonly }}
 {{- $required := IsTrue .required }}
-{{- $checked := IsTrue .checked }}
+{{- $class := .class }}
+{{- if not $class }}
+{{- $class = themeClass "radio" "control" }}
+{{- end }}
 
-<div class="mb-4">
-    <span class="block text-base font-medium text-gray-800 mb-1">{{ .label }}</span>
-    <div class="mt-1 space-y-2">
-        {{ range .options }}
-        <label class="inline-flex items-center">
-            <input type="radio" 
-			class="border border-gray-300 text-indigo-600 shadow-sm focus:border-indigo-300 focus:ring focus:ring-indigo-200 focus:ring-opacity-50"
-					id="{{ $ID }}_{{.name}}" name="{{ .name }}" value="{{ .value }}"
-	               {{ if $checked}}checked{{ end }}
-				   {{ if $required}}required{{ end }} 
-				   {{ if $readonly}}readonly{{ end }} 
-				   {{ if $disabled}}disabled{{ end }}   
-				   >
-            <span class="ml-2 text-base text-gray-800">{{ . }}</span>
+<div{{ with themeClass "radio" "wrapper" }} class="{{ . }}"{{ end }}>
+    <span{{ with themeClass "radio" "label" }} class="{{ . }}"{{ end }}>{{ .label }}</span>
+    {{- range $g, $group := SelectOptions .options .value }}
+    <div{{ with themeClass "radio" "group" }} class="{{ . }}"{{ end }} role="radiogroup"{{ with $group.Label }} aria-label="{{ . }}"{{ end }}>
+        {{- with $group.Label }}
+        <span{{ with themeClass "radio" "text" }} class="{{ . }}"{{ end }}>{{ . }}</span>
+        {{- end }}
+        {{- range $i, $option := $group.Options }}
+        <label for="{{ $ID }}_{{ $g }}_{{ $i }}"{{ with themeClass "radio" "option" }} class="{{ . }}"{{ end }}>
+            <input type="radio" id="{{ $ID }}_{{ $g }}_{{ $i }}" name="{{ $name }}" value="{{ $option.Value }}"
+                {{- if $class }} class="{{ $class }}"{{ end }}
+                {{- if $option.Selected }} checked{{ end }}
+                {{- if $required }} required{{ end }}
+                {{- if or $disabled $option.Disabled }} disabled{{ end }}>
+            <span{{ with themeClass "radio" "text" }} class="{{ . }}"{{ end }}>{{ $option.Label }}</span>
         </label>
-        {{ end }}
+        {{- end }}
     </div>
+    {{- end }}
 </div>
 {{ end }}
 
 
 {{- block "button" . }}
 {{- $disabled := IsTrue .disabled }}
-{{ $type := .type }}
-{{ if not $type }}
-    {{ $type = "submit" }}
+{{- $type := .type }}
+{{- if not $type }}
+    {{- $type = "submit" }}
+{{- end }}
+
+{{- $variant := print (or .variant "primary") }}
+{{- if not (themeClass "button" $variant) }}
+    {{- $variant = "primary" }}
+{{- end }}
+
+{{- $class := .class }}
+{{- if not $class }}
+    {{- if $disabled }}
+        {{- $class = themeClass "button" "control" $variant "disabled" }}
+    {{- else }}
+        {{- $class = themeClass "button" "control" $variant }}
+    {{- end }}
+{{- end }}
+
+<button type="{{ $type }}"{{ if .id }} id="{{ .id }}"{{ end }}
+        {{- if $class }} class="{{ $class }}"{{ end }}
+        {{- if $disabled }} disabled{{ end }}>{{ .text }}</button>
 {{ end }}
 
-{{- $variant := .variant }}
-{{- $class := "secondary" }}
-
-{{- if eq $variant "primary" }}
-    {{ $class = "whitespace-nowrap inline-flex items-center px-4 py-2 border border-transparent text-base font-medium rounded-md shadow-sm text-white bg-indigo-600 hover:bg-indigo-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-indigo-500" }}
-{{- else if eq $variant "secondary" }}
-    {{ $class = "whitespace-nowrap inline-flex items-center px-4 py-2 border border-gray-300 text-base font-medium rounded-md shadow-sm text-gray-700 bg-white hover:bg-gray-100 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-gray-500" }}
-{{- else if eq $variant "success" }}
-    {{ $class = "whitespace-nowrap inline-flex items-center px-4 py-2 border border-transparent text-base font-medium rounded-md shadow-sm text-white bg-green-600 hover:bg-green-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-green-500" }}
-{{- else if eq $variant "danger" }}
-    {{ $class = "whitespace-nowrap inline-flex items-center px-4 py-2 border border-transparent text-base font-medium rounded-md shadow-sm text-white bg-red-600 hover:bg-red-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-red-500" }}
-{{- else if eq $variant "warning" }}
-    {{ $class = "whitespace-nowrap inline-flex items-center px-4 py-2 border border-transparent text-base font-medium rounded-md shadow-sm text-white bg-yellow-600 hover:bg-yellow-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-yellow-500" }}
-{{- else if eq $variant "info" }}
-    {{ $class = "whitespace-nowrap inline-flex items-center px-4 py-2 border border-transparent text-base font-medium rounded-md shadow-sm text-white bg-sky-600 hover:bg-sky-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-sky-500" }}
-{{- else }}
-    {{ $class = "whitespace-nowrap inline-flex items-center px-4 py-2 border border-transparent text-base font-medium rounded-md shadow-sm text-white bg-indigo-600 hover:bg-indigo-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-indigo-500" }}
+{{- block "table" . }}
+{{- $table := .table }}
+<table{{ with themeClass "table" "table" }} class="{{ . }}"{{ end }}>
+    <thead{{ with themeClass "table" "head" }} class="{{ . }}"{{ end }}>
+        <tr>
+            {{- range $table.Columns }}
+            <th scope="col"{{ with themeClass "table" "header" }} class="{{ . }}"{{ end }}
+                {{- if eq .SortDir "asc" }} aria-sort="ascending"{{ else if eq .SortDir "desc" }} aria-sort="descending"{{ end }}>
+                {{- if .Sortable }}
+                <a href="{{ .SortURL }}"{{ with themeClass "table" "sort" }} class="{{ . }}"{{ end }}>{{ .Label }}
+                    {{- if eq .SortDir "asc" }} &#9650;{{ else if eq .SortDir "desc" }} &#9660;{{ end }}</a>
+                {{- else }}
+                {{- .Label }}
+                {{- end -}}
+            </th>
+            {{- end }}
+        </tr>
+    </thead>
+    <tbody>
+        {{- range $table.Rows }}
+        <tr{{ with themeClass "table" "row" }} class="{{ . }}"{{ end }}>
+            {{- range . }}
+            <td{{ with themeClass "table" "cell" }} class="{{ . }}"{{ end }}>{{ . }}</td>
+            {{- end }}
+        </tr>
+        {{- else }}
+        <tr>
+            <td colspan="{{ len $table.Columns }}"{{ with themeClass "table" "empty" }} class="{{ . }}"{{ end }}>{{ or .empty "No records found." }}</td>
+        </tr>
+        {{- end }}
+    </tbody>
+</table>
+{{ end }}
+
+{{- block "pagination" . }}
+{{- $p := .pagination }}
+{{- if gt $p.TotalPages 1 }}
+<nav aria-label="Pagination"{{ with themeClass "pagination" "nav" }} class="{{ . }}"{{ end }}>
+    <ul{{ with themeClass "pagination" "list" }} class="{{ . }}"{{ end }}>
+        {{- if $p.HasPrev }}
+        <li{{ with themeClass "pagination" "item" }} class="{{ . }}"{{ end }}><a href="{{ $p.PrevURL }}" rel="prev"{{ with themeClass "pagination" "link" }} class="{{ . }}"{{ end }}>Previous</a></li>
+        {{- else }}
+        <li{{ with themeClass "pagination" "item" "disabled" }} class="{{ . }}"{{ end }}><span{{ with themeClass "pagination" "link" }} class="{{ . }}"{{ end }}>Previous</span></li>
+        {{- end }}
+        {{- range $p.Pages }}
+        {{- if .Ellipsis }}
+        <li{{ with themeClass "pagination" "item" "disabled" }} class="{{ . }}"{{ end }}><span{{ with themeClass "pagination" "link" }} class="{{ . }}"{{ end }}>&hellip;</span></li>
+        {{- else if .Active }}
+        <li{{ with themeClass "pagination" "item" "active" }} class="{{ . }}"{{ end }}><a href="{{ .URL }}" aria-current="page"{{ with themeClass "pagination" "link" }} class="{{ . }}"{{ end }}>{{ .Number }}</a></li>
+        {{- else }}
+        <li{{ with themeClass "pagination" "item" }} class="{{ . }}"{{ end }}><a href="{{ .URL }}"{{ with themeClass "pagination" "link" }} class="{{ . }}"{{ end }}>{{ .Number }}</a></li>
+        {{- end }}
+        {{- end }}
+        {{- if $p.HasNext }}
+        <li{{ with themeClass "pagination" "item" }} class="{{ . }}"{{ end }}><a href="{{ $p.NextURL }}" rel="next"{{ with themeClass "pagination" "link" }} class="{{ . }}"{{ end }}>Next</a></li>
+        {{- else }}
+        <li{{ with themeClass "pagination" "item" "disabled" }} class="{{ . }}"{{ end }}><span{{ with themeClass "pagination" "link" }} class="{{ . }}"{{ end }}>Next</span></li>
+        {{- end }}
+    </ul>
+</nav>
+{{- end }}
 {{ end }}
 
-<button type="{{ $type }}" {{ if .id }} id="{{ .id }}"{{ end }}
-        class="{{ $class }} {{ if $disabled }}opacity-50 cursor-not-allowed{{ end }}"
-        {{ if $disabled }}disabled{{ end }}>
-    {{ .text }}
-</button>
+{{- block "alert" . }}
+{{- $kind := print (or .kind "info") }}
+<div role="alert"{{ with themeClass "alert" "alert" $kind }} class="{{ . }}"{{ end }}>
+    {{ .message }}
+    {{- if IsTrue .dismissible }}
+    <button type="button" aria-label="Close" data-gor-dismiss{{ with themeClass "alert" "close" }} class="{{ . }}"{{ end }}>&times;</button>
+    {{- end }}
+</div>
 {{ end }}
 
+{{- block "alerts" . }}
+{{- range .alerts }}
+{{- template "alert" Props "kind" .Kind "message" .Message "dismissible" .Dismissible }}
+{{- end }}
+{{ end }}
 
+{{- block "modal" . }}
+<dialog id="{{ .id }}"{{ with themeClass "modal" "dialog" }} class="{{ . }}"{{ end }}>
+    <form method="{{ if .action }}post{{ else }}dialog{{ end }}"{{ with .action }} action="{{ . }}"{{ end }}{{ with themeClass "modal" "content" }} class="{{ . }}"{{ end }}>
+        {{- with .csrf }}
+        {{ template "csrf" Props "token" . }}
+        {{- end }}
+        <header{{ with themeClass "modal" "header" }} class="{{ . }}"{{ end }}>
+            <h2{{ with themeClass "modal" "title" }} class="{{ . }}"{{ end }}>{{ .title }}</h2>
+        </header>
+        <div{{ with themeClass "modal" "body" }} class="{{ . }}"{{ end }}>{{ .body }}</div>
+        <footer{{ with themeClass "modal" "footer" }} class="{{ . }}"{{ end }}>
+            <button type="submit" formmethod="dialog" formnovalidate{{ with themeClass "modal" "cancel" }} class="{{ . }}"{{ end }}>{{ or .cancel "Cancel" }}</button>
+            <button type="submit"{{ with themeClass "modal" "confirm" }} class="{{ . }}"{{ end }}>{{ or .confirm "OK" }}</button>
+        </footer>
+    </form>
+</dialog>
+{{ end }}
+
+{{- block "breadcrumbs" . }}
+<nav aria-label="Breadcrumb"{{ with themeClass "breadcrumbs" "nav" }} class="{{ . }}"{{ end }}>
+    <ol{{ with themeClass "breadcrumbs" "list" }} class="{{ . }}"{{ end }}>
+        {{- range .crumbs }}
+        {{- if .URL }}
+        <li{{ with themeClass "breadcrumbs" "item" }} class="{{ . }}"{{ end }}><a href="{{ .URL }}"{{ with themeClass "breadcrumbs" "link" }} class="{{ . }}"{{ end }}>{{ .Label }}</a></li>
+        {{- else }}
+        <li aria-current="page"{{ with themeClass "breadcrumbs" "item" "active" }} class="{{ . }}"{{ end }}>{{ .Label }}</li>
+        {{- end }}
+        {{- end }}
+    </ol>
+</nav>
+{{ end }}
 
+{{- block "csrf" . }}
+<input type="hidden" name="{{ or .name "csrf_token" }}" value="{{ .token }}">
+{{ end }}
+
+{{- block "gor_scripts" . }}
+<script{{ with .nonce }} nonce="{{ . }}"{{ end }}>
+document.addEventListener("click", function (e) {
+    var el = e.target.closest("[data-gor-dismiss], [data-gor-open]");
+    if (!el) return;
+    if (el.hasAttribute("data-gor-open")) {
+        document.getElementById(el.getAttribute("data-gor-open")).showModal();
+    } else {
+        el.closest("[role=alert]").remove();
+    }
+});
+</script>
+{{ end }}
+
+{{- block "field_error" . }}
+{{- with .error }}
+    <p{{ with themeClass "form" "error" }} class="{{ . }}"{{ end }}>{{ . }}</p>
+{{- end }}
+{{- end }}
+
+{{- define "gor_form" }}
+<form method="{{ .Options.Method }}"
+{{- with .Options.Action }} action="{{ . }}"{{ end }}
+{{- with .Options.ID }} id="{{ . }}"{{ end }}
+{{- with .Options.Enctype }} enctype="{{ . }}"{{ end }}
+{{- with themeClass "form" "form" }} class="{{ . }}"{{ end }}>
+{{- with .Options.CSRFToken }}
+{{- template "csrf" Props "token" . "name" $.Options.CSRFField }}
+{{- end }}
+{{- with .Error }}
+<div{{ with themeClass "form" "alert" }} class="{{ . }}"{{ end }} role="alert">{{ . }}</div>
+{{- end }}
+{{- range .Fields }}
+{{- if eq .type "hidden" }}
+<input type="hidden" id="{{ .id }}" name="{{ .name }}" value="{{ .value }}">
+{{- else if eq .component "checkbox" }}
+{{- template "checkbox" . }}
+{{- else if eq .component "select" }}
+{{- template "select" . }}
+{{- else if eq .component "textarea" }}
+{{- template "textarea" . }}
+{{- else }}
+{{- template "input" . }}
+{{- end }}
+{{- end }}
+{{- template "button" Props "text" .Options.Submit }}</form>
+{{- end }}
 `
@@ -0,0 +1,386 @@
+package gor
+
+import (
+	"html/template"
+	"strings"
+)
+
+// ComponentTheme maps the slots of a component to their CSS classes.
+//
+// Slots used by the built-in components:
+//
+//	wrapper:  element wrapping the label and the control.
+//	label:    the label(or legend of radio buttons).
+//	control:  the input, select, textarea or button element.
+//	group:    radio only. Element wrapping the options.
+//	option:   checkbox and radio. Element wrapping each option.
+//	text:     checkbox and radio. Text next to each option.
+//	disabled: button only. Added when the button is disabled.
+//	invalid:  added to the control of generated forms when the field has an error.
+//
+// Forms generated with RenderForm also use the "form" component with the slots
+// "form"(the form element), "error"(message below an invalid field) and
+// "alert"(errors that do not belong to a field).
+//
+// Buttons also use a slot per variant("primary", "secondary", "success",
+// "danger", "warning", "info") that is added to the control class.
+type ComponentTheme map[string]string
+
+// Theme supplies the CSS classes of the built-in form components and, when the
+// CSS framework expects another structure, their markup.
+// Select a theme when parsing templates:
+//
+//	t, err := gor.ParseTemplatesRecursive("templates", gor.BootstrapTheme.Funcs(template.FuncMap{}))
+//
+// The default theme is TailwindTheme.
+type Theme struct {
+	Name       string
+	Components map[string]ComponentTheme
+
+	// Templates replace the markup of components, by component name(e.g "checkbox").
+	// They receive the same props as the built-in components and are parsed after them,
+	// so templates parsed with ParseTemplatesRecursive can still override them.
+	Templates map[string]string
+}
+
+// With returns a copy of the theme with the classes of component replaced.
+// Slots missing in classes keep their value in the theme.
+//
+//	theme := gor.BootstrapTheme.With("button", gor.ComponentTheme{"control": "btn btn-lg"})
+func (t *Theme) With(component string, classes ComponentTheme) *Theme {
+	clone := t.clone()
+
+	merged := make(ComponentTheme, len(t.Components[component])+len(classes))
+	for slot, class := range t.Components[component] {
+		merged[slot] = class
+	}
+	for slot, class := range classes {
+		merged[slot] = class
+	}
+	clone.Components[component] = merged
+	return clone
+}
+
+// WithTemplate returns a copy of the theme with the markup of component replaced by text.
+// See Theme.Templates.
+//
+//	theme := gor.TailwindTheme.WithTemplate("checkbox", `<label>...</label>`)
+func (t *Theme) WithTemplate(component, text string) *Theme {
+	clone := t.clone()
+	clone.Templates[component] = text
+	return clone
+}
+
+// clone returns a copy of the theme whose maps can be modified.
+func (t *Theme) clone() *Theme {
+	clone := &Theme{
+		Name:       t.Name,
+		Components: make(map[string]ComponentTheme, len(t.Components)+1),
+		Templates:  make(map[string]string, len(t.Templates)+1),
+	}
+
+	for name, slots := range t.Components {
+		clone.Components[name] = slots
+	}
+
+	for name, text := range t.Templates {
+		clone.Templates[name] = text
+	}
+	return clone
+}
+
+// Class returns the classes of a slot of component, joined with a space
+// when several slots are given. Empty classes are skipped.
+func (t *Theme) Class(component string, slots ...string) string {
+	c := t.Components[component]
+
+	classes := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		if class := c[slot]; class != "" {
+			classes = append(classes, class)
+		}
+	}
+	return strings.Join(classes, " ")
+}
+
+// Funcs adds the template functions used by the built-in components to funcMap
+// and returns it. Pass the result to ParseTemplatesRecursive to use the theme:
+//
+//	theme:      returns the theme.
+//	themeClass: returns the classes of a component slot. See Theme.Class.
+//	form:       generates a form from a struct. See RenderForm.
+func (t *Theme) Funcs(funcMap template.FuncMap) template.FuncMap {
+	if funcMap == nil {
+		funcMap = template.FuncMap{}
+	}
+	funcMap["theme"] = func() *Theme { return t }
+	funcMap["themeClass"] = t.Class
+	funcMap["form"] = t.formFunc
+	return funcMap
+}
+
+// addThemeFuncs adds the default theme functions unless a theme was selected.
+func addThemeFuncs(funcMap template.FuncMap) {
+	if _, ok := funcMap["themeClass"]; !ok {
+		TailwindTheme.Funcs(funcMap)
+	}
+}
+
+const (
+	tailwindControl = "py-2 px-3 mt-1 block w-full rounded-md border border-gray-300 shadow-sm focus:border-indigo-300 focus:ring focus:ring-indigo-200 focus:ring-opacity-50"
+	tailwindLabel   = "block text-base font-medium text-gray-800 mb-1"
+	tailwindButton  = "whitespace-nowrap inline-flex items-center px-4 py-2 border text-base font-medium rounded-md shadow-sm focus:outline-none focus:ring-2 focus:ring-offset-2"
+)
+
+// TailwindTheme styles the components with Tailwind CSS utility classes.
+var TailwindTheme = &Theme{
+	Name: "tailwind",
+	Components: map[string]ComponentTheme{
+		"form": {
+			"error": "mt-1 text-sm text-red-600",
+			"alert": "mb-4 rounded-md border border-red-200 bg-red-50 p-3 text-sm text-red-700",
+		},
+		"input": {
+			"wrapper": "mb-4",
+			"label":   tailwindLabel,
+			"control": tailwindControl,
+			"invalid": "border-red-500 focus:border-red-500 focus:ring-red-200",
+		},
+		"textarea": {
+			"wrapper": "mb-4",
+			"label":   tailwindLabel,
+			"control": tailwindControl,
+			"invalid": "border-red-500 focus:border-red-500 focus:ring-red-200",
+		},
+		"select": {
+			"wrapper": "mb-4",
+			"label":   tailwindLabel,
+			"control": tailwindControl + " bg-white",
+			"invalid": "border-red-500 focus:border-red-500 focus:ring-red-200",
+		},
+		"checkbox": {
+			"wrapper": "mb-4",
+			"option":  "inline-flex items-center gap-2",
+			"control": "py-2 px-3 rounded border border-gray-300 text-indigo-600 shadow-sm focus:border-indigo-300 focus:ring focus:ring-indigo-200 focus:ring-opacity-50",
+			"text":    "ml-2 text-base text-gray-800",
+			"invalid": "border-red-500 focus:border-red-500 focus:ring-red-200",
+		},
+		"radio": {
+			"wrapper": "mb-4",
+			"label":   tailwindLabel,
+			"group":   "mt-1 space-y-2",
+			"option":  "inline-flex items-center",
+			"control": "border border-gray-300 text-indigo-600 shadow-sm focus:border-indigo-300 focus:ring focus:ring-indigo-200 focus:ring-opacity-50",
+			"text":    "ml-2 text-base text-gray-800",
+		},
+		"table": {
+			"table":  "min-w-full divide-y divide-gray-200 text-left text-sm",
+			"head":   "bg-gray-50",
+			"header": "px-4 py-2 font-semibold text-gray-700",
+			"sort":   "hover:underline",
+			"row":    "border-b border-gray-100",
+			"cell":   "px-4 py-2 text-gray-800",
+			"empty":  "px-4 py-6 text-center text-gray-500",
+		},
+		"pagination": {
+			"list":     "inline-flex items-center gap-1",
+			"link":     "block rounded-md border border-gray-300 px-3 py-1 text-sm text-gray-700 hover:bg-gray-100",
+			"active":   "font-semibold",
+			"disabled": "opacity-50 cursor-not-allowed",
+		},
+		"alert": {
+			"alert":   "mb-4 flex items-start justify-between rounded-md border p-3 text-sm",
+			"info":    "border-sky-200 bg-sky-50 text-sky-800",
+			"success": "border-green-200 bg-green-50 text-green-800",
+			"warning": "border-yellow-200 bg-yellow-50 text-yellow-800",
+			"danger":  "border-red-200 bg-red-50 text-red-800",
+			"close":   "ml-4 font-bold",
+		},
+		"modal": {
+			"dialog":  "rounded-lg p-0 shadow-xl backdrop:bg-black/50",
+			"content": "w-full max-w-lg",
+			"header":  "border-b border-gray-200 px-6 py-4",
+			"title":   "text-lg font-semibold text-gray-900",
+			"body":    "px-6 py-4 text-gray-700",
+			"footer":  "flex justify-end gap-2 border-t border-gray-200 px-6 py-4",
+			"cancel":  tailwindButton + " border-gray-300 text-gray-700 bg-white hover:bg-gray-100 focus:ring-gray-500",
+			"confirm": tailwindButton + " border-transparent text-white bg-indigo-600 hover:bg-indigo-700 focus:ring-indigo-500",
+		},
+		"breadcrumbs": {
+			"list":   "flex items-center gap-2 text-sm text-gray-500",
+			"link":   "hover:underline",
+			"active": "font-medium text-gray-800",
+		},
+		"button": {
+			"control":   tailwindButton,
+			"primary":   "border-transparent text-white bg-indigo-600 hover:bg-indigo-700 focus:ring-indigo-500",
+			"secondary": "border-gray-300 text-gray-700 bg-white hover:bg-gray-100 focus:ring-gray-500",
+			"success":   "border-transparent text-white bg-green-600 hover:bg-green-700 focus:ring-green-500",
+			"danger":    "border-transparent text-white bg-red-600 hover:bg-red-700 focus:ring-red-500",
+			"warning":   "border-transparent text-white bg-yellow-600 hover:bg-yellow-700 focus:ring-yellow-500",
+			"info":      "border-transparent text-white bg-sky-600 hover:bg-sky-700 focus:ring-sky-500",
+			"disabled":  "opacity-50 cursor-not-allowed",
+		},
+	},
+}
+
+// BootstrapTheme styles the components with Bootstrap 5 classes.
+var BootstrapTheme = &Theme{
+	Name: "bootstrap",
+	Components: map[string]ComponentTheme{
+		"form": {
+			"error": "invalid-feedback d-block",
+			"alert": "alert alert-danger",
+		},
+		"input": {
+			"wrapper": "mb-3",
+			"label":   "form-label",
+			"control": "form-control",
+			"invalid": "is-invalid",
+		},
+		"textarea": {
+			"wrapper": "mb-3",
+			"label":   "form-label",
+			"control": "form-control",
+			"invalid": "is-invalid",
+		},
+		"select": {
+			"wrapper": "mb-3",
+			"label":   "form-label",
+			"control": "form-select",
+			"invalid": "is-invalid",
+		},
+		"checkbox": {
+			"wrapper": "mb-3 form-check",
+			"control": "form-check-input",
+			"text":    "form-check-label",
+			"invalid": "is-invalid",
+		},
+		"radio": {
+			"wrapper": "mb-3",
+			"label":   "form-label d-block",
+			"option":  "form-check form-check-inline",
+			"control": "form-check-input",
+			"text":    "form-check-label",
+		},
+		"table": {
+			"table": "table table-striped",
+			"empty": "text-center text-muted",
+		},
+		"pagination": {
+			"list":     "pagination",
+			"item":     "page-item",
+			"link":     "page-link",
+			"active":   "active",
+			"disabled": "disabled",
+		},
+		"alert": {
+			"alert":   "alert",
+			"info":    "alert-info",
+			"success": "alert-success",
+			"warning": "alert-warning",
+			"danger":  "alert-danger",
+			"close":   "btn-close",
+		},
+		"modal": {
+			"dialog":  "border-0 rounded p-0 shadow",
+			"content": "modal-content",
+			"header":  "modal-header",
+			"title":   "modal-title fs-5",
+			"body":    "modal-body",
+			"footer":  "modal-footer",
+			"cancel":  "btn btn-secondary",
+			"confirm": "btn btn-primary",
+		},
+		"breadcrumbs": {
+			"list":   "breadcrumb",
+			"item":   "breadcrumb-item",
+			"active": "active",
+		},
+		"button": {
+			"control":   "btn",
+			"primary":   "btn-primary",
+			"secondary": "btn-secondary",
+			"success":   "btn-success",
+			"danger":    "btn-danger",
+			"warning":   "btn-warning",
+			"info":      "btn-info",
+			"disabled":  "disabled",
+		},
+	},
+	Templates: map[string]string{
+		"checkbox": bootstrapCheckbox,
+		"radio":    bootstrapRadio,
+	},
+}
+
+// ClasslessTheme renders the components without classes, for classless CSS
+// frameworks(e.g Pico CSS, Water.css) or hand written stylesheets that style elements.
+var ClasslessTheme = &Theme{
+	Name:       "classless",
+	Components: map[string]ComponentTheme{},
+}
+
+// Bootstrap places the input before its label in a form-check element:
+// https://getbootstrap.com/docs/5.3/forms/checks-radios/.
+const bootstrapCheckbox = `
+{{- $ID := .id }}
+{{- if not $ID }}
+{{- $ID = .name }}
+{{- end }}
+{{- $class := .class }}
+{{- if not $class }}
+{{- if .error }}
+{{- $class = themeClass "checkbox" "control" "invalid" }}
+{{- else }}
+{{- $class = themeClass "checkbox" "control" }}
+{{- end }}
+{{- end }}
+
+<div{{ with themeClass "checkbox" "wrapper" }} class="{{ . }}"{{ end }}>
+    <input type="checkbox" id="{{ $ID }}" name="{{ .name }}" value="{{ .value }}"
+           {{- if $class }} class="{{ $class }}"{{ end }}
+           {{- if IsTrue .checked }} checked{{ end }}
+           {{- if IsTrue .required }} required{{ end }}
+           {{- if IsTrue .readonly }} readonly{{ end }}
+           {{- if IsTrue .disabled }} disabled{{ end }}
+           {{- if .error }} aria-invalid="true"{{ end }}>
+    <label for="{{ $ID }}"{{ with themeClass "checkbox" "text" }} class="{{ . }}"{{ end }}>{{ .label }}</label>
+    {{- template "field_error" . }}
+</div>
+`
+
+const bootstrapRadio = `
+{{- $ID := .id }}
+{{- if not $ID }}
+{{- $ID = .name }}
+{{- end }}
+{{- $name := .name }}
+{{- $disabled := IsTrue .disabled }}
+{{- $required := IsTrue .required }}
+{{- $class := .class }}
+{{- if not $class }}
+{{- $class = themeClass "radio" "control" }}
+{{- end }}
+
+<div{{ with themeClass "radio" "wrapper" }} class="{{ . }}"{{ end }}>
+    <span{{ with themeClass "radio" "label" }} class="{{ . }}"{{ end }}>{{ .label }}</span>
+    {{- range $g, $group := SelectOptions .options .value }}
+    <div{{ with themeClass "radio" "group" }} class="{{ . }}"{{ end }} role="radiogroup"{{ with $group.Label }} aria-label="{{ . }}"{{ end }}>
+        {{- with $group.Label }}
+        <span{{ with themeClass "radio" "label" }} class="{{ . }}"{{ end }}>{{ . }}</span>
+        {{- end }}
+        {{- range $i, $option := $group.Options }}
+        <div{{ with themeClass "radio" "option" }} class="{{ . }}"{{ end }}>
+            <input type="radio" id="{{ $ID }}_{{ $g }}_{{ $i }}" name="{{ $name }}" value="{{ $option.Value }}"
+                {{- if $class }} class="{{ $class }}"{{ end }}
+                {{- if $option.Selected }} checked{{ end }}
+                {{- if $required }} required{{ end }}
+                {{- if or $disabled $option.Disabled }} disabled{{ end }}>
+            <label for="{{ $ID }}_{{ $g }}_{{ $i }}"{{ with themeClass "radio" "text" }} class="{{ . }}"{{ end }}>{{ $option.Label }}</label>
+        </div>
+        {{- end }}
+    </div>
+    {{- end }}
+</div>
+`
@@ -0,0 +1,119 @@
+package gor_test
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+func renderThemedForm(t *testing.T, funcMap template.FuncMap) string {
+	t.Helper()
+
+	templ, err := gor.ParseTemplatesRecursive("testdata/themes", funcMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := templ.ExecuteTemplate(&buf, "form.html", nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestThemes(t *testing.T) {
+	tests := []struct {
+		name     string
+		funcMap  template.FuncMap
+		contains []string
+		excludes []string
+	}{
+		{
+			name:    "default is tailwind",
+			funcMap: template.FuncMap{},
+			contains: []string{
+				`<div class="mb-4">`,
+				`class="` + gor.TailwindTheme.Class("input", "control") + `"`,
+				gor.TailwindTheme.Class("button", "danger"),
+				gor.TailwindTheme.Class("button", "primary", "disabled"),
+			},
+		},
+		{
+			name:    "bootstrap",
+			funcMap: gor.BootstrapTheme.Funcs(template.FuncMap{}),
+			contains: []string{
+				`<div class="mb-3">`,
+				`<label for="email" class="form-label">Email</label>`,
+				`class="form-control"`,
+				`class="btn btn-danger"`,
+				`class="btn btn-primary disabled"`,
+				"<div class=\"mb-3 form-check\">\n    <input type=\"checkbox\" id=\"agree\" name=\"agree\" value=\"true\" class=\"form-check-input\">\n" +
+					`    <label for="agree" class="form-check-label">I agree</label>`,
+			},
+			excludes: []string{"rounded-md"},
+		},
+		{
+			name:     "classless",
+			funcMap:  gor.ClasslessTheme.Funcs(template.FuncMap{}),
+			contains: []string{`<div>`, `<label for="email">Email</label>`},
+			excludes: []string{"class="},
+		},
+		{
+			name: "component override",
+			funcMap: gor.BootstrapTheme.
+				With("button", gor.ComponentTheme{"control": "btn btn-lg"}).
+				Funcs(template.FuncMap{}),
+			contains: []string{`class="form-control"`, `class="btn btn-lg btn-danger"`},
+		},
+		{
+			name: "template override",
+			funcMap: gor.ClasslessTheme.
+				WithTemplate("checkbox", `<label><input type="checkbox" name="{{ .name }}"> {{ .label }}</label>`).
+				Funcs(template.FuncMap{}),
+			contains: []string{`<label><input type="checkbox" name="agree"> I agree</label>`, `<label for="email">Email</label>`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := renderThemedForm(t, tt.funcMap)
+
+			for _, want := range tt.contains {
+				if !strings.Contains(html, want) {
+					t.Errorf("expected %q in:\n%s", want, html)
+				}
+			}
+
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(html, unwanted) {
+					t.Errorf("unexpected %q in:\n%s", unwanted, html)
+				}
+			}
+		})
+	}
+
+	// With and WithTemplate do not modify the original theme.
+	if class := gor.BootstrapTheme.Class("button", "control"); class != "btn" {
+		t.Errorf("expected BootstrapTheme to be unchanged, got %q", class)
+	}
+
+	if len(gor.ClasslessTheme.Templates) != 0 {
+		t.Errorf("expected ClasslessTheme to be unchanged, got %v", gor.ClasslessTheme.Templates)
+	}
+}
+
+func TestThemeTemplatesInForms(t *testing.T) {
+	theme := gor.ClasslessTheme.WithTemplate("input", `<input name="{{ .name }}" data-theme="{{ theme.Name }}">`)
+
+	html, err := gor.RenderForm(signupForm{}, gor.FormOptions{Theme: theme})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `<input name="email" data-theme="classless">`; !strings.Contains(string(html), want) {
+		t.Errorf("expected %s in:\n%s", want, html)
+	}
+}
@@ -0,0 +1,224 @@
+package gor
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"text/template/parse"
+)
+
+// ErrTemplateNotFound is returned when rendering a template or layout that does not exist.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// templateNotFound returns an error wrapping ErrTemplateNotFound for the named template.
+func templateNotFound(kind, name string) error {
+	return fmt.Errorf("gor: %s %q: %w", kind, name, ErrTemplateNotFound)
+}
+
+// ValidateTemplates checks the template configuration of the router so that
+// mistakes are caught at startup instead of at request time. It verifies that:
+//
+//   - the base layout, error template and layouts declared with LayoutParent exist.
+//   - each layout outputs the content block.
+//   - every {{ template "name" }} refers to a defined template. See CheckTemplateReferences.
+//
+// All problems found are returned together.
+//
+// Example:
+//
+//	r := gor.NewRouter(gor.WithTemplates(t), gor.BaseLayout("layouts/base.html"))
+//	if err := r.ValidateTemplates(); err != nil {
+//		log.Fatal(err)
+//	}
+func (r *Router) ValidateTemplates() error {
+	if err := r.reloadTemplates(); err != nil {
+		return err
+	}
+
+	t := r.templates()
+	if t == nil {
+		return errors.New("gor: no template is configured")
+	}
+
+	var errs []error
+
+	layouts := make(map[string]bool)
+	if r.baseLayout != "" && r.baseLayout != NoLayout {
+		layouts[r.baseLayout] = true
+	}
+
+	for layout, parent := range r.layoutParents {
+		layouts[layout] = true
+		if parent != "" && parent != NoLayout {
+			layouts[parent] = true
+		}
+	}
+
+	names := make([]string, 0, len(layouts))
+	for layout := range layouts {
+		names = append(names, layout)
+	}
+	sort.Strings(names)
+
+	for _, layout := range names {
+		lt := t.Lookup(layout)
+		if lt == nil {
+			errs = append(errs, templateNotFound("layout", layout))
+			continue
+		}
+
+		if r.contentBlock == "" {
+			errs = append(errs, fmt.Errorf("gor: layout %q is set but the content block is empty", layout))
+		} else if !usesField(lt, r.contentBlock) {
+			errs = append(errs, fmt.Errorf("gor: layout %q does not output the content block {{ .%s }}", layout, r.contentBlock))
+		}
+	}
+
+	if r.errorTemplate != "" && lookupPage(t, r.errorTemplate) == nil {
+		errs = append(errs, templateNotFound("error template", r.errorTemplate))
+	}
+
+	if err := CheckTemplateReferences(t); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// CheckTemplateReferences statically analyses all templates associated with t and
+// returns an error for every {{ template "name" }} action that refers to an
+// undefined template. Run it in tests or CI to catch typos before deploying.
+//
+//	t, _ := gor.ParseTemplatesRecursive("templates", template.FuncMap{})
+//	if err := gor.CheckTemplateReferences(t); err != nil {
+//		log.Fatal(err)
+//	}
+func CheckTemplateReferences(t *template.Template) error {
+	defined := make(map[string]bool)
+	for _, tmpl := range t.Templates() {
+		defined[tmpl.Name()] = true
+	}
+
+	// Fragments share their parse trees with the page, so the same
+	// reference can be seen more than once.
+	seen := make(map[string]bool)
+	var errs []error
+
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+
+		tree := tmpl.Tree
+		walkNodes(tree.Root, func(node parse.Node) {
+			ref, ok := node.(*parse.TemplateNode)
+			if !ok || defined[ref.Name] {
+				return
+			}
+
+			location, _ := tree.ErrorContext(ref)
+			if seen[location] {
+				return
+			}
+			seen[location] = true
+			errs = append(errs, fmt.Errorf("%s: template %q is not defined: %w", location, ref.Name, ErrTemplateNotFound))
+		})
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return errors.Join(errs...)
+}
+
+// lookupPage looks up the template name, adding the ".html" extension if missing.
+func lookupPage(t *template.Template, name string) *template.Template {
+	if filepath.Ext(name) == "" {
+		name = name + ".html"
+	}
+	return t.Lookup(name)
+}
+
+// usesField reports whether the template outputs the field .name directly,
+// or in one of the templates it includes.
+func usesField(t *template.Template, name string) bool {
+	visited := make(map[string]bool)
+
+	var check func(t *template.Template) bool
+	check = func(t *template.Template) bool {
+		if t == nil || t.Tree == nil || visited[t.Name()] {
+			return false
+		}
+		visited[t.Name()] = true
+
+		found := false
+		walkNodes(t.Tree.Root, func(node parse.Node) {
+			if found {
+				return
+			}
+
+			switch n := node.(type) {
+			case *parse.FieldNode:
+				found = len(n.Ident) > 0 && n.Ident[0] == name
+			case *parse.TemplateNode:
+				found = check(t.Lookup(n.Name))
+			}
+		})
+		return found
+	}
+	return check(t)
+}
+
+// walkNodes calls fn for node and every node below it.
+func walkNodes(node parse.Node, fn func(parse.Node)) {
+	if node == nil || isNilNode(node) {
+		return
+	}
+
+	fn(node)
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			walkNodes(child, fn)
+		}
+	case *parse.ActionNode:
+		walkNodes(n.Pipe, fn)
+	case *parse.PipeNode:
+		for _, cmd := range n.Cmds {
+			walkNodes(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkNodes(arg, fn)
+		}
+	case *parse.ChainNode:
+		walkNodes(n.Node, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkNodes(n.Pipe, fn)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(parse.Node)) {
+	walkNodes(n.Pipe, fn)
+	walkNodes(n.List, fn)
+	walkNodes(n.ElseList, fn)
+}
+
+// isNilNode reports whether node is a typed nil pointer, e.g a missing else list.
+func isNilNode(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		return n == nil
+	case *parse.PipeNode:
+		return n == nil
+	}
+	return false
+}
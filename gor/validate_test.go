@@ -0,0 +1,91 @@
+package gor_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	for _, dir := range []string{"testdata/fragments", "testdata/layouts"} {
+		templ, err := gor.ParseTemplatesRecursive(dir, template.FuncMap{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := gor.CheckTemplateReferences(templ); err != nil {
+			t.Errorf("%s: unexpected error: %v", dir, err)
+		}
+	}
+
+	templ, err := gor.ParseTemplatesRecursive("testdata/validate", template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = gor.CheckTemplateReferences(templ)
+	if !errors.Is(err, gor.ErrTemplateNotFound) {
+		t.Fatalf("expected ErrTemplateNotFound, got %v", err)
+	}
+
+	if want := `pages/home.html:3:`; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected the error to contain the location %q, got %q", want, err)
+	}
+
+	r := gor.NewRouter(
+		gor.WithTemplates(templ),
+		gor.BaseLayout("layouts/base.html"),
+		gor.LayoutParent("layouts/empty.html", "layouts/missing.html"),
+		gor.ErrorTemplate("errors/500.html"),
+	)
+
+	err = r.ValidateTemplates()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		`layout "layouts/missing.html"`,
+		`layout "layouts/empty.html" does not output the content block {{ .Content }}`,
+		`error template "errors/500.html"`,
+		`template "userCard" is not defined`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in %q", want, msg)
+		}
+	}
+
+	// The base layout outputs the content block through the "nav" template.
+	if strings.Contains(msg, `"layouts/base.html"`) {
+		t.Errorf("unexpected error for layouts/base.html: %q", msg)
+	}
+}
+
+func TestRenderMissingTemplate(t *testing.T) {
+	templ, err := gor.ParseTemplatesRecursive("testdata/layouts", template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gor.NewRouter(gor.WithTemplates(templ), gor.BaseLayout("layouts/root.html"))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		gor.Render(w, req, "pages/userz", gor.Map{})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+
+	if want := `gor: template "pages/userz.html": template not found`; w.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, w.Body.String())
+	}
+}
@@ -0,0 +1,253 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Hub keeps track of connections and broadcasts messages to them.
+// Connections can optionally join rooms to receive room specific broadcasts.
+//
+// Every connection gets its own send queue and writer goroutine so that
+// a slow client does not block the others. Clients whose queue is full are
+// disconnected with ClosePolicyViolation.
+//
+//	hub := websocket.NewHub()
+//
+//	websocket.Route(r, "/chat", func(conn *websocket.Conn, req *http.Request) {
+//		hub.Add(conn)
+//		defer hub.Remove(conn)
+//
+//		for {
+//			mt, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			hub.Broadcast(mt, msg)
+//		}
+//	})
+type Hub struct {
+	// Number of messages queued per connection. Default is 64.
+	QueueSize int
+
+	// Write deadline for a single message. Default is 10 seconds.
+	WriteTimeout time.Duration
+
+	mu      sync.RWMutex
+	clients map[*Conn]*hubClient
+	rooms   map[string]map[*Conn]struct{}
+}
+
+type hubMessage struct {
+	messageType MessageType
+	data        []byte
+}
+
+type hubClient struct {
+	conn  *Conn
+	send  chan hubMessage
+	rooms map[string]struct{}
+	done  chan struct{}
+}
+
+// NewHub creates an empty hub.
+func NewHub() *Hub {
+	return &Hub{
+		QueueSize:    64,
+		WriteTimeout: 10 * time.Second,
+		clients:      make(map[*Conn]*hubClient),
+		rooms:        make(map[string]map[*Conn]struct{}),
+	}
+}
+
+// Add registers the connection with the hub and joins the given rooms.
+func (h *Hub) Add(conn *Conn, rooms ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client, ok := h.clients[conn]
+	if !ok {
+		client = &hubClient{
+			conn:  conn,
+			send:  make(chan hubMessage, h.QueueSize),
+			rooms: make(map[string]struct{}),
+			done:  make(chan struct{}),
+		}
+		h.clients[conn] = client
+		go h.writePump(client)
+	}
+
+	for _, room := range rooms {
+		h.join(client, room)
+	}
+}
+
+func (h *Hub) join(client *hubClient, room string) {
+	members, ok := h.rooms[room]
+	if !ok {
+		members = make(map[*Conn]struct{})
+		h.rooms[room] = members
+	}
+	members[client.conn] = struct{}{}
+	client.rooms[room] = struct{}{}
+}
+
+// Join adds a registered connection to a room.
+func (h *Hub) Join(conn *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if client, ok := h.clients[conn]; ok {
+		h.join(client, room)
+	}
+}
+
+// Leave removes the connection from a room.
+func (h *Hub) Leave(conn *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if client, ok := h.clients[conn]; ok {
+		h.leave(client, room)
+	}
+}
+
+func (h *Hub) leave(client *hubClient, room string) {
+	delete(client.rooms, room)
+	if members, ok := h.rooms[room]; ok {
+		delete(members, client.conn)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Remove unregisters the connection. It does not close it.
+func (h *Hub) Remove(conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(conn)
+}
+
+func (h *Hub) remove(conn *Conn) {
+	client, ok := h.clients[conn]
+	if !ok {
+		return
+	}
+
+	for room := range client.rooms {
+		h.leave(client, room)
+	}
+	delete(h.clients, conn)
+	close(client.done)
+}
+
+// Len returns the number of registered connections.
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// RoomLen returns the number of connections in a room.
+func (h *Hub) RoomLen(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast queues the message for all registered connections.
+func (h *Hub) Broadcast(messageType MessageType, data []byte) {
+	h.broadcast(messageType, data, "", nil)
+}
+
+// BroadcastTo queues the message for all connections in the room.
+func (h *Hub) BroadcastTo(room string, messageType MessageType, data []byte) {
+	h.broadcast(messageType, data, room, nil)
+}
+
+// BroadcastExcept queues the message for all registered connections except one,
+// typically the sender.
+func (h *Hub) BroadcastExcept(except *Conn, messageType MessageType, data []byte) {
+	h.broadcast(messageType, data, "", except)
+}
+
+// BroadcastJSON queues v encoded as JSON text for all registered connections.
+func (h *Hub) BroadcastJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h.Broadcast(TextMessage, b)
+	return nil
+}
+
+func (h *Hub) broadcast(messageType MessageType, data []byte, room string, except *Conn) {
+	msg := hubMessage{messageType: messageType, data: data}
+
+	var slow []*Conn
+
+	h.mu.RLock()
+	if room == "" {
+		for conn, client := range h.clients {
+			if conn != except && !client.enqueue(msg) {
+				slow = append(slow, conn)
+			}
+		}
+	} else {
+		for conn := range h.rooms[room] {
+			if conn != except && !h.clients[conn].enqueue(msg) {
+				slow = append(slow, conn)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	if len(slow) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	for _, conn := range slow {
+		h.remove(conn)
+	}
+	h.mu.Unlock()
+
+	for _, conn := range slow {
+		conn.WriteControl(CloseMessage, FormatCloseMessage(ClosePolicyViolation, "too slow"), time.Now().Add(time.Second))
+		conn.Close()
+	}
+}
+
+// enqueue adds the message to the send queue. It returns false if the queue is full.
+func (c *hubClient) enqueue(msg hubMessage) bool {
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *Hub) writePump(client *hubClient) {
+	for {
+		select {
+		case <-client.done:
+			return
+		case <-client.conn.Done():
+			h.Remove(client.conn)
+			return
+		case msg := <-client.send:
+			if h.WriteTimeout > 0 {
+				client.conn.SetWriteDeadline(time.Now().Add(h.WriteTimeout))
+			}
+
+			if err := client.conn.WriteMessage(msg.messageType, msg.data); err != nil {
+				h.Remove(client.conn)
+				client.conn.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,1176 @@
+/*
+Package websocket implements the WebSocket protocol(RFC 6455) on top of gor.ResponseWriter.Hijack.
+
+It supports the opening handshake with origin checks and subprotocol negotiation,
+text and binary messages, fragmentation, ping/pong keepalive, the close handshake
+and the permessage-deflate extension(RFC 7692). No external libraries are used.
+
+	upgrader := &websocket.Upgrader{EnableCompression: true}
+
+	upgrader.Route(r, "/ws", func(conn *websocket.Conn, req *http.Request) {
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(mt, msg)
+		}
+	})
+*/
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// MessageType is the type of a WebSocket message or frame opcode.
+type MessageType int
+
+// Message types defined in RFC 6455, section 11.8.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+	CloseMessage  MessageType = 8
+	PingMessage   MessageType = 9
+	PongMessage   MessageType = 10
+
+	continuationFrame MessageType = 0
+)
+
+// Close codes defined in RFC 6455, section 11.7.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	finBit  = 0x80
+	rsv1Bit = 0x40
+	rsv2Bit = 0x20
+	rsv3Bit = 0x10
+	maskBit = 0x80
+
+	maxControlPayload = 125
+
+	// Magic value used to compute Sec-WebSocket-Accept.
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	defaultBufferSize     = 4096
+	defaultReadLimit      = 32 << 20
+	defaultCloseTimeout   = 5 * time.Second
+	compressionThreshold  = 64
+	permessageDeflate     = "permessage-deflate"
+	deflateResponseParams = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+)
+
+var (
+	// ErrCloseSent is returned when writing a data message after a close frame has been sent.
+	ErrCloseSent = errors.New("websocket: close sent")
+
+	// ErrReadLimit is returned when a message exceeds the read limit.
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+
+	// ErrBadHandshake is returned when the opening handshake request is invalid.
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+
+	// The 4 bytes appended by a deflate sync flush, stripped from compressed messages.
+	deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+)
+
+// CloseError is returned by ReadMessage when the peer sends a close frame.
+type CloseError struct {
+	Code int    // Close code sent by the peer, CloseNoStatusReceived if none.
+	Text string // Close reason sent by the peer.
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// IsCloseError reports whether err is a *CloseError with one of the given codes.
+// If no codes are given, any close error matches.
+func IsCloseError(err error, codes ...int) bool {
+	var ce *CloseError
+	if !errors.As(err, &ce) {
+		return false
+	}
+
+	if len(codes) == 0 {
+		return true
+	}
+
+	for _, code := range codes {
+		if ce.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrader upgrades HTTP connections to WebSocket connections.
+// The zero value is ready to use.
+type Upgrader struct {
+	// Size of the read and write buffers. Default is 4096.
+	ReadBufferSize  int
+	WriteBufferSize int
+
+	// Supported subprotocols in order of preference.
+	// The first protocol requested by the client that is in this list is selected.
+	Subprotocols []string
+
+	// CheckOrigin returns true if the request Origin is acceptable.
+	// If nil, requests without an Origin header and same-origin requests are accepted.
+	CheckOrigin func(r *http.Request) bool
+
+	// Negotiate permessage-deflate compression with the client.
+	EnableCompression bool
+
+	// Maximum size in bytes of a message read from the peer. Default is 32MB.
+	ReadLimit int64
+}
+
+// DefaultUpgrader is used by the package level Route function.
+var DefaultUpgrader = &Upgrader{}
+
+// checkSameOrigin accepts requests without an Origin header and those whose Origin host matches the Host.
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// headerContainsToken reports whether the comma separated header contains token(case-insensitive).
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Upgrade upgrades the HTTP connection to the WebSocket protocol.
+// responseHeader is included in the 101 response, e.g to set cookies.
+//
+// On failure, Upgrade replies to the client with an HTTP error and returns the error.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	fail := func(status int, reason string) (*Conn, error) {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, http.StatusText(status), status)
+		return nil, fmt.Errorf("%w: %s", ErrBadHandshake, reason)
+	}
+
+	if r.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "request method is not GET")
+	}
+
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return fail(http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return fail(http.StatusUpgradeRequired, "unsupported version")
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+
+	if !checkOrigin(r) {
+		return fail(http.StatusForbidden, "origin not allowed")
+	}
+
+	key := strings.TrimSpace(r.Header.Get("Sec-WebSocket-Key"))
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid 'Sec-WebSocket-Key' header")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+	compress := u.EnableCompression && headerContainsExtension(r.Header, permessageDeflate)
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, err.Error())
+	}
+
+	// The server may have set deadlines(e.g WriteTimeout), clear them.
+	netConn.SetDeadline(time.Time{})
+
+	var resp bytes.Buffer
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	resp.WriteString("Upgrade: websocket\r\n")
+	resp.WriteString("Connection: Upgrade\r\n")
+	resp.WriteString("Sec-WebSocket-Accept: " + computeAcceptKey(key) + "\r\n")
+
+	if subprotocol != "" {
+		resp.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+
+	if compress {
+		resp.WriteString("Sec-WebSocket-Extensions: " + deflateResponseParams + "\r\n")
+	}
+
+	for k, values := range responseHeader {
+		if k == "Sec-Websocket-Protocol" || k == "Sec-Websocket-Extensions" {
+			continue
+		}
+		for _, v := range values {
+			resp.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+		}
+	}
+	resp.WriteString("\r\n")
+
+	if _, err := netConn.Write(resp.Bytes()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	br := brw.Reader
+	if u.ReadBufferSize > 0 && br.Buffered() == 0 {
+		br = bufio.NewReaderSize(netConn, u.ReadBufferSize)
+	}
+
+	conn := newConn(netConn, br, true, u.WriteBufferSize)
+	conn.subprotocol = subprotocol
+	conn.compressionNegotiated = compress
+	conn.compressWrites = compress
+	if u.ReadLimit > 0 {
+		conn.readLimit = u.ReadLimit
+	}
+	return conn, nil
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	var requested []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			requested = append(requested, strings.TrimSpace(p))
+		}
+	}
+
+	for _, supported := range u.Subprotocols {
+		for _, p := range requested {
+			if p == supported {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+func headerContainsExtension(h http.Header, name string) bool {
+	for _, v := range h.Values("Sec-WebSocket-Extensions") {
+		for _, ext := range strings.Split(v, ",") {
+			extName := strings.TrimSpace(strings.Split(ext, ";")[0])
+			if strings.EqualFold(extName, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsWebSocketUpgrade reports whether the request asks for a WebSocket upgrade.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// Handler is called with an upgraded connection.
+// When the Handler returns, the connection is closed with a close handshake
+// if it is still open.
+type Handler func(conn *Conn, req *http.Request)
+
+// Registrar is implemented by gor.Router and gor.Group.
+type Registrar interface {
+	Get(path string, handler http.HandlerFunc, middlewares ...gor.Middleware)
+}
+
+// HandlerFunc returns an http.HandlerFunc that upgrades the connection and calls h.
+func (u *Upgrader) HandlerFunc(h Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		conn, err := u.Upgrade(w, req, nil)
+		if err != nil {
+			return // Upgrade already replied with an error.
+		}
+
+		defer func() {
+			select {
+			case <-conn.Done():
+			default:
+				conn.Shutdown(CloseNormalClosure, "", time.Second)
+			}
+		}()
+
+		h(conn, req)
+	}
+}
+
+// Route registers a WebSocket route on a gor.Router or gor.Group.
+func (u *Upgrader) Route(r Registrar, path string, h Handler, middlewares ...gor.Middleware) {
+	r.Get(path, u.HandlerFunc(h), middlewares...)
+}
+
+// Route registers a WebSocket route using the DefaultUpgrader.
+func Route(r Registrar, path string, h Handler, middlewares ...gor.Middleware) {
+	DefaultUpgrader.Route(r, path, h, middlewares...)
+}
+
+// Conn is a WebSocket connection.
+//
+// Only one goroutine may read from a Conn at a time.
+// Writes are safe for concurrent use. Control frames(ping, pong, close) can be
+// written while a fragmented message is being written with NextWriter.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+
+	subprotocol           string
+	compressionNegotiated bool
+	compressWrites        bool
+	readLimit             int64
+
+	// dataMu serializes data messages, writeMu serializes frames.
+	dataMu     sync.Mutex
+	writeMu    sync.Mutex
+	bw         *bufio.Writer
+	writeBufSz int
+	closeSent  bool
+
+	// writeDeadline is the deadline set with SetWriteDeadline, restored
+	// after control frames written with their own deadline.
+	deadlineMu    sync.Mutex
+	writeDeadline time.Time
+
+	readMu      sync.Mutex
+	pingHandler func(appData string) error
+	pongHandler func(appData string) error
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newConn(netConn net.Conn, br *bufio.Reader, isServer bool, writeBufferSize int) *Conn {
+	if br == nil {
+		br = bufio.NewReaderSize(netConn, defaultBufferSize)
+	}
+
+	if writeBufferSize <= 0 {
+		writeBufferSize = defaultBufferSize
+	}
+
+	c := &Conn{
+		conn:       netConn,
+		br:         br,
+		isServer:   isServer,
+		readLimit:  defaultReadLimit,
+		bw:         bufio.NewWriterSize(netConn, writeBufferSize+maxFrameHeaderSize),
+		writeBufSz: writeBufferSize,
+		closed:     make(chan struct{}),
+	}
+
+	c.pingHandler = func(appData string) error {
+		err := c.WriteControl(PongMessage, []byte(appData), time.Now().Add(time.Second))
+		if errors.Is(err, ErrCloseSent) {
+			return nil
+		}
+		return err
+	}
+	c.pongHandler = func(string) error { return nil }
+	return c
+}
+
+// Subprotocol returns the negotiated subprotocol.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// CompressionNegotiated reports whether permessage-deflate was negotiated.
+func (c *Conn) CompressionNegotiated() bool {
+	return c.compressionNegotiated
+}
+
+// EnableWriteCompression enables or disables compression of written messages.
+// It has no effect if compression was not negotiated.
+func (c *Conn) EnableWriteCompression(enable bool) {
+	c.dataMu.Lock()
+	defer c.dataMu.Unlock()
+	c.compressWrites = enable && c.compressionNegotiated
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// NetConn returns the underlying network connection.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// SetReadDeadline sets the read deadline on the underlying connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline on the underlying connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	c.writeDeadline = t
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetReadLimit sets the maximum size in bytes of a message read from the peer.
+// If a message exceeds the limit, the connection is closed with CloseMessageTooBig.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPingHandler sets the handler for ping messages.
+// The default handler replies with a pong.
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler sets the handler for pong messages. The default handler does nothing.
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	c.pongHandler = h
+}
+
+// Done returns a channel that is closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.closed
+}
+
+// Close closes the underlying connection without sending a close frame.
+// Use Shutdown for a clean close handshake.
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// Shutdown performs the close handshake: it sends a close frame with code and reason,
+// waits up to timeout for the peer to reply with its close frame and closes the connection.
+// If timeout is 0, 5 seconds is used.
+func (c *Conn) Shutdown(code int, reason string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultCloseTimeout
+	}
+
+	err := c.WriteControl(CloseMessage, FormatCloseMessage(code, reason), time.Now().Add(timeout))
+	if err != nil && !errors.Is(err, ErrCloseSent) {
+		c.Close()
+		return err
+	}
+
+	// If no goroutine is reading, read until the peer's close frame arrives.
+	// Otherwise the reader will close the connection when it sees it.
+	if c.readMu.TryLock() {
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+		for {
+			if _, _, err := c.readMessage(); err != nil {
+				break
+			}
+		}
+		c.readMu.Unlock()
+	} else {
+		select {
+		case <-c.closed:
+		case <-time.After(timeout):
+		}
+	}
+	return c.Close()
+}
+
+// FormatCloseMessage formats code and text as the payload of a close frame.
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+// ==================== Writing ====================
+
+const maxFrameHeaderSize = 2 + 8 + 4
+
+// writeFrame writes a single frame. The caller must hold writeMu.
+func (c *Conn) writeFrame(fin bool, rsv1 bool, opcode MessageType, payload []byte) error {
+	var header [maxFrameHeaderSize]byte
+
+	b0 := byte(opcode)
+	if fin {
+		b0 |= finBit
+	}
+
+	if rsv1 {
+		b0 |= rsv1Bit
+	}
+	header[0] = b0
+
+	n := 2
+	length := len(payload)
+	switch {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+		n += 2
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+		n += 8
+	}
+
+	// Clients must mask all frames(section 5.3).
+	var key [4]byte
+	if !c.isServer {
+		header[1] |= maskBit
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		copy(header[n:], key[:])
+		n += 4
+	}
+
+	if _, err := c.bw.Write(header[:n]); err != nil {
+		return err
+	}
+
+	if c.isServer {
+		if _, err := c.bw.Write(payload); err != nil {
+			return err
+		}
+	} else {
+		masked := make([]byte, len(payload))
+		copy(masked, payload)
+		maskBytes(key, 0, masked)
+		if _, err := c.bw.Write(masked); err != nil {
+			return err
+		}
+	}
+	return c.bw.Flush()
+}
+
+// WriteControl writes a control message(close, ping or pong) with the given deadline.
+// A zero deadline keeps the deadline set with SetWriteDeadline, which is restored
+// after the message is written.
+func (c *Conn) WriteControl(messageType MessageType, data []byte, deadline time.Time) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("websocket: invalid control message type %d", messageType)
+	}
+
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload too large")
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	if !deadline.IsZero() {
+		c.conn.SetWriteDeadline(deadline)
+		defer c.restoreWriteDeadline()
+	}
+
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	return c.writeFrame(true, false, messageType, data)
+}
+
+func (c *Conn) restoreWriteDeadline() {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+	c.conn.SetWriteDeadline(c.writeDeadline)
+}
+
+// Ping sends a ping with the given application data.
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteControl(PingMessage, data, time.Now().Add(10*time.Second))
+}
+
+// WriteMessage writes a text or binary message.
+// If compression is enabled, messages larger than 64 bytes are compressed.
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return c.WriteControl(messageType, data, time.Time{})
+	}
+
+	c.dataMu.Lock()
+	defer c.dataMu.Unlock()
+
+	compress := c.compressWrites && len(data) >= compressionThreshold
+	if compress {
+		var err error
+		data, err = compressMessage(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	return c.writeFrame(true, compress, messageType, data)
+}
+
+// WriteJSON writes v as a JSON encoded text message.
+func (c *Conn) WriteJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, b)
+}
+
+// NextWriter returns a writer for the next message. The message is sent as a
+// sequence of fragments of at most the write buffer size and is completed when
+// the writer is closed. Other data messages are blocked until the writer is closed.
+func (c *Conn) NextWriter(messageType MessageType) (io.WriteCloser, error) {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return nil, fmt.Errorf("websocket: invalid data message type %d", messageType)
+	}
+
+	c.dataMu.Lock()
+	mw := &messageWriter{c: c, opcode: messageType, compress: c.compressWrites}
+	mw.frames = &fragmentWriter{mw: mw}
+
+	if mw.compress {
+		fw := flateWriterPool.Get().(*flate.Writer)
+		fw.Reset(&mw.trunc)
+		mw.trunc.w = mw.frames
+		mw.flate = fw
+	}
+	return mw, nil
+}
+
+type messageWriter struct {
+	c        *Conn
+	opcode   MessageType
+	compress bool
+	flate    *flate.Writer
+	trunc    truncWriter
+	frames   *fragmentWriter
+	closed   bool
+	err      error
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("websocket: write to closed writer")
+	}
+
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	var n int
+	if w.compress {
+		n, w.err = w.flate.Write(p)
+	} else {
+		n, w.err = w.frames.Write(p)
+	}
+	return n, w.err
+}
+
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.c.dataMu.Unlock()
+
+	if w.compress {
+		if w.err == nil {
+			w.err = w.flate.Flush()
+		}
+		w.flate.Reset(io.Discard)
+		flateWriterPool.Put(w.flate)
+	}
+
+	if w.err != nil {
+		return w.err
+	}
+	return w.frames.flush(true)
+}
+
+// fragmentWriter buffers message data and writes it as frames.
+type fragmentWriter struct {
+	mw      *messageWriter
+	buf     []byte
+	started bool
+}
+
+func (f *fragmentWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	size := f.mw.c.writeBufSz
+	for len(p) > 0 {
+		space := size - len(f.buf)
+		if space == 0 {
+			if err := f.flush(false); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if space > len(p) {
+			space = len(p)
+		}
+		f.buf = append(f.buf, p[:space]...)
+		p = p[space:]
+	}
+	return n, nil
+}
+
+func (f *fragmentWriter) flush(final bool) error {
+	opcode := continuationFrame
+	rsv1 := false
+	if !f.started {
+		opcode = f.mw.opcode
+		rsv1 = f.mw.compress // RSV1 is only set on the first frame.
+		f.started = true
+	}
+
+	c := f.mw.c
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	err := c.writeFrame(final, rsv1, opcode, f.buf)
+	f.buf = f.buf[:0]
+	return err
+}
+
+// truncWriter holds back the last 4 bytes written to it, which for a flushed
+// deflate stream are the 0x00 0x00 0xff 0xff tail(RFC 7692 section 7.2.1).
+type truncWriter struct {
+	w    io.Writer
+	tail [4]byte
+	n    int
+}
+
+func (t *truncWriter) Write(p []byte) (int, error) {
+	written := len(p)
+
+	// Fill the tail first.
+	if t.n < len(t.tail) {
+		k := copy(t.tail[t.n:], p)
+		t.n += k
+		p = p[k:]
+		if len(p) == 0 {
+			return written, nil
+		}
+	}
+
+	// Emit what no longer fits in the tail.
+	combined := append(t.tail[:t.n:t.n], p...)
+	emit := combined[:len(combined)-len(t.tail)]
+	if _, err := t.w.Write(emit); err != nil {
+		return 0, err
+	}
+	copy(t.tail[:], combined[len(combined)-len(t.tail):])
+	return written, nil
+}
+
+var flateWriterPool = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+func compressMessage(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fw := flateWriterPool.Get().(*flate.Writer)
+	defer flateWriterPool.Put(fw)
+
+	fw.Reset(&buf)
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+
+	b := buf.Bytes()
+	return bytes.TrimSuffix(b, deflateTail), nil
+}
+
+// ==================== Reading ====================
+
+type frameHeader struct {
+	fin    bool
+	rsv1   bool
+	opcode MessageType
+	length int64
+	masked bool
+	key    [4]byte
+}
+
+// protocolError closes the connection with the given code and returns an error.
+func (c *Conn) protocolError(code int, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	c.WriteControl(CloseMessage, FormatCloseMessage(code, msg), time.Now().Add(time.Second))
+	c.Close()
+	return fmt.Errorf("websocket: %s", msg)
+}
+
+func (c *Conn) readFrameHeader() (frameHeader, error) {
+	var h frameHeader
+	var b [8]byte
+
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		return h, err
+	}
+
+	h.fin = b[0]&finBit != 0
+	h.rsv1 = b[0]&rsv1Bit != 0
+	h.opcode = MessageType(b[0] & 0x0f)
+	h.masked = b[1]&maskBit != 0
+
+	if b[0]&(rsv2Bit|rsv3Bit) != 0 {
+		return h, c.protocolError(CloseProtocolError, "unexpected reserved bits")
+	}
+
+	switch length := b[1] & 0x7f; length {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			return h, err
+		}
+		l := binary.BigEndian.Uint64(b[:8])
+		if l > 1<<63-1 {
+			return h, c.protocolError(CloseProtocolError, "invalid payload length")
+		}
+		h.length = int64(l)
+	default:
+		h.length = int64(length)
+	}
+
+	if h.masked {
+		if _, err := io.ReadFull(c.br, h.key[:]); err != nil {
+			return h, err
+		}
+	}
+
+	// Servers must reject unmasked frames, clients must reject masked ones(section 5.1).
+	if h.masked != c.isServer {
+		return h, c.protocolError(CloseProtocolError, "incorrect frame masking")
+	}
+
+	switch h.opcode {
+	case CloseMessage, PingMessage, PongMessage:
+		if h.length > maxControlPayload {
+			return h, c.protocolError(CloseProtocolError, "control frame too large")
+		}
+
+		if !h.fin {
+			return h, c.protocolError(CloseProtocolError, "fragmented control frame")
+		}
+
+		if h.rsv1 {
+			return h, c.protocolError(CloseProtocolError, "compressed control frame")
+		}
+	case continuationFrame, TextMessage, BinaryMessage:
+		if h.rsv1 && !c.compressionNegotiated {
+			return h, c.protocolError(CloseProtocolError, "unexpected RSV1 bit")
+		}
+	default:
+		return h, c.protocolError(CloseProtocolError, "unknown opcode %d", h.opcode)
+	}
+	return h, nil
+}
+
+func (c *Conn) readPayload(h frameHeader) ([]byte, error) {
+	payload := make([]byte, h.length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return nil, err
+	}
+
+	if h.masked {
+		maskBytes(h.key, 0, payload)
+	}
+	return payload, nil
+}
+
+// handleControl processes a control frame. It returns a *CloseError for close frames.
+func (c *Conn) handleControl(h frameHeader, payload []byte) error {
+	switch h.opcode {
+	case PingMessage:
+		return c.pingHandler(string(payload))
+	case PongMessage:
+		return c.pongHandler(string(payload))
+	}
+
+	// Close frame.
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.protocolError(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+
+		if !isValidReceivedCloseCode(closeErr.Code) {
+			return c.protocolError(CloseProtocolError, "invalid close code %d", closeErr.Code)
+		}
+
+		if !utf8.ValidString(closeErr.Text) {
+			return c.protocolError(CloseInvalidFramePayloadData, "invalid utf8 in close reason")
+		}
+	}
+
+	// Echo the close frame(section 5.5.1) and close the connection.
+	reply := FormatCloseMessage(closeErr.Code, "")
+	c.WriteControl(CloseMessage, reply, time.Now().Add(time.Second))
+	c.Close()
+	return closeErr
+}
+
+func isValidReceivedCloseCode(code int) bool {
+	switch code {
+	case 1000, 1001, 1002, 1003, 1007, 1008, 1009, 1010, 1011, 1012, 1013, 1014:
+		return true
+	}
+	return code >= 3000 && code <= 4999
+}
+
+// ReadMessage reads the next text or binary message, reassembling fragments and
+// decompressing it if needed. Control frames are handled while reading.
+//
+// When the peer closes the connection, a *CloseError is returned.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	return c.readMessage()
+}
+
+func (c *Conn) readMessage() (MessageType, []byte, error) {
+	var (
+		messageType MessageType
+		compressed  bool
+		message     []byte
+		started     bool
+	)
+
+	for {
+		h, err := c.readFrameHeader()
+		if err != nil {
+			c.Close()
+			return 0, nil, err
+		}
+
+		if h.opcode >= CloseMessage {
+			payload, err := c.readPayload(h)
+			if err != nil {
+				c.Close()
+				return 0, nil, err
+			}
+
+			if err := c.handleControl(h, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		if h.opcode == continuationFrame {
+			if !started {
+				return 0, nil, c.protocolError(CloseProtocolError, "continuation frame without a message")
+			}
+
+			if h.rsv1 {
+				return 0, nil, c.protocolError(CloseProtocolError, "RSV1 set on continuation frame")
+			}
+		} else {
+			if started {
+				return 0, nil, c.protocolError(CloseProtocolError, "expected continuation frame")
+			}
+			started = true
+			messageType = h.opcode
+			compressed = h.rsv1
+		}
+
+		// Compare without adding, h.length is up to 2^63-1.
+		if h.length > c.readLimit-int64(len(message)) {
+			return 0, nil, c.tooBig()
+		}
+
+		payload, err := c.readPayload(h)
+		if err != nil {
+			c.Close()
+			return 0, nil, err
+		}
+		message = append(message, payload...)
+
+		if h.fin {
+			break
+		}
+	}
+
+	if compressed {
+		var err error
+		message, err = c.decompress(message)
+		if err != nil {
+			if errors.Is(err, ErrReadLimit) {
+				return 0, nil, c.tooBig()
+			}
+			return 0, nil, c.protocolError(CloseInvalidFramePayloadData, "invalid compressed data")
+		}
+	}
+
+	if messageType == TextMessage && !utf8.Valid(message) {
+		return 0, nil, c.protocolError(CloseInvalidFramePayloadData, "invalid utf8 in text message")
+	}
+	return messageType, message, nil
+}
+
+func (c *Conn) tooBig() error {
+	c.protocolError(CloseMessageTooBig, "message too big")
+	return ErrReadLimit
+}
+
+func (c *Conn) decompress(data []byte) ([]byte, error) {
+	// The peer uses no context takeover, so every message is a standalone stream.
+	fr := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)))
+	defer fr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(fr, c.readLimit+1))
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	if int64(len(out)) > c.readLimit {
+		return nil, ErrReadLimit
+	}
+	return out, nil
+}
+
+// ReadJSON reads the next message and decodes it as JSON into v.
+func (c *Conn) ReadJSON(v any) error {
+	_, msg, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, v)
+}
+
+// KeepAlive sends a ping every interval and closes the connection if no pong is
+// received within interval+pongWait. Only pongs extend the read deadline, other
+// messages do not. ReadMessage must be called in a loop for pongs to be processed.
+func (c *Conn) KeepAlive(interval, pongWait time.Duration) {
+	extend := func() {
+		c.conn.SetReadDeadline(time.Now().Add(interval + pongWait))
+	}
+	extend()
+
+	pongHandler := c.pongHandler
+	c.SetPongHandler(func(appData string) error {
+		extend()
+		return pongHandler(appData)
+	})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.closed:
+				return
+			case <-ticker.C:
+				if err := c.Ping(nil); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// maskBytes applies the masking key to b starting at position pos in the payload.
+func maskBytes(key [4]byte, pos int, b []byte) int {
+	for i := range b {
+		b[i] ^= key[pos&3]
+		pos++
+	}
+	return pos & 3
+}
@@ -0,0 +1,324 @@
+package websocket
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/gor/gor"
+)
+
+// dial performs a client handshake against the test server and returns a client side Conn.
+func dial(t *testing.T, serverURL, path string, header http.Header) (*Conn, *http.Response) {
+	t.Helper()
+
+	addr := strings.TrimPrefix(serverURL, "http://")
+	netConn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, serverURL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	if err := req.Write(netConn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(netConn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		netConn.Close()
+		return nil, res
+	}
+
+	conn := newConn(netConn, br, false, 16)
+	conn.compressionNegotiated = headerContainsExtension(res.Header, permessageDeflate)
+	conn.compressWrites = conn.compressionNegotiated
+	t.Cleanup(func() { conn.Close() })
+	return conn, res
+}
+
+func newEchoServer(u *Upgrader) *httptest.Server {
+	r := gor.NewRouter()
+	u.Route(r, "/echo", func(conn *Conn, req *http.Request) {
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if err := conn.WriteMessage(mt, msg); err != nil {
+				return
+			}
+		}
+	})
+	return httptest.NewServer(r)
+}
+
+func TestHandshake(t *testing.T) {
+	ts := newEchoServer(&Upgrader{Subprotocols: []string{"chat"}})
+	defer ts.Close()
+
+	conn, res := dial(t, ts.URL, "/echo", http.Header{"Sec-Websocket-Protocol": {"superchat, chat"}})
+	if conn == nil {
+		t.Fatalf("expected 101, got %d", res.StatusCode)
+	}
+
+	// Example from RFC 6455 section 1.3.
+	if accept := res.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected Sec-WebSocket-Accept %q", accept)
+	}
+
+	if p := res.Header.Get("Sec-WebSocket-Protocol"); p != "chat" {
+		t.Errorf("expected subprotocol chat, got %q", p)
+	}
+}
+
+func TestHandshakeOriginRejected(t *testing.T) {
+	ts := newEchoServer(&Upgrader{})
+	defer ts.Close()
+
+	_, res := dial(t, ts.URL, "/echo", http.Header{"Origin": {"https://evil.example.com"}})
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", res.StatusCode)
+	}
+}
+
+func TestEchoFragmentedAndControl(t *testing.T) {
+	ts := newEchoServer(&Upgrader{})
+	defer ts.Close()
+
+	conn, _ := dial(t, ts.URL, "/echo", nil)
+
+	// Fragmented text message with a ping in between.
+	conn.writeMu.Lock()
+	conn.writeFrame(false, false, TextMessage, []byte("Hello, "))
+	conn.writeFrame(true, false, PingMessage, []byte("are you there?"))
+	conn.writeFrame(true, false, continuationFrame, []byte("World"))
+	conn.writeMu.Unlock()
+
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(appData string) error {
+		pong <- appData
+		return nil
+	})
+
+	mt, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mt != TextMessage || string(msg) != "Hello, World" {
+		t.Errorf("expected text 'Hello, World', got %d %q", mt, msg)
+	}
+
+	select {
+	case data := <-pong:
+		if data != "are you there?" {
+			t.Errorf("unexpected pong data %q", data)
+		}
+	default:
+		t.Errorf("expected a pong before the echoed message")
+	}
+
+	// Streamed message is sent in fragments of the write buffer size(16 bytes).
+	w, _ := conn.NextWriter(BinaryMessage)
+	payload := strings.Repeat("0123456789", 10)
+	w.Write([]byte(payload))
+	w.Close()
+
+	mt, msg, err = conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mt != BinaryMessage || string(msg) != payload {
+		t.Errorf("expected binary echo, got %d %q", mt, msg)
+	}
+
+	if err := conn.Shutdown(CloseNormalClosure, "bye", time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	ts := newEchoServer(&Upgrader{EnableCompression: true})
+	defer ts.Close()
+
+	conn, res := dial(t, ts.URL, "/echo", http.Header{
+		"Sec-Websocket-Extensions": {"permessage-deflate; client_max_window_bits"},
+	})
+
+	if !conn.CompressionNegotiated() {
+		t.Fatalf("expected compression to be negotiated, got %q", res.Header.Get("Sec-WebSocket-Extensions"))
+	}
+
+	payload := strings.Repeat("compress me please ", 200)
+	if err := conn.WriteMessage(TextMessage, []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(msg) != payload {
+		t.Errorf("compressed round trip failed, got %d bytes", len(msg))
+	}
+
+	// Compressed and streamed.
+	w, _ := conn.NextWriter(TextMessage)
+	for i := 0; i < 50; i++ {
+		w.Write([]byte("streamed and compressed "))
+	}
+	w.Close()
+
+	_, msg, err = conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(msg) != strings.Repeat("streamed and compressed ", 50) {
+		t.Errorf("streamed compressed round trip failed, got %q", msg)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	ts := newEchoServer(&Upgrader{})
+	defer ts.Close()
+
+	conn, _ := dial(t, ts.URL, "/echo", nil)
+
+	// A continuation frame without a message is a protocol error.
+	conn.writeMu.Lock()
+	conn.writeFrame(true, false, continuationFrame, []byte("oops"))
+	conn.writeMu.Unlock()
+
+	_, _, err := conn.ReadMessage()
+	if !IsCloseError(err, CloseProtocolError) {
+		t.Errorf("expected protocol error close, got %v", err)
+	}
+
+	conn, _ = dial(t, ts.URL, "/echo", nil)
+	conn.WriteMessage(TextMessage, []byte{0xff, 0xfe})
+
+	_, _, err = conn.ReadMessage()
+	if !IsCloseError(err, CloseInvalidFramePayloadData) {
+		t.Errorf("expected invalid payload close, got %v", err)
+	}
+}
+
+func TestHubBroadcast(t *testing.T) {
+	hub := NewHub()
+
+	r := gor.NewRouter()
+	Route(r, "/hub", func(conn *Conn, req *http.Request) {
+		hub.Add(conn, req.URL.Query().Get("room"))
+		defer hub.Remove(conn)
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	a, _ := dial(t, ts.URL, "/hub?room=a", nil)
+	b, _ := dial(t, ts.URL, "/hub?room=b", nil)
+
+	for i := 0; i < 100 && hub.Len() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hub.BroadcastTo("b", TextMessage, []byte("only b"))
+	hub.Broadcast(TextMessage, []byte("everyone"))
+
+	_, msg, _ := a.ReadMessage()
+	if string(msg) != "everyone" {
+		t.Errorf("expected a to receive 'everyone', got %q", msg)
+	}
+
+	_, msg, _ = b.ReadMessage()
+	if string(msg) != "only b" {
+		t.Errorf("expected b to receive 'only b', got %q", msg)
+	}
+
+	a.Shutdown(CloseGoingAway, "", time.Second)
+	for i := 0; i < 100 && hub.Len() > 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if hub.Len() != 1 || hub.RoomLen("a") != 0 {
+		t.Errorf("expected closed connection to be removed, got %d connections", hub.Len())
+	}
+}
+
+func TestReadLimitOverflow(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	conn := newConn(server, nil, true, 0)
+	defer conn.Close()
+
+	go func() {
+		// 1 byte text fragment, then a continuation of 2^63-1 bytes. Payloads are not
+		// sent, the length must be rejected first.
+		client.Write([]byte{0x01, 0x81, 0, 0, 0, 0, 'a'})
+		client.Write([]byte{0x80, 0xff, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+		io.Copy(io.Discard, client) // The close frame.
+	}()
+
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrReadLimit) {
+		t.Fatalf("expected ErrReadLimit, got %v", err)
+	}
+}
+
+func TestWriteControlRestoresDeadline(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	conn := newConn(server, nil, true, 0)
+	defer conn.Close()
+
+	// Nothing reads the pipe: writes block until the deadline.
+	conn.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+
+	go io.Copy(io.Discard, io.LimitReader(client, 2)) // Read the ping only.
+	if err := conn.Ping(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- conn.WriteMessage(TextMessage, []byte("blocked")) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("expected the write deadline to be exceeded, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the deadline set before the ping to still apply")
+	}
+}